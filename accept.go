@@ -0,0 +1,149 @@
+package errors
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// responseFormat is a representation of error responses that clients can ask for with
+// the Accept header.
+type responseFormat int
+
+const (
+	formatJSON responseFormat = iota
+	formatText
+	formatXML
+)
+
+// formatMediaTypes lists the media types each format is served for, in order of
+// preference when the Accept header rates several formats equally.
+var formatMediaTypes = []struct {
+	format     responseFormat
+	mediaTypes []string
+}{
+	{formatJSON, []string{"application/json", ProblemContentType}},
+	{formatText, []string{"text/plain"}},
+	{formatXML, []string{"application/xml", "text/xml"}},
+}
+
+// mediaRange is a media range of an Accept header with its quality.
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// negotiateFormat returns the format an Accept header rates highest. JSON is the default
+// for a missing header, wildcards and headers naming no supported media type.
+func negotiateFormat(accept string) responseFormat {
+	ranges := parseAccept(accept)
+	best, bestQuality := formatJSON, 0.0
+	for _, f := range formatMediaTypes {
+		for _, mediaType := range f.mediaTypes {
+			if quality := acceptQuality(ranges, mediaType); quality > bestQuality {
+				best, bestQuality = f.format, quality
+			}
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality of the most specific range matching mediaType, or 0.
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, 0
+	for _, r := range ranges {
+		s := 0
+		switch r.mediaType {
+		case mediaType:
+			s = 3
+		case typ + "/*":
+			s = 2
+		case "*/*":
+			s = 1
+		}
+		if s > specificity {
+			quality, specificity = r.quality, s
+		}
+	}
+	return quality
+}
+
+// parseAccept returns the lowercased media ranges of an Accept header, ignoring
+// parameters other than q.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, found := strings.CutPrefix(strings.TrimSpace(param), "q="); found {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType, quality})
+	}
+	return ranges
+}
+
+// encodeBody renders body in format, or as a problem document for the request to
+// instance when problem details are on and JSON is wanted, and returns it with its
+// content type.
+func (c *handlerConfig) encodeBody(format responseFormat, status int, body HttpError, instance string) (string, []byte, error) {
+	switch format {
+	case formatXML:
+		data, err := xml.Marshal(body)
+		return "application/xml; charset=utf-8", data, err
+	case formatText:
+		return "text/plain; charset=utf-8", []byte(body.text()), nil
+	}
+	if c.problemDetails {
+		data, err := json.Marshal(c.problem(status, body, instance))
+		return ProblemContentType, data, err
+	}
+	data, err := json.Marshal(body)
+	return "application/json; charset=utf-8", data, err
+}
+
+// text renders e as a single line, e.g. "NOT_FOUND: data not found (request_id=4bf9)".
+func (e HttpError) text() string {
+	line := e.Code + ": " + e.Message
+	if e.RequestID != "" {
+		line += " (request_id=" + e.RequestID + ")"
+	}
+	return line + "\n"
+}
+
+// MarshalXML renders e as an <error> element. Details are sorted <detail key="...">
+// elements, with non-string values JSON-encoded as in RPCMetadata.
+func (e HttpError) MarshalXML(enc *xml.Encoder, _ xml.StartElement) error {
+	type detail struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type details struct {
+		Detail []detail `xml:"detail"`
+	}
+	doc := struct {
+		XMLName   xml.Name `xml:"error"`
+		Code      string   `xml:"code"`
+		Type      string   `xml:"type,omitempty"`
+		Message   string   `xml:"message"`
+		Details   *details `xml:"details,omitempty"`
+		RequestID string   `xml:"request_id"`
+	}{Code: e.Code, Type: e.Type, Message: e.Message, RequestID: e.RequestID}
+	if len(e.Details) > 0 {
+		doc.Details = &details{}
+		for _, key := range sortedKeys(e.Details) {
+			doc.Details.Detail = append(doc.Details.Detail, detail{key, rpcMetadataValue(e.Details[key])})
+		}
+	}
+	return enc.Encode(doc)
+}
@@ -0,0 +1,71 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   responseFormat
+	}{
+		{"", formatJSON},
+		{"*/*", formatJSON},
+		{"image/png", formatJSON},
+		{"application/*", formatJSON},
+		{"text/plain", formatText},
+		{"TEXT/PLAIN", formatText},
+		{"text/*", formatText},
+		{"text/xml", formatXML},
+		{"application/xml;q=0.5, text/plain;q=0.9", formatText},
+		{"text/*;q=0.8, application/xml", formatXML},
+		{"application/xml;charset=utf-8;q=0.7, application/json;q=0.6", formatXML},
+		{"application/json;q=0.1, text/*;q=0.5", formatText},
+		{"text/plain;q=0", formatJSON},
+		{"text/*;q=0.9, text/plain;q=0.2", formatXML},
+		{"application/problem+json, text/plain;q=0.9", formatJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := negotiateFormat(tt.accept); got != tt.want {
+				t.Errorf("negotiateFormat(%q) = %d, want %d", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleNegotiatesFormat(t *testing.T) {
+	tests := []struct {
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{"text/html, text/plain;q=0.8", "text/plain; charset=utf-8", "NOT_FOUND: data not found (request_id=" + testTraceID + ")\n"},
+		{"application/xml", "application/xml; charset=utf-8", "<error><code>NOT_FOUND</code>"},
+		{"*/*;q=0.1, application/json", "application/json; charset=utf-8", `{"code":"NOT_FOUND"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/test", Handle(returning(ErrorNotFound), WithLogging(false)))
+			req := withTrace(httptest.NewRequest(http.MethodGet, "/test", nil))
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if !strings.HasPrefix(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to start with %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
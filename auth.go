@@ -0,0 +1,34 @@
+package errors
+
+// Auth reasons tell clients why a request was rejected as unauthenticated, so they
+// can refresh an expired token instead of asking the user to sign in again.
+const (
+	AuthReasonTokenMissing = "token_missing"
+	AuthReasonTokenExpired = "token_expired"
+	AuthReasonTokenInvalid = "token_invalid"
+	// AuthReasonTokenMalformed and AuthReasonSignatureInvalid narrow down
+	// AuthReasonTokenInvalid for tokens that can't be parsed or weren't signed by us.
+	AuthReasonTokenMalformed   = "token_malformed"
+	AuthReasonSignatureInvalid = "signature_invalid"
+)
+
+// DetailKeyAuthReason is the details key holding the auth reason of an Unauthorized error.
+const DetailKeyAuthReason = "auth_reason"
+
+// Unauthorized returns ErrorUnauthorized wrapped with reason, one of the AuthReason
+// constants, and the given key-value pairs.
+func Unauthorized(reason string, keyValues ...any) error {
+	return Wrap(ErrorUnauthorized, append([]any{DetailKeyAuthReason, reason}, keyValues...)...)
+}
+
+// WithAuthChallenge wraps an error with a WWW-Authenticate challenge, e.g.
+// `Bearer realm="api", error="invalid_token"`, which is sent as a response header.
+func WithAuthChallenge(err error, challenge string) error {
+	return Wrap(err, WrapKeyAuthChallenge, challenge)
+}
+
+// AuthChallenge returns the outermost WWW-Authenticate challenge in err's chain, if any.
+func AuthChallenge(err error) string {
+	challenge, _ := GetData[string](err, WrapKeyAuthChallenge)
+	return challenge
+}
@@ -0,0 +1,41 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestUnauthorized(t *testing.T) {
+	const challenge = `Bearer realm="api", error="invalid_token"`
+	tests := []struct {
+		name          string
+		err           error
+		wantReason    string
+		wantChallenge string
+	}{
+		{"reason", Unauthorized(AuthReasonTokenExpired), AuthReasonTokenExpired, ""},
+		{"challenge", WithAuthChallenge(Unauthorized(AuthReasonTokenInvalid, "kid", "k1"), challenge), AuthReasonTokenInvalid, challenge},
+		{"wrapped challenge", fmt.Errorf("authenticating: %w", WithAuthChallenge(Unauthorized(AuthReasonSignatureInvalid), challenge)), AuthReasonSignatureInvalid, challenge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AuthChallenge(tt.err); got != tt.wantChallenge {
+				t.Errorf("AuthChallenge() = %q, want %q", got, tt.wantChallenge)
+			}
+			w, body := serve(t, returning(tt.err), WithLogging(false))
+			if w.Code != http.StatusUnauthorized || body.Code != string(KeyUnauthorized) {
+				t.Errorf("response = %d %s, want 401 %s", w.Code, body.Code, KeyUnauthorized)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+			if body.Details[DetailKeyAuthReason] != tt.wantReason {
+				t.Errorf("details = %v, want %s=%s", body.Details, DetailKeyAuthReason, tt.wantReason)
+			}
+			if _, exists := body.Details[WrapKeyAuthChallenge]; exists {
+				t.Errorf("details = %v, want the challenge only in the header", body.Details)
+			}
+		})
+	}
+}
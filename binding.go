@@ -0,0 +1,146 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// BindingError marks an error as coming from binding request data, so that it maps to
+// WRONG_PARAMETER (400) even when the underlying error, such as a *strconv.NumError, would
+// be an internal error anywhere else. Source names the binding, e.g. "query" or "uri".
+// Parameter and Value identify the offending input when they can be recovered.
+type BindingError struct {
+	Source    string
+	Parameter string
+	Value     string
+	Err       error
+	// boundType is the type bound into, used to report fields by their tag names.
+	boundType reflect.Type
+}
+
+func (e *BindingError) Error() string {
+	if e.Parameter != "" {
+		return fmt.Sprintf("binding %s parameter %q: %v", e.Source, e.Parameter, e.Err)
+	}
+	return fmt.Sprintf("binding %s: %v", e.Source, e.Err)
+}
+
+func (e *BindingError) Unwrap() error {
+	return e.Err
+}
+
+// Bind binds the request into obj with the binding gin picks from the method and
+// content type, like ctx.ShouldBind, marking failures as a *BindingError.
+func Bind(ctx *gin.Context, obj any) error {
+	return BindWith(ctx, obj, binding.Default(ctx.Request.Method, ctx.ContentType()))
+}
+
+// BindQuery binds the query string into obj, marking failures as a *BindingError.
+func BindQuery(ctx *gin.Context, obj any) error {
+	return BindWith(ctx, obj, binding.Query)
+}
+
+// BindHeader binds the request headers into obj, marking failures as a *BindingError.
+func BindHeader(ctx *gin.Context, obj any) error {
+	return BindWith(ctx, obj, binding.Header)
+}
+
+// BindURI binds the path parameters into obj, marking failures as a *BindingError.
+func BindURI(ctx *gin.Context, obj any) error {
+	if err := ctx.ShouldBindUri(obj); err != nil {
+		params := make(map[string][]string, len(ctx.Params))
+		for _, param := range ctx.Params {
+			params[param.Key] = append(params[param.Key], param.Value)
+		}
+		return newBindingError("uri", err, params, obj)
+	}
+	return nil
+}
+
+// BindWith binds the request into obj with b, marking failures as a *BindingError.
+func BindWith(ctx *gin.Context, obj any, b binding.Binding) error {
+	if err := ctx.ShouldBindWith(obj, b); err != nil {
+		var values map[string][]string
+		switch b {
+		case binding.Query:
+			values = ctx.Request.URL.Query()
+		case binding.Header:
+			values = ctx.Request.Header
+		case binding.Form, binding.FormPost, binding.FormMultipart:
+			values = ctx.Request.Form
+		}
+		return newBindingError(b.Name(), err, values, obj)
+	}
+	return nil
+}
+
+// newBindingError marks err from binding into obj as a binding failure, recovering the
+// received value from parse errors and the parameter name from the bound values when
+// exactly one matches.
+func newBindingError(source string, err error, values map[string][]string, obj any) *BindingError {
+	bindingErr := &BindingError{Source: source, Err: err, boundType: reflect.TypeOf(obj)}
+	if value, ok := receivedValue(err); ok {
+		bindingErr.Value = value
+		bindingErr.Parameter = parameterFor(values, value)
+	}
+	return bindingErr
+}
+
+// receivedValue returns the input that failed to parse in err, if it is a parse error.
+func receivedValue(err error) (string, bool) {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return numErr.Num, true
+	}
+	var timeErr *time.ParseError
+	if errors.As(err, &timeErr) {
+		return timeErr.Value, true
+	}
+	return "", false
+}
+
+// parameterFor returns the only parameter in values holding value, or "" when none or
+// several do.
+func parameterFor(values map[string][]string, value string) string {
+	var parameter string
+	for name, candidates := range values {
+		for _, candidate := range candidates {
+			if candidate != value {
+				continue
+			}
+			if parameter != "" && parameter != name {
+				return ""
+			}
+			parameter = name
+		}
+	}
+	return parameter
+}
+
+// FormFile returns the uploaded file for the multipart form field name, like
+// ctx.FormFile, marking failures such as a missing file or a malformed body as a
+// *BindingError naming the field.
+func FormFile(ctx *gin.Context, name string) (*multipart.FileHeader, error) {
+	file, err := ctx.FormFile(name)
+	if err != nil {
+		return nil, &BindingError{Source: binding.FormMultipart.Name(), Parameter: name, Err: err}
+	}
+	return file, nil
+}
+
+// MultipartForm returns the parsed multipart form, like ctx.MultipartForm, marking
+// failures as a *BindingError.
+func MultipartForm(ctx *gin.Context) (*multipart.Form, error) {
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return nil, &BindingError{Source: binding.FormMultipart.Name(), Err: err}
+	}
+	return form, nil
+}
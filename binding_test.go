@@ -0,0 +1,204 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// upload is the body bound by the upload handlers.
+type upload struct {
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+func TestMaxBytesReader(t *testing.T) {
+	const limit = 16
+	handler := func(ctx *gin.Context) error {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		var u upload
+		return Bind(ctx, &u)
+	}
+	req := postJSON(`{"name":"avatar.png","data":"` + strings.Repeat("A", 64) + `"}`)
+	w, body := serveRequest(t, req, handler, WithLogging(false))
+	if w.Code != http.StatusRequestEntityTooLarge || body.Code != string(KeyPayloadTooLarge) {
+		t.Errorf("response = %d %s, want 413 %s", w.Code, body.Code, KeyPayloadTooLarge)
+	}
+	if body.Details["limit_bytes"] != float64(limit) {
+		t.Errorf("details = %v, want limit_bytes=%d", body.Details, limit)
+	}
+	if strings.Contains(body.Message, "http: request body too large") {
+		t.Errorf("message = %q, want the raw reader error hidden", body.Message)
+	}
+}
+
+func TestMaxBytesReaderMultipart(t *testing.T) {
+	const limit = 64
+	handler := func(ctx *gin.Context) error {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		_, err := MultipartForm(ctx)
+		return err
+	}
+	req := multipartRequest(t, map[string]string{"caption": strings.Repeat("A", 256)}, nil)
+	w, body := serveRequest(t, req, handler, WithLogging(false))
+	if w.Code != http.StatusRequestEntityTooLarge || body.Code != string(KeyPayloadTooLarge) {
+		t.Errorf("response = %d %s, want 413 %s", w.Code, body.Code, KeyPayloadTooLarge)
+	}
+}
+
+// multipartRequest returns a multipart POST request to /test with the given fields and
+// files, keyed by form field name.
+func multipartRequest(t *testing.T, fields map[string]string, files map[string][]byte) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		part, err := mw.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/test", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestBindingErrors(t *testing.T) {
+	type listQuery struct {
+		Limit int       `form:"limit"`
+		Since time.Time `form:"since" time_format:"2006-01-02"`
+	}
+	type postURI struct {
+		ID int `uri:"id"`
+	}
+	type pageHeader struct {
+		Page int `header:"X-Page"`
+	}
+	tests := []struct {
+		name          string
+		route         string
+		target        string
+		header        http.Header
+		bind          HandlerFunc
+		wantSource    string
+		wantParameter string
+		wantValue     string
+	}{
+		{"query number", "/posts", "/posts?limit=abc", nil, func(ctx *gin.Context) error { return BindQuery(ctx, &listQuery{}) }, "query", "limit", "abc"},
+		{"query time", "/posts", "/posts?since=yesterday", nil, func(ctx *gin.Context) error { return BindQuery(ctx, &listQuery{}) }, "query", "since", "yesterday"},
+		{"uri", "/posts/:id", "/posts/abc", nil, func(ctx *gin.Context) error { return BindURI(ctx, &postURI{}) }, "uri", "id", "abc"},
+		{"header", "/posts", "/posts", http.Header{"X-Page": {"two"}}, func(ctx *gin.Context) error { return BindHeader(ctx, &pageHeader{}) }, "header", "X-Page", "two"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET(tt.route, Handle(tt.bind, WithLogging(false)))
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			var body HttpError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+			}
+			if w.Code != http.StatusBadRequest || body.Code != string(KeyWrongParams) {
+				t.Errorf("response = %d %s, want 400 %s", w.Code, body.Code, KeyWrongParams)
+			}
+			want := map[string]any{"source": tt.wantSource, "parameter": tt.wantParameter, "value": tt.wantValue}
+			for k, v := range want {
+				if body.Details[k] != v {
+					t.Errorf("details[%s] = %v, want %v", k, body.Details[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestInternalParseErrorsStayInternal(t *testing.T) {
+	_, numErr := strconv.Atoi("abc")
+	_, timeErr := time.Parse(time.DateOnly, "yesterday")
+	for _, err := range []error{numErr, timeErr} {
+		if got := StatusOf(fmt.Errorf("parsing config: %w", err)); got != http.StatusInternalServerError {
+			t.Errorf("StatusOf(%v) = %d, want %d outside of binding", err, got, http.StatusInternalServerError)
+		}
+	}
+}
+
+func TestMultipartErrors(t *testing.T) {
+	const maxMemory = 1 << 10
+	formFile := func(ctx *gin.Context) error {
+		_, err := FormFile(ctx, "avatar")
+		return err
+	}
+	malformed := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("--x\r\nnot a part"))
+	malformed.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	noBoundary := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("caption=a"))
+	noBoundary.Header.Set("Content-Type", "multipart/form-data")
+	tests := []struct {
+		name          string
+		req           *http.Request
+		wantStatus    int
+		wantCode      ErrorCode
+		wantMessage   string
+		wantParameter any
+	}{
+		{"missing file", multipartRequest(t, map[string]string{"caption": "a"}, nil), http.StatusBadRequest, KeyWrongParams, "missing file 'avatar'", "avatar"},
+		{"over memory limit", multipartRequest(t, map[string]string{"caption": strings.Repeat("A", 10<<20+2*maxMemory)}, nil), http.StatusRequestEntityTooLarge, KeyPayloadTooLarge, "request body too large", "avatar"},
+		{"malformed", malformed, http.StatusBadRequest, KeyWrongParams, "malformed multipart body", "avatar"},
+		{"no boundary", noBoundary, http.StatusBadRequest, KeyWrongParams, "malformed multipart body: missing boundary", "avatar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.MaxMultipartMemory = maxMemory
+			router.POST("/test", Handle(formFile, WithLogging(false)))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, tt.req)
+			var body HttpError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+			}
+			if w.Code != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			if body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Message, tt.wantMessage)
+			}
+			if body.Details["parameter"] != tt.wantParameter {
+				t.Errorf("parameter = %v, want %v", body.Details["parameter"], tt.wantParameter)
+			}
+		})
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	_, body := serveRequest(t, multipartRequest(t, nil, map[string][]byte{"avatar": []byte("png")}), func(ctx *gin.Context) error {
+		if _, err := FormFile(ctx, "avatar"); err != nil {
+			return err
+		}
+		return ErrorConflict
+	}, WithLogging(false))
+	if body.Code != string(KeyConflict) {
+		t.Errorf("code = %s, want the upload accepted", body.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultBodyCaptureLimit is the number of body bytes CaptureBody keeps when given a
+// non-positive limit.
+const DefaultBodyCaptureLimit = 64 << 10
+
+// capturedBodyKey is the gin context key holding the body bytes recorded by CaptureBody.
+const capturedBodyKey = "github.com/A-pen-app/errors/captured-body"
+
+// CaptureBody returns a gin middleware that records up to maxBytes of the request body
+// as the handler reads it, so that JSON syntax errors can be reported with a line and
+// column. The body is never read ahead or buffered beyond maxBytes.
+func CaptureBody(maxBytes int) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		maxBytes = DefaultBodyCaptureLimit
+	}
+	return func(ctx *gin.Context) {
+		if ctx.Request.Body != nil && ctx.Request.Body != http.NoBody {
+			capture := &bodyCapture{ReadCloser: ctx.Request.Body, limit: maxBytes}
+			ctx.Request.Body = capture
+			ctx.Set(capturedBodyKey, capture)
+		}
+		ctx.Next()
+	}
+}
+
+// bodyCapture records the first limit bytes read from a request body.
+type bodyCapture struct {
+	io.ReadCloser
+	buf   bytes.Buffer
+	limit int
+}
+
+func (c *bodyCapture) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if remaining := c.limit - c.buf.Len(); remaining > 0 {
+		c.buf.Write(p[:min(n, remaining)])
+	}
+	return n, err
+}
+
+// requestBody returns the request body bytes known to ctx: those cached by gin's
+// ShouldBindBodyWith, or else those recorded by CaptureBody.
+func requestBody(ctx *gin.Context) []byte {
+	if cached, ok := ctx.Get(gin.BodyBytesKey); ok {
+		if body, ok := cached.([]byte); ok {
+			return body
+		}
+	}
+	if captured, ok := ctx.Get(capturedBodyKey); ok {
+		if capture, ok := captured.(*bodyCapture); ok {
+			return capture.buf.Bytes()
+		}
+	}
+	return nil
+}
+
+// lineColumn returns the 1-based line and column of the byte a json.SyntaxError with the
+// given offset points at, i.e. the last byte read, and false when it lies beyond body.
+func lineColumn(body []byte, offset int64) (line, column int, ok bool) {
+	if offset <= 0 || offset > int64(len(body)) {
+		return 0, 0, false
+	}
+	prefix := body[:offset-1]
+	line = bytes.Count(prefix, []byte{'\n'}) + 1
+	column = len(prefix) - bytes.LastIndexByte(prefix, '\n')
+	return line, column, true
+}
@@ -0,0 +1,72 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindUpload binds the request body into an upload.
+func bindUpload(ctx *gin.Context) error {
+	var u upload
+	return Bind(ctx, &u)
+}
+
+// serveCaptured is serveRequest with CaptureBody(limit) in front of the handler.
+func serveCaptured(t *testing.T, req *http.Request, limit int) HttpError {
+	t.Helper()
+	router := gin.New()
+	router.Use(CaptureBody(limit))
+	router.POST("/test", Handle(bindUpload, WithLogging(false)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var body HttpError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+	}
+	return body
+}
+
+func TestJSONSyntaxErrorLocation(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    string
+		limit      int
+		wantLine   any
+		wantColumn any
+	}{
+		{"trailing comma", "{\n  \"name\": \"a\",\n}", 0, float64(3), float64(1)},
+		{"unquoted value", `{"name": a}`, 0, float64(1), float64(10)},
+		{"beyond capture limit", "{\n  \"name\": \"a\",\n}", 4, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := serveCaptured(t, postJSON(tt.payload), tt.limit)
+			if body.Message != "malformed JSON" {
+				t.Errorf("message = %q, want %q", body.Message, "malformed JSON")
+			}
+			if _, ok := body.Details["offset"]; !ok {
+				t.Errorf("details = %v, want the offset", body.Details)
+			}
+			if body.Details["line"] != tt.wantLine || body.Details["column"] != tt.wantColumn {
+				t.Errorf("line:column = %v:%v, want %v:%v", body.Details["line"], body.Details["column"], tt.wantLine, tt.wantColumn)
+			}
+		})
+	}
+}
+
+func TestJSONSyntaxErrorWithoutCapture(t *testing.T) {
+	_, body := serveRequest(t, postJSON(`{"name": a}`), bindUpload, WithLogging(false))
+	if body.Details["offset"] != float64(10) {
+		t.Errorf("offset = %v, want 10", body.Details["offset"])
+	}
+	if _, ok := body.Details["line"]; ok {
+		t.Errorf("details = %v, want no line for a body that wasn't captured", body.Details)
+	}
+}
@@ -0,0 +1,113 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/A-pen-app/logging"
+)
+
+// Classification describes how a typed error from a third-party library is reported:
+// its mapping plus extra response details, a client-safe message and whether the failed
+// operation may be retried.
+type Classification struct {
+	Code   ErrorCode
+	Status int
+	// Message replaces the raw error string in responses when set; an explicit public
+	// message still wins.
+	Message string
+	// Details are added to the response details; wrap data wins on duplicate keys.
+	Details map[string]any
+	// Retryable marks the error retryable, unless an explicit flag says otherwise.
+	Retryable bool
+	// Severity is the severity the error is logged at, unless it carries an explicit one.
+	// SeverityDefault leaves it to SetCodeSeverity and the status.
+	Severity Severity
+}
+
+// classifier classifies an error in a chain with the function registered for typ,
+// reporting false if it doesn't apply.
+type classifier struct {
+	typ      reflect.Type
+	classify func(error) (Classification, bool)
+}
+
+// classifiers holds the registered classifiers in registration order, guarded by registryMu.
+var classifiers []classifier
+
+// RegisterTypeClassifier classifies every error of type T anywhere in the chain with fn,
+// which may decline by returning false, e.g. for driver error codes it doesn't know. It is
+// the extension point for integrations with libraries whose errors carry codes, such as
+// SQLSTATEs, that decide the status, details and retryability together. The mapping
+// takes part in resolution like RegisterTypeFunc, with one exception: a joined error with
+// a member of type T resolves to fn's classification instead of to its most severe member,
+// since libraries such as golang-jwt join their own error with the underlying cause.
+func RegisterTypeClassifier[T error](fn func(T) (Classification, bool)) error {
+	if fn == nil {
+		return fmt.Errorf("errors: cannot register nil classifier for %s", reflect.TypeFor[T]())
+	}
+	c := classifier{typ: reflect.TypeFor[T]()}
+	c.classify = func(err error) (Classification, bool) {
+		var target T
+		if !errors.As(err, &target) {
+			return Classification{}, false
+		}
+		c, ok := fn(target)
+		if ok && !isValidStatus(c.Status) {
+			return Classification{}, false
+		}
+		return c, ok
+	}
+	if err := RegisterMatcher(func(err error) (ErrorMapping, bool) {
+		classification, ok := callClassifier(c, err)
+		return ErrorMapping{classification.Code, classification.Status}, ok
+	}); err != nil {
+		return err
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	classifiers = append(classifiers, c)
+	return nil
+}
+
+// classification returns the classification of an UpstreamError in err's chain, else of
+// the first registered classifier that applies to err, else of a transport failure.
+func classification(err error) (Classification, bool) {
+	if c, ok := upstreamClassification(err); ok {
+		return c, true
+	}
+	if c, ok := registeredClassification(err); ok {
+		return c, true
+	}
+	return networkClassification(err)
+}
+
+// registeredClassification returns the classification of the first registered classifier
+// that applies to err.
+func registeredClassification(err error) (Classification, bool) {
+	registryMu.RLock()
+	registered := append([]classifier(nil), classifiers...)
+	registryMu.RUnlock()
+
+	for _, classifier := range registered {
+		if c, ok := callClassifier(classifier, err); ok {
+			return c, true
+		}
+	}
+	return Classification{}, false
+}
+
+// callClassifier runs a classifier, logging panics and treating them as a non-match like
+// callMatcher.
+func callClassifier(classifier classifier, err error) (c Classification, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error(context.Background(), "errors: classifier for %s panicked on %T: %v", classifier.typ, err, r)
+			c, ok = Classification{}, false
+		}
+	}()
+	return classifier.classify(err)
+}
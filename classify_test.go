@@ -0,0 +1,84 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// panickyError is classified by a classifier that panics.
+type panickyError struct{}
+
+func (panickyError) Error() string { return "panicky" }
+
+func TestClassifierPanicLogged(t *testing.T) {
+	if err := RegisterTypeClassifier(func(panickyError) (Classification, bool) {
+		panic("classifier bug")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var status int
+	logs := captureLogs(t, func() {
+		status = StatusOf(Wrap(panickyError{}, "k", "v"))
+	})
+	if status != http.StatusInternalServerError {
+		t.Errorf("StatusOf() = %d, want %d", status, http.StatusInternalServerError)
+	}
+	if !strings.Contains(logs, "classifier for errors.panickyError panicked") || !strings.Contains(logs, "classifier bug") {
+		t.Errorf("logs = %q, want the classifier type and panic value", logs)
+	}
+	if strings.Contains(logs, "matcher panicked") {
+		t.Errorf("logs = %q, want the panic logged once, as the classifier's", logs)
+	}
+}
+
+// dialFailure wraps the joined errors of every address it tried, like pgconn.ConnectError.
+type dialFailure struct {
+	err error
+}
+
+func (e *dialFailure) Error() string { return "dial failed: " + e.err.Error() }
+
+func (e *dialFailure) Unwrap() error { return e.err }
+
+func TestClassifiedErrorWrappingJoin(t *testing.T) {
+	if err := RegisterTypeClassifier(func(*dialFailure) (Classification, bool) {
+		return Classification{Code: "TEST_DIAL_FAILURE", Status: http.StatusServiceUnavailable}, true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	err := fmt.Errorf("connecting: %w", &dialFailure{err: errors.Join(ErrorNotFound, ErrorConflict)})
+	if got := Code(err); got != "TEST_DIAL_FAILURE" {
+		t.Errorf("Code() = %s, want the wrapper's classification over the joined members", got)
+	}
+	if got := StatusOf(err); got != http.StatusServiceUnavailable {
+		t.Errorf("StatusOf() = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+}
+
+// tokenRejected is joined with the underlying cause, like golang-jwt's validation errors.
+type tokenRejected struct{}
+
+func (tokenRejected) Error() string { return "token rejected" }
+
+func TestClassifiedJoinMemberWins(t *testing.T) {
+	if err := RegisterTypeClassifier(func(tokenRejected) (Classification, bool) {
+		return Classification{Code: "TEST_TOKEN_REJECTED", Status: http.StatusUnauthorized}, true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{
+		errors.Join(ErrorConflict, tokenRejected{}),
+		fmt.Errorf("verifying: %w", errors.Join(tokenRejected{}, errors.New("signature is invalid"))),
+	} {
+		if got := Code(err); got != "TEST_TOKEN_REJECTED" {
+			t.Errorf("Code(%q) = %s, want the classified member's over the most severe member's", err, got)
+		}
+		if got := StatusOf(err); got != http.StatusUnauthorized {
+			t.Errorf("StatusOf(%q) = %d, want %d", err, got, http.StatusUnauthorized)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package errors
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/A-pen-app/logging"
+)
+
+// configMu guards the package-level rendering settings below.
+var configMu sync.RWMutex
+
+var (
+	codePrefix       string
+	exemptCodes      = map[ErrorCode]bool{}
+	unknownErrorHook func(ctx context.Context, err error)
+	// codeSeverities starts with codes that are expected rather than broken.
+	codeSeverities = map[ErrorCode]Severity{
+		KeyNotImplemented: SeverityWarn,
+	}
+)
+
+// DetailLimits caps the details built from client input, such as validation failures,
+// so that a request can't make the response or log line arbitrarily large.
+// Non-positive values disable a limit.
+type DetailLimits struct {
+	// MaxFields is the maximum number of entries in the "fields" details.
+	MaxFields int
+	// MaxValueLength is the maximum length in bytes of a value echoed back in the details.
+	// Longer strings are truncated and longer composite values are left out.
+	MaxValueLength int
+	// MaxDetailsBytes is the maximum size of the JSON-encoded details, and of the logged
+	// error message.
+	MaxDetailsBytes int
+}
+
+// DefaultDetailLimits are the limits in effect until SetDetailLimits is called.
+var DefaultDetailLimits = DetailLimits{
+	MaxFields:       50,
+	MaxValueLength:  256,
+	MaxDetailsBytes: 16 << 10,
+}
+
+// detailLimits holds the limits set by SetDetailLimits, guarded by configMu.
+var detailLimits = DefaultDetailLimits
+
+// SetDetailLimits replaces the limits applied to details built from client input.
+func SetDetailLimits(limits DetailLimits) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	detailLimits = limits
+}
+
+// currentDetailLimits returns the limits set by SetDetailLimits.
+func currentDetailLimits() DetailLimits {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return detailLimits
+}
+
+// SetCodePrefix sets a namespace prepended to every code sent to clients and logged,
+// e.g. "FEED" renders KeyNotFound as "FEED.NOT_FOUND". The ErrorCode constants used in
+// Go code are unaffected, and codes already starting with the prefix are sent as they
+// are. An empty prefix disables namespacing.
+func SetCodePrefix(prefix string) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	codePrefix = prefix
+}
+
+// ExemptFromCodePrefix keeps the given codes unprefixed when a code prefix is set.
+func ExemptFromCodePrefix(codes ...ErrorCode) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	for _, code := range codes {
+		exemptCodes[code] = true
+	}
+}
+
+// renderCode returns code as sent to clients, with the configured prefix applied unless
+// code already carries it, as codes received from a service sharing the prefix do.
+func renderCode(code ErrorCode) string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if codePrefix == "" || exemptCodes[code] || strings.HasPrefix(string(code), codePrefix+".") {
+		return string(code)
+	}
+	return codePrefix + "." + string(code)
+}
+
+// SetCodeSeverity sets the severity errors resolving to code are logged at, unless they
+// carry an explicit severity, e.g. to keep 503s during planned maintenance from paging
+// anyone. SeverityDefault restores the status-based default.
+func SetCodeSeverity(code ErrorCode, severity Severity) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if severity == SeverityDefault {
+		delete(codeSeverities, code)
+		return
+	}
+	codeSeverities[code] = severity
+}
+
+// codeSeverity returns the severity set for code, or SeverityDefault.
+func codeSeverity(code ErrorCode) Severity {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return codeSeverities[code]
+}
+
+// SetUnknownErrorHook sets a function called by handleError whenever an error has no
+// mapping and falls back to INTERNAL_ERROR, e.g. to count missing mappings. It receives
+// the original wrapped error and runs after logging but before the response is written.
+// Panics inside the hook are recovered. A nil hook disables it.
+func SetUnknownErrorHook(hook func(ctx context.Context, err error)) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	unknownErrorHook = hook
+}
+
+// callUnknownErrorHook runs the unknown error hook, if any, recovering from panics.
+func callUnknownErrorHook(ctx context.Context, err error) {
+	configMu.RLock()
+	hook := unknownErrorHook
+	configMu.RUnlock()
+	if hook == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error(ctx, "errors: unknown error hook panicked: %v", r)
+		}
+	}()
+	hook(ctx, err)
+}
@@ -0,0 +1,95 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetCodePrefix(t *testing.T) {
+	t.Cleanup(func() {
+		SetCodePrefix("")
+		configMu.Lock()
+		delete(exemptCodes, KeyUnauthorized)
+		configMu.Unlock()
+	})
+	ExemptFromCodePrefix(KeyUnauthorized)
+
+	tests := []struct {
+		name           string
+		err            error
+		wantUnprefixed string
+		wantPrefixed   string
+	}{
+		{"sentinel", ErrorNotFound, "NOT_FOUND", "FEED.NOT_FOUND"},
+		{"explicit code", New("TEST_POST_HIDDEN", http.StatusForbidden, "hidden"), "TEST_POST_HIDDEN", "FEED.TEST_POST_HIDDEN"},
+		{"already prefixed", New("FEED.TEST_POST_HIDDEN", http.StatusForbidden, "hidden"), "FEED.TEST_POST_HIDDEN", "FEED.TEST_POST_HIDDEN"},
+		{"other prefix", New("AUTH.TEST_EXPIRED", http.StatusForbidden, "expired"), "AUTH.TEST_EXPIRED", "FEED.AUTH.TEST_EXPIRED"},
+		{"prefix without separator", New("FEEDBACK_CLOSED", http.StatusForbidden, "closed"), "FEEDBACK_CLOSED", "FEED.FEEDBACK_CLOSED"},
+		{"exempt", ErrorUnauthorized, "UNAUTHORIZED", "UNAUTHORIZED"},
+	}
+	for _, mode := range []struct{ name, prefix string }{{"unprefixed", ""}, {"prefixed", "FEED"}} {
+		SetCodePrefix(mode.prefix)
+		for _, tt := range tests {
+			t.Run(mode.name+"/"+tt.name, func(t *testing.T) {
+				want := tt.wantUnprefixed
+				if mode.prefix != "" {
+					want = tt.wantPrefixed
+				}
+				if _, body := serve(t, returning(tt.err), WithLogging(false)); body.Code != want {
+					t.Errorf("code = %s, want %s", body.Code, want)
+				}
+			})
+		}
+	}
+}
+
+func TestSetUnknownErrorHook(t *testing.T) {
+	var got []error
+	SetUnknownErrorHook(func(_ context.Context, err error) {
+		got = append(got, err)
+	})
+	t.Cleanup(func() { SetUnknownErrorHook(nil) })
+
+	unmapped := Wrap(fmt.Errorf("querying posts: %w", io.ErrClosedPipe), "post_id", 7)
+	tests := []struct {
+		name     string
+		err      error
+		wantHook bool
+	}{
+		{"unmapped", unmapped, true},
+		{"sentinel", Wrap(ErrorNotFound, "post_id", 7), false},
+		{"explicit internal code", New(KeyInternalError, http.StatusInternalServerError, "boom"), false},
+		{"explicit status", WithStatus(fmt.Errorf("quota"), http.StatusTooManyRequests), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			serve(t, returning(tt.err), WithLogging(false))
+			if tt.wantHook != (len(got) == 1) {
+				t.Fatalf("hook calls = %v, want hook called %t", got, tt.wantHook)
+			}
+			if tt.wantHook && got[0] != tt.err {
+				t.Errorf("hook got %v, want the original wrapped error", got[0])
+			}
+		})
+	}
+}
+
+func TestUnknownErrorHookPanic(t *testing.T) {
+	SetUnknownErrorHook(func(context.Context, error) { panic("hook failed") })
+	t.Cleanup(func() { SetUnknownErrorHook(nil) })
+
+	var w *httptest.ResponseRecorder
+	var body HttpError
+	logs := captureLogs(t, func() {
+		w, body = serve(t, returning(fmt.Errorf("connection reset")))
+	})
+	if w.Code != http.StatusInternalServerError || body.Code != string(KeyInternalError) {
+		t.Errorf("response = %d %s, want 500 %s", w.Code, body.Code, KeyInternalError)
+	}
+	logLine(t, logs, "unknown error hook panicked: hook failed")
+}
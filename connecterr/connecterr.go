@@ -0,0 +1,95 @@
+// Package connecterr converts errors returned by connect-go handlers into *connect.Error
+// values, so that connect services share the error taxonomy of the HTTP ones.
+package connecterr
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/A-pen-app/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// MetadataKeyRequestID is the ErrorInfo metadata key holding the request ID.
+const MetadataKeyRequestID = errors.RPCMetadataKeyRequestID
+
+// NewInterceptor returns a server-side interceptor for unary and streaming handlers that
+// resolves and logs their errors as errors.Handle does, and returns them as a
+// *connect.Error:
+//
+//	mux.Handle(postsv1connect.NewPostServiceHandler(svc,
+//		connect.WithInterceptors(connecterr.NewInterceptor()),
+//	))
+//
+// Codes are converted with errors.RPCCodeFor, and the error message is the response
+// message. The error carries an errdetails.ErrorInfo whose reason is the error code and
+// whose metadata holds the details, with non-string values JSON-encoded, and the request
+// ID; a retry delay is carried as an errdetails.RetryInfo. Handlers returning a
+// *connect.Error themselves keep it, and client calls are left alone. Options taking a
+// *gin.Context don't apply.
+func NewInterceptor(opts ...errors.Option) connect.Interceptor {
+	return &interceptor{responder: errors.NewResponder(opts...)}
+}
+
+type interceptor struct {
+	responder *errors.Responder
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		resp, err := next(ctx, req)
+		if err != nil && !req.Spec().IsClient {
+			return resp, i.toConnectError(ctx, err)
+		}
+		return resp, err
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := next(ctx, conn); err != nil {
+			return i.toConnectError(ctx, err)
+		}
+		return nil
+	}
+}
+
+// toConnectError resolves and logs err, and returns the *connect.Error sent for it.
+func (i *interceptor) toConnectError(ctx context.Context, err error) error {
+	resp := i.responder.ResponseForContext(ctx, err)
+	if _, ok := err.(*connect.Error); ok {
+		return err
+	}
+	if resp.Body == nil {
+		return connect.NewError(connect.CodeCanceled, context.Canceled)
+	}
+	code := connect.Code(errors.RPCCodeFor(resp.Code, resp.Status))
+	connectErr := connect.NewError(code, stderrors.New(resp.Body.Message))
+	for _, msg := range errorDetails(resp) {
+		if detail, detailErr := connect.NewErrorDetail(msg); detailErr == nil {
+			connectErr.AddDetail(detail)
+		}
+	}
+	return connectErr
+}
+
+// errorDetails returns the error details for resp: an ErrorInfo, and a RetryInfo when
+// resp has a Retry-After header.
+func errorDetails(resp errors.Response) []proto.Message {
+	details := []proto.Message{&errdetails.ErrorInfo{Reason: resp.Body.Code, Metadata: errors.RPCMetadata(resp.Body)}}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		details = append(details, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(time.Duration(seconds) * time.Second),
+		})
+	}
+	return details
+}
@@ -0,0 +1,163 @@
+package connecterr
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/A-pen-app/errors"
+	"github.com/A-pen-app/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestMain(m *testing.M) {
+	logging.Initialize(nil)
+	m.Run()
+}
+
+const (
+	unaryProcedure  = "/test.v1.PostService/GetPost"
+	streamProcedure = "/test.v1.PostService/WatchPosts"
+)
+
+// serve serves a unary and a server-streaming procedure failing with err behind the
+// interceptor, and returns the server's URL.
+func serve(t *testing.T, err error, opts ...errors.Option) string {
+	t.Helper()
+	interceptors := connect.WithInterceptors(NewInterceptor(opts...))
+	mux := http.NewServeMux()
+	mux.Handle(unaryProcedure, connect.NewUnaryHandler(unaryProcedure,
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			return nil, err
+		}, interceptors))
+	mux.Handle(streamProcedure, connect.NewServerStreamHandler(streamProcedure,
+		func(_ context.Context, _ *connect.Request[emptypb.Empty], stream *connect.ServerStream[emptypb.Empty]) error {
+			if sendErr := stream.Send(&emptypb.Empty{}); sendErr != nil {
+				return sendErr
+			}
+			return err
+		}, interceptors))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// errorInfo returns the ErrorInfo detail of err.
+func errorInfo(t *testing.T, err *connect.Error) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, d := range err.Details() {
+		if msg, valueErr := d.Value(); valueErr == nil {
+			if info, ok := msg.(*errdetails.ErrorInfo); ok {
+				return info
+			}
+		}
+	}
+	t.Fatalf("error %v has no ErrorInfo", err)
+	return nil
+}
+
+// callUnary calls the unary procedure and returns its error.
+func callUnary(t *testing.T, url string) *connect.Error {
+	t.Helper()
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](http.DefaultClient, url+unaryProcedure)
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	var connectErr *connect.Error
+	if !stderrors.As(err, &connectErr) {
+		t.Fatalf("CallUnary() = %v, want a *connect.Error", err)
+	}
+	return connectErr
+}
+
+func TestInterceptorUnary(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   connect.Code
+		wantReason string
+	}{
+		{"not found", errors.Wrap(errors.ErrorNotFound, "post_id", 7), connect.CodeNotFound, "NOT_FOUND"},
+		{"wrong params", errors.ErrorWrongParams, connect.CodeInvalidArgument, "WRONG_PARAMETER"},
+		{"internal", errors.New(errors.KeyInternalError, http.StatusInternalServerError, "db password rejected"), connect.CodeInternal, "INTERNAL_ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := callUnary(t, serve(t, tt.err))
+			if err.Code() != tt.wantCode {
+				t.Errorf("code = %v, want %v", err.Code(), tt.wantCode)
+			}
+			if info := errorInfo(t, err); info.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", info.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestInterceptorDetails(t *testing.T) {
+	err := errors.WithRetryAfter(errors.Wrap(errors.ErrorTooManyRequests, "user_id", 123), 30*time.Second)
+	connectErr := callUnary(t, serve(t, err))
+	if connectErr.Code() != connect.CodeResourceExhausted {
+		t.Errorf("code = %v, want %v", connectErr.Code(), connect.CodeResourceExhausted)
+	}
+	if info := errorInfo(t, connectErr); info.Metadata["user_id"] != "123" {
+		t.Errorf("metadata = %v, want user_id=123", info.Metadata)
+	}
+	for _, d := range connectErr.Details() {
+		if msg, valueErr := d.Value(); valueErr == nil {
+			if retry, ok := msg.(*errdetails.RetryInfo); ok {
+				if got := retry.RetryDelay.AsDuration(); got != 30*time.Second {
+					t.Errorf("retry delay = %v, want 30s", got)
+				}
+				return
+			}
+		}
+	}
+	t.Error("error has no RetryInfo")
+}
+
+func TestInterceptorKeepsConnectErrors(t *testing.T) {
+	err := callUnary(t, serve(t, connect.NewError(connect.CodeAborted, errors.ErrorConflict)))
+	if err.Code() != connect.CodeAborted {
+		t.Errorf("code = %v, want %v", err.Code(), connect.CodeAborted)
+	}
+}
+
+func TestInterceptorCodePrefix(t *testing.T) {
+	errors.SetCodePrefix("FEED")
+	t.Cleanup(func() { errors.SetCodePrefix("") })
+
+	// DUPLICATE_ENTRY maps to AlreadyExists by code, where its 409 status would give
+	// Aborted, so the prefixed code sent must not be what is mapped.
+	err := callUnary(t, serve(t, errors.WithCode(errors.ErrorConflict, errors.KeyDuplicateEntry)))
+	if err.Code() != connect.CodeAlreadyExists {
+		t.Errorf("code = %v, want %v", err.Code(), connect.CodeAlreadyExists)
+	}
+	if info := errorInfo(t, err); info.Reason != "FEED.DUPLICATE_ENTRY" {
+		t.Errorf("reason = %q, want %q", info.Reason, "FEED.DUPLICATE_ENTRY")
+	}
+}
+
+func TestInterceptorStream(t *testing.T) {
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](http.DefaultClient, serve(t, errors.ErrorPermissionDenied)+streamProcedure)
+	stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	for stream.Receive() {
+	}
+	var connectErr *connect.Error
+	if !stderrors.As(stream.Err(), &connectErr) {
+		t.Fatalf("stream error = %v, want a *connect.Error", stream.Err())
+	}
+	if connectErr.Code() != connect.CodePermissionDenied {
+		t.Errorf("code = %v, want %v", connectErr.Code(), connect.CodePermissionDenied)
+	}
+	if info := errorInfo(t, connectErr); info.Reason != "PERMISSION_DENIED" {
+		t.Errorf("reason = %q, want %q", info.Reason, "PERMISSION_DENIED")
+	}
+}
@@ -0,0 +1,89 @@
+package errors
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// DetailKeyResource is the details key holding the resource identifier of a Conflict error.
+	DetailKeyResource = "resource"
+	// DetailKeyResourceType and DetailKeyResourceID are the details keys identifying
+	// the resource of a Gone error.
+	DetailKeyResourceType = "resource_type"
+	DetailKeyResourceID   = "resource_id"
+	// DetailKeyETag is the details key holding the current ETag of a PreconditionFailed
+	// error, which is also sent as the ETag header.
+	DetailKeyETag = "etag"
+	// DetailKeyFeature is the details key holding the feature name of a NotImplemented error.
+	DetailKeyFeature = "feature"
+	// DetailKeyRetryAfterSeconds is the details key holding the retry delay of a
+	// TooManyRequests or Unavailable error in whole seconds.
+	DetailKeyRetryAfterSeconds = "retry_after_seconds"
+)
+
+// Conflict returns ErrorConflict wrapped with the identifier of the conflicting resource,
+// e.g. "handle:alice", and the given key-value pairs. Use it for optimistic-locking
+// failures and unique-constraint violations; clients should refetch before retrying.
+func Conflict(resource string, keyValues ...any) error {
+	return Wrap(ErrorConflict, append([]any{DetailKeyResource, resource}, keyValues...)...)
+}
+
+// TooManyRequests returns ErrorTooManyRequests wrapped with the given key-value pairs.
+// A positive retryAfter is sent as a Retry-After header and as retry_after_seconds in the
+// details, both rounded up to whole seconds; zero or negative durations omit both.
+func TooManyRequests(retryAfter time.Duration, keyValues ...any) error {
+	return retryableAfter(ErrorTooManyRequests, retryAfter, keyValues)
+}
+
+// Unavailable returns ErrorUnavailable wrapped with the given key-value pairs, for planned
+// maintenance or an open circuit breaker. The error is retryable, and a positive retryAfter
+// is sent like in TooManyRequests. Use SetCodeSeverity with KeyServiceUnavailable to
+// change the severity these errors are logged at.
+func Unavailable(retryAfter time.Duration, keyValues ...any) error {
+	return retryableAfter(ErrorUnavailable, retryAfter, keyValues)
+}
+
+// retryableAfter wraps sentinel as retryable after retryAfter, adding the delay to the
+// details when positive.
+func retryableAfter(sentinel error, retryAfter time.Duration, keyValues []any) error {
+	if retryAfter > 0 {
+		keyValues = append([]any{DetailKeyRetryAfterSeconds, retryAfterSeconds(retryAfter)}, keyValues...)
+	}
+	return WithRetryAfter(Wrap(sentinel, keyValues...), retryAfter)
+}
+
+// NotImplemented returns ErrorNotImplemented wrapped with the name of the feature that
+// exists but isn't enabled here, e.g. behind a feature flag, and the given key-value pairs.
+// These errors are logged at Warn rather than Error by default.
+func NotImplemented(feature string, keyValues ...any) error {
+	return Wrap(ErrorNotImplemented, append([]any{DetailKeyFeature, feature}, keyValues...)...)
+}
+
+// Gone returns ErrorGone wrapped with the type and identifier of a resource that existed
+// but was deleted, so clients can tell it apart from one that never existed.
+func Gone(resourceType string, id any) error {
+	return Wrap(ErrorGone, DetailKeyResourceType, resourceType, DetailKeyResourceID, id)
+}
+
+// PreconditionFailed returns ErrorPreconditionFailed for a write whose If-Match header no
+// longer matches. currentETag, as sent in ETag headers including quotes, is added to the
+// details and sent as the ETag header so the client can refetch.
+func PreconditionFailed(currentETag string) error {
+	return Wrap(ErrorPreconditionFailed, DetailKeyETag, currentETag)
+}
+
+// PreconditionRequired returns ErrorPreconditionRequired for a write to an endpoint that
+// requires an If-Match header when none was supplied.
+func PreconditionRequired() error {
+	return Wrap(ErrorPreconditionRequired, "header", "If-Match")
+}
+
+// currentETag returns the ETag of a PreconditionFailed error in err's chain, if any.
+func currentETag(err error, status int) string {
+	if status != http.StatusPreconditionFailed {
+		return ""
+	}
+	etag, _ := GetData[string](err, DetailKeyETag)
+	return etag
+}
@@ -0,0 +1,135 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTooManyRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		retryAfter  time.Duration
+		wantHeader  string
+		wantSeconds any
+	}{
+		{"whole seconds", 30 * time.Second, "30", float64(30)},
+		{"rounded up", 1500 * time.Millisecond, "2", float64(2)},
+		{"zero", 0, "", nil},
+		{"negative", -5 * time.Second, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := serve(t, returning(TooManyRequests(tt.retryAfter, "user_id", 42)), WithLogging(false))
+			if w.Code != http.StatusTooManyRequests || body.Code != string(KeyTooManyRequests) {
+				t.Errorf("response = %d %s, want 429 %s", w.Code, body.Code, KeyTooManyRequests)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantHeader {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantHeader)
+			}
+			if got := body.Details[DetailKeyRetryAfterSeconds]; got != tt.wantSeconds {
+				t.Errorf("%s = %v, want %v", DetailKeyRetryAfterSeconds, got, tt.wantSeconds)
+			}
+			if body.Details["user_id"] != float64(42) {
+				t.Errorf("details = %v, want user_id=42", body.Details)
+			}
+		})
+	}
+}
+
+func TestConflict(t *testing.T) {
+	err := fmt.Errorf("renaming user: %w", Conflict("handle:alice", "user_id", 42))
+	if !errors.Is(err, ErrorConflict) {
+		t.Error("errors.Is(err, ErrorConflict) = false, want true")
+	}
+	if IsRetryable(err) {
+		t.Error("IsRetryable() = true, want false until the client refetches")
+	}
+	w, body := serve(t, returning(err), WithLogging(false))
+	if w.Code != http.StatusConflict || body.Code != string(KeyConflict) {
+		t.Errorf("response = %d %s, want 409 %s", w.Code, body.Code, KeyConflict)
+	}
+	if want := map[string]any{DetailKeyResource: "handle:alice", "user_id": float64(42)}; !reflect.DeepEqual(body.Details, want) {
+		t.Errorf("details = %v, want %v", body.Details, want)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		wantHeader string
+	}{
+		{"with delay", 90 * time.Second, "90"},
+		{"without delay", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("loading feed: %w", Unavailable(tt.retryAfter, "dependency", "recommender"))
+			if !errors.Is(err, ErrorUnavailable) || !IsRetryable(err) {
+				t.Errorf("errors.Is() = %t, IsRetryable() = %t, want both true", errors.Is(err, ErrorUnavailable), IsRetryable(err))
+			}
+			w, body := serve(t, returning(err), WithLogging(false))
+			if w.Code != http.StatusServiceUnavailable || body.Code != string(KeyServiceUnavailable) {
+				t.Errorf("response = %d %s, want 503 %s", w.Code, body.Code, KeyServiceUnavailable)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantHeader {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantHeader)
+			}
+			if body.Details["retryable"] != true || body.Details["dependency"] != "recommender" {
+				t.Errorf("details = %v, want retryable=true dependency=recommender", body.Details)
+			}
+		})
+	}
+}
+
+func TestUnavailableSeverity(t *testing.T) {
+	err := Unavailable(time.Minute, "reason", "maintenance")
+	if got := SeverityOf(err); got != SeverityError {
+		t.Errorf("SeverityOf() = %s, want error by default", got)
+	}
+	SetCodeSeverity(KeyServiceUnavailable, SeverityInfo)
+	t.Cleanup(func() { SetCodeSeverity(KeyServiceUnavailable, SeverityDefault) })
+	if got := SeverityOf(err); got != SeverityInfo {
+		t.Errorf("SeverityOf() = %s, want info after SetCodeSeverity", got)
+	}
+	if got := SeverityOf(WithSeverity(err, SeverityWarn)); got != SeverityWarn {
+		t.Errorf("SeverityOf() = %s, want the explicit warn", got)
+	}
+}
+
+func TestPreconditionFailed(t *testing.T) {
+	const etag = `"v42"`
+	err := fmt.Errorf("updating post: %w", PreconditionFailed(etag))
+	if !errors.Is(err, ErrorPreconditionFailed) {
+		t.Error("errors.Is(err, ErrorPreconditionFailed) = false, want true")
+	}
+	w, body := serve(t, returning(err), WithLogging(false))
+	if w.Code != http.StatusPreconditionFailed || body.Code != string(KeyPreconditionFailed) {
+		t.Errorf("response = %d %s, want 412 %s", w.Code, body.Code, KeyPreconditionFailed)
+	}
+	if got := w.Header().Get("ETag"); got != etag {
+		t.Errorf("ETag = %q, want %q", got, etag)
+	}
+	if body.Details[DetailKeyETag] != etag {
+		t.Errorf("details = %v, want %s=%s", body.Details, DetailKeyETag, etag)
+	}
+
+	w, _ = serve(t, returning(WithStatus(PreconditionFailed(etag), http.StatusConflict)), WithLogging(false))
+	if got := w.Header().Get("ETag"); got != "" {
+		t.Errorf("ETag = %q on a 409, want none", got)
+	}
+}
+
+func TestPreconditionRequired(t *testing.T) {
+	w, body := serve(t, returning(PreconditionRequired()), WithLogging(false))
+	if w.Code != http.StatusPreconditionRequired || body.Code != string(KeyPreconditionRequired) {
+		t.Errorf("response = %d %s, want 428 %s", w.Code, body.Code, KeyPreconditionRequired)
+	}
+	if body.Details["header"] != "If-Match" || w.Header().Get("ETag") != "" {
+		t.Errorf("response = %v %v, want header=If-Match and no ETag", body.Details, w.Header())
+	}
+}
@@ -0,0 +1,82 @@
+// Package echoadapter serves errors returned by Echo handlers in the same HttpError format
+// and with the same resolution and logging as the gin handlers of the errors package.
+package echoadapter
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/A-pen-app/errors"
+	"github.com/labstack/echo/v4"
+)
+
+// HTTPErrorHandler returns an echo.HTTPErrorHandler that writes errors as an HttpError:
+//
+//	e := echo.New()
+//	e.HTTPErrorHandler = echoadapter.HTTPErrorHandler()
+//
+// Errors resolve through the errors registry, except that an *echo.HTTPError, such as
+// echo's own 404 and 405, keeps its status, with its message sent for 4xx statuses. The
+// request ID is the trace ID of the OpenTelemetry span in the request context, falling
+// back to the X-Request-ID header. Options work as they do for errors.Handle, except for
+// those taking a *gin.Context. Errors returned after the response was committed are only
+// logged.
+func HTTPErrorHandler(opts ...errors.Option) echo.HTTPErrorHandler {
+	responder := errors.NewResponder(opts...)
+	return func(err error, c echo.Context) {
+		resp := responder.ResponseFor(c.Request(), fromHTTPError(err))
+		if c.Response().Committed {
+			return
+		}
+		for key, values := range resp.Header {
+			c.Response().Header()[key] = values
+		}
+		if resp.Body == nil || c.Request().Method == http.MethodHead {
+			c.NoContent(resp.Status)
+			return
+		}
+		if resp.Body.RequestID == "" {
+			resp.Body.RequestID = requestID(c)
+		}
+		c.JSON(resp.Status, resp.Body)
+	}
+}
+
+// fromHTTPError pins the status of an *echo.HTTPError in err's chain, so that an
+// internal error it carries can't change the response.
+func fromHTTPError(err error) error {
+	var httpErr *echo.HTTPError
+	if !stderrors.As(err, &httpErr) {
+		return err
+	}
+	status := httpErr.Code
+	err = errors.WithStatus(errors.WithCode(err, errors.CodeForStatus(status)), status)
+	if status < http.StatusInternalServerError {
+		err = errors.WithPublicMessage(err, message(httpErr))
+	}
+	return err
+}
+
+// message returns the client-facing message of an *echo.HTTPError.
+func message(httpErr *echo.HTTPError) string {
+	switch msg := httpErr.Message.(type) {
+	case string:
+		return msg
+	case error:
+		return msg.Error()
+	case nil:
+		return http.StatusText(httpErr.Code)
+	default:
+		return fmt.Sprint(msg)
+	}
+}
+
+// requestID returns the X-Request-ID set by echo's RequestID middleware or sent by the
+// client.
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
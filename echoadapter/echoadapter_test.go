@@ -0,0 +1,87 @@
+package echoadapter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/A-pen-app/errors"
+	"github.com/labstack/echo/v4"
+)
+
+// newEcho returns an echo server using HTTPErrorHandler, with a /posts/:id route failing
+// with err.
+func newEcho(err error) *echo.Echo {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler(errors.WithLogging(false))
+	e.GET("/posts/:id", func(echo.Context) error { return err })
+	return e
+}
+
+// do sends req to e and returns the response and its decoded HttpError.
+func do(t *testing.T, e *echo.Echo, req *http.Request) (*httptest.ResponseRecorder, errors.HttpError) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	var body errors.HttpError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+	}
+	return w, body
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		path        string
+		method      string
+		wantStatus  int
+		wantCode    errors.ErrorCode
+		wantMessage string
+	}{
+		{"sentinel", errors.Wrap(errors.ErrorNotFound, "post_id", 7), "/posts/7", http.MethodGet, http.StatusNotFound, errors.KeyNotFound, "data not found"},
+		{"echo HTTPError", echo.NewHTTPError(http.StatusBadRequest, "id must be numeric"), "/posts/x", http.MethodGet, http.StatusBadRequest, errors.KeyWrongParams, "id must be numeric"},
+		{"echo 5xx HTTPError", echo.NewHTTPError(http.StatusBadGateway, "upstream said: secret"), "/posts/7", http.MethodGet, http.StatusBadGateway, errors.CodeForStatus(http.StatusBadGateway), ""},
+		{"unknown route", nil, "/users", http.MethodGet, http.StatusNotFound, errors.KeyNotFound, "Not Found"},
+		{"wrong method", nil, "/posts/7", http.MethodDelete, http.StatusMethodNotAllowed, errors.CodeForStatus(http.StatusMethodNotAllowed), "Method Not Allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := do(t, newEcho(tt.err), httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantMessage != "" && body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Message, tt.wantMessage)
+			}
+			if body.Message == "upstream said: secret" {
+				t.Errorf("message = %q, want the 5xx message hidden", body.Message)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandlerRequestID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/posts/7", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	_, body := do(t, newEcho(errors.ErrorNotFound), req)
+	if body.RequestID != "req-1" {
+		t.Errorf("request_id = %q, want %q", body.RequestID, "req-1")
+	}
+}
+
+func TestHTTPErrorHandlerCommitted(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler(errors.WithLogging(false))
+	e.GET("/posts", func(c echo.Context) error {
+		c.String(http.StatusOK, "[]")
+		return errors.ErrorConflict
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Errorf("response = %d %q, want the committed 200 %q", w.Code, w.Body.String(), "[]")
+	}
+}
@@ -1,75 +1,628 @@
 package errors
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/A-pen-app/logging"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// New creates an ad-hoc error with an explicit code and HTTP status, for errors that
+// don't warrant a package-level sentinel. The message is sent to clients as-is, even
+// for 5xx statuses. Statuses outside 100-599 fall back to 500. The caller's stack is
+// recorded for %+v.
+func New(code ErrorCode, status int, message string) error {
+	if !isValidStatus(status) {
+		status = http.StatusInternalServerError
+	}
+	return &AppError{
+		cause:     errors.New(message),
+		code:      code,
+		status:    status,
+		publicMsg: message,
+		data:      make(map[string]any),
+		stack:     callers(0),
+	}
+}
+
 // Wrap wraps an error with additional context data.
-// Works for both business logic errors and system errors.
+// Works for both business logic errors and system errors. The caller's stack is recorded
+// for %+v, unless an AppError in err's chain already recorded one.
 func Wrap(err error, keyValues ...any) error {
 	if err == nil {
 		return nil
 	}
-	data := parseKeyValues(keyValues)
+	data, keys := parseKeyValues(keyValues)
 	return &AppError{
 		cause: err,
 		data:  data,
+		keys:  keys,
+		stack: stackFor(err),
+	}
+}
+
+// WrapMap wraps an error with the given data map, which is copied rather than aliased.
+// If err is already an AppError the data is merged into a copy of it instead of adding a layer.
+// The stack is recorded as by Wrap.
+func WrapMap(err error, data map[string]any) error {
+	if err == nil {
+		return nil
+	}
+	if appErr, ok := err.(*AppError); ok {
+		return appErr.WithAll(data)
+	}
+
+	copied := make(map[string]any, len(data))
+	for k, v := range data {
+		copied[k] = wrapLazy(v)
+	}
+	return &AppError{
+		cause: err,
+		data:  copied,
+		keys:  sortedKeys(copied),
+		stack: stackFor(err),
+	}
+}
+
+// Wrapf wraps an error with a formatted context message, similar to fmt.Errorf.
+// The original error is kept as the cause so error mapping is unaffected. The stack is
+// recorded as by Wrap.
+func Wrapf(err error, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	return &AppError{
+		cause: err,
+		msg:   fmt.Sprintf(format, args...),
+		data:  make(map[string]any),
+		stack: stackFor(err),
+	}
+}
+
+// WithCode wraps an error with an ErrorCode that overrides the mapped code in the response.
+// The mapped HTTP status is kept, and the override survives further wrapping.
+func WithCode(err error, code ErrorCode) error {
+	if err == nil {
+		return nil
+	}
+	return &AppError{
+		cause: err,
+		code:  code,
+		data:  make(map[string]any),
+	}
+}
+
+// WithStatus wraps an error with an HTTP status that overrides the mapped status in the response.
+// The mapped code is kept. Statuses outside 100-599 are ignored, with a warning logged
+// when the error is handled.
+func WithStatus(err error, status int) error {
+	if err == nil {
+		return nil
+	}
+	if !isValidStatus(status) {
+		return &AppError{
+			cause:         err,
+			invalidStatus: &status,
+			data:          make(map[string]any),
+		}
+	}
+	return &AppError{
+		cause:  err,
+		status: status,
+		data:   make(map[string]any),
+	}
+}
+
+// Code returns the ErrorCode that handleError would send for err.
+// Unknown errors resolve to KeyInternalError; a nil error returns an empty code.
+func Code(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	_, mapping := resolveError(err)
+	return mapping.Code
+}
+
+// CodeEquals reports whether err and target resolve to the same code, e.g. a service's
+// own not-found sentinel and ErrorNotFound. Errors resolving to KeyInternalError are never
+// equal, so unrelated failures don't compare as equivalent, and nil equals nothing.
+func CodeEquals(err, target error) bool {
+	if err == nil || target == nil {
+		return false
+	}
+	code := Code(err)
+	return code != KeyInternalError && code == Code(target)
+}
+
+// StatusOf returns the HTTP status that handleError would send for err.
+// Unknown errors resolve to 500; a nil error returns 200.
+func StatusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	_, mapping := resolveError(err)
+	return mapping.StatusCode
+}
+
+// DetailsOf merges the data of every AppError in err's Unwrap chain into a single map,
+// including the members of joined errors. Outer layers (and earlier join members) win
+// when the same key is set more than once.
+func DetailsOf(err error) map[string]any {
+	details := make(map[string]any)
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok {
+			for k, v := range appErr.data {
+				if _, exists := details[k]; !exists {
+					details[k] = resolveValue(v)
+				}
+			}
+		}
+		return true
+	})
+	return details
+}
+
+// GetData returns the value stored under key by the nearest AppError in err's chain.
+// Integer values are converted between integer types when the value fits, so a value
+// stored as int can be read as int64. It returns the zero value and false when the key
+// is missing, the value is not assignable to T, or err holds no AppError.
+func GetData[T any](err error, key string) (T, bool) {
+	var zero T
+	var value any
+	found := false
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok {
+			value, found = appErr.data[key]
+		}
+		return !found
+	})
+	if !found {
+		return zero, false
+	}
+	value = resolveValue(value)
+
+	if typed, ok := value.(T); ok {
+		return typed, true
+	}
+	if converted, ok := convertInteger(value, reflect.TypeOf(zero)); ok {
+		return converted.Interface().(T), true
+	}
+	return zero, false
+}
+
+// convertInteger converts an integer value to another integer type when it fits without overflow.
+func convertInteger(value any, target reflect.Type) (reflect.Value, bool) {
+	if value == nil || target == nil {
+		return reflect.Value{}, false
+	}
+	rv := reflect.ValueOf(value)
+	out := reflect.New(target).Elem()
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := rv.Int()
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if out.OverflowInt(n) {
+				return reflect.Value{}, false
+			}
+			out.SetInt(n)
+			return out, true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n < 0 || out.OverflowUint(uint64(n)) {
+				return reflect.Value{}, false
+			}
+			out.SetUint(uint64(n))
+			return out, true
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := rv.Uint()
+		switch target.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n > uint64(1<<63-1) || out.OverflowInt(int64(n)) {
+				return reflect.Value{}, false
+			}
+			out.SetInt(int64(n))
+			return out, true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if out.OverflowUint(n) {
+				return reflect.Value{}, false
+			}
+			out.SetUint(n)
+			return out, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// WithType wraps an error with an ErrorType used to categorize it in logs and responses.
+// The type survives further wrapping, with the outermost type winning.
+func WithType(err error, t ErrorType) error {
+	if err == nil {
+		return nil
+	}
+	return &AppError{
+		cause:   err,
+		errType: t,
+		data:    make(map[string]any),
+	}
+}
+
+// TypeOf returns the ErrorType of the outermost AppError in err's chain that carries one.
+func TypeOf(err error) ErrorType {
+	var errType ErrorType
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok {
+			errType = appErr.errType
+		}
+		return errType == ""
+	})
+	return errType
+}
+
+// IsClientError reports whether err resolves to a 4xx status, i.e. the caller's fault.
+// It returns false for nil.
+func IsClientError(err error) bool {
+	return err != nil && isClientStatus(StatusOf(err))
+}
+
+// IsServerError reports whether err resolves to a 5xx status. Unknown errors are server
+// errors. It returns false for nil.
+func IsServerError(err error) bool {
+	return err != nil && isServerStatus(StatusOf(err))
+}
+
+func isClientStatus(status int) bool {
+	return status >= 400 && status < 500
+}
+
+func isServerStatus(status int) bool {
+	return status >= 500 && status < 600
+}
+
+// statusClass names the classification of status used in log fields.
+func statusClass(status int) string {
+	switch {
+	case isClientStatus(status):
+		return "client"
+	case isServerStatus(status):
+		return "server"
 	}
+	return "other"
 }
 
 // HandlerFunc defines a Gin handler function that returns an error.
 type HandlerFunc func(*gin.Context) error
 
 // Handle wraps a HandlerFunc to automatically handle errors using the unified error handling system.
-func Handle(fn HandlerFunc) gin.HandlerFunc {
+// Options customize error handling for this handler only, on top of the global options set
+// by Configure, and are validated immediately.
+func Handle(fn HandlerFunc, opts ...Option) gin.HandlerFunc {
+	configs := newConfigCache(opts)
 	return func(ctx *gin.Context) {
 		if err := fn(ctx); err != nil {
-			handleError(ctx, err)
+			handleError(ctx, err, configs.get())
+		}
+	}
+}
+
+// joinedMessages returns the messages of the first multi-error found in err's chain.
+func joinedMessages(err error) []string {
+	var messages []string
+	walkErrors(err, func(err error) bool {
+		errs := multiErrors(err)
+		if errs == nil {
+			return true
+		}
+		for _, member := range errs {
+			if member != nil {
+				messages = append(messages, redactURLs(member, member.Error()))
+			}
+		}
+		return false
+	})
+	return messages
+}
+
+// causeDetails returns the response details derived from well-known causes in err's
+// chain, such as the body size limit of an *http.MaxBytesError. Wrap data wins over
+// these on duplicate keys. body, when known, locates JSON syntax errors, and lt, when not
+// nil, translates validation messages.
+func causeDetails(err error, body []byte, lt *localeTranslator) map[string]any {
+	details := make(map[string]any)
+	if c, ok := classification(err); ok {
+		for k, v := range c.Details {
+			details[k] = v
+		}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		details["offset"] = syntaxErr.Offset
+		if line, column, ok := lineColumn(body, syntaxErr.Offset); ok {
+			details["line"] = line
+			details["column"] = column
+		}
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		details["limit_bytes"] = maxBytesErr.Limit
+	}
+	if validationErrs := validationErrors(err); len(validationErrs) > 0 {
+		violations := fieldViolations(validationErrs, fieldNamerFor(err), lt)
+		if limit := currentDetailLimits().MaxFields; limit > 0 && len(violations) > limit {
+			details["fields_total"] = len(violations)
+			details["fields_shown"] = limit
+			details["truncated"] = true
+			violations = violations[:limit]
+		}
+		details["fields"] = violations
+		if lt != nil {
+			details["locale"] = lt.locale
+		}
+	}
+	var unmarshalErr *json.UnmarshalTypeError
+	if errors.As(err, &unmarshalErr) {
+		for k, v := range unmarshalTypeDetails(unmarshalErr) {
+			details[k] = v
+		}
+	}
+	var bindingErr *BindingError
+	if errors.As(err, &bindingErr) {
+		details["source"] = bindingErr.Source
+		if bindingErr.Parameter != "" {
+			details["parameter"] = bindingErr.Parameter
+		}
+		if bindingErr.Value != "" {
+			details["value"] = bindingErr.Value
+		}
+	}
+	var mediaTypeErr *UnsupportedMediaTypeError
+	if errors.As(err, &mediaTypeErr) {
+		details["content_type"] = mediaTypeErr.Received
+		details["expected_content_types"] = mediaTypeErr.Expected
+	}
+	return details
+}
+
+// stripReservedKeys removes the reserved wrap keys from details. Their effects are
+// applied during resolution; values of the wrong type are logged and ignored.
+func stripReservedKeys(ctx context.Context, details map[string]any) {
+	if value, exists := details[WrapKeyHTTPStatus]; exists {
+		delete(details, WrapKeyHTTPStatus)
+		if status, ok := value.(int); !ok || !isValidStatus(status) {
+			logging.Warn(ctx, "errors: ignoring invalid %s value %v", WrapKeyHTTPStatus, value)
+		}
+	}
+
+	if value, exists := details[WrapKeyPublicMessage]; exists {
+		delete(details, WrapKeyPublicMessage)
+		if _, ok := value.(string); !ok {
+			logging.Warn(ctx, "errors: ignoring invalid %s value %v", WrapKeyPublicMessage, value)
+		}
+	}
+
+	if value, exists := details[WrapKeyRetryAfter]; exists {
+		delete(details, WrapKeyRetryAfter)
+		if _, ok := retryAfterValue(value); !ok {
+			logging.Warn(ctx, "errors: ignoring invalid %s value %v", WrapKeyRetryAfter, value)
+		}
+	}
+
+	if value, exists := details[WrapKeyAuthChallenge]; exists {
+		delete(details, WrapKeyAuthChallenge)
+		if _, ok := value.(string); !ok {
+			logging.Warn(ctx, "errors: ignoring invalid %s value %v", WrapKeyAuthChallenge, value)
 		}
 	}
 }
 
-// handleError processes an error and sends a structured JSON response to the client.
+// formatRetryAfter renders d as a Retry-After header value in whole seconds, rounded up.
+func formatRetryAfter(d time.Duration) string {
+	return strconv.FormatInt(retryAfterSeconds(d), 10)
+}
+
+// retryAfterSeconds returns d in whole seconds, rounded up.
+func retryAfterSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// handleError processes an error and sends a structured response to the client, as JSON
+// unless the Accept header asks for XML or plain text.
 // It separates internal error context (logged) from external API messages (sent to frontend).
-func handleError(ctx *gin.Context, err error) {
+// Settings come from cfg; a nil cfg uses the global options.
+func handleError(ctx *gin.Context, err error, cfg *handlerConfig) {
+	respondError(ctx, err, cfg, ctx.Writer.Written())
+}
+
+// respondError is handleError for a response that may have started already, as streaming
+// handlers' responses do. A started response can't carry the error response, so the error
+// is only logged, with a response_started field, and passed to the stream error writer.
+func respondError(ctx *gin.Context, err error, cfg *handlerConfig, started bool) {
 	if err == nil {
 		return
 	}
+	if cfg == nil {
+		cfg = effectiveConfig(nil)
+	}
 
-	// Extract actual error for key and status determination
-	var actualErr error
-	var details map[string]any
+	scope := ginScope(ctx, cfg)
+	scope.responseStarted = started
+	resp := buildResponse(err, cfg, scope)
+	if started {
+		if !resp.clientGone && cfg.streamErrorWriter != nil {
+			resp.body.RequestID = cfg.requestID(ctx)
+			cfg.streamErrorWriter(ctx, resp.body)
+		}
+		ctx.Abort()
+		return
+	}
+	if resp.clientGone {
+		ctx.AbortWithStatus(resp.status)
+		return
+	}
+	for key, values := range resp.header {
+		for _, value := range values {
+			ctx.Header(key, value)
+		}
+	}
+
+	// Send error response
+	resp.body.RequestID = cfg.requestID(ctx)
+	cfg.encode(ctx, resp.status, resp.body)
+}
 
-	var appErr *AppError
-	if errors.As(err, &appErr) {
-		actualErr = appErr.Unwrap()
-		details = appErr.Data()
-	} else {
-		actualErr = err
-		details = make(map[string]any)
+// ginScope returns the requestScope of a gin request.
+func ginScope(ctx *gin.Context, cfg *handlerConfig) requestScope {
+	return requestScope{
+		ctx:            ctx.Request.Context(),
+		acceptLanguage: ctx.GetHeader("Accept-Language"),
+		body:           requestBody(ctx),
+		route:          ctx.FullPath(),
+		legacyCodes:    cfg.legacyCodes(ctx),
 	}
+}
+
+// requestScope is what error handling needs to know about the request that failed,
+// independent of the framework serving it.
+type requestScope struct {
+	ctx            context.Context
+	acceptLanguage string
+	// body holds the request body bytes known to the framework, if any.
+	body []byte
+	// route is the matched route pattern, used in logs.
+	route string
+	// legacyCodes reports whether deprecated codes should be sent.
+	legacyCodes bool
+	// responseStarted reports whether the response was already started.
+	responseStarted bool
+	// messageAction is how a queue message that failed was settled, if any.
+	messageAction string
+}
+
+// errorResponse is the response built for an error: status, resolved code, headers and
+// body, or only a status and code when the client is gone. The body's RequestID is left
+// to the caller.
+type errorResponse struct {
+	status     int
+	code       ErrorCode
+	header     http.Header
+	body       HttpError
+	clientGone bool
+}
+
+// buildResponse resolves err, logs it and builds the response to send for it.
+func buildResponse(err error, cfg *handlerConfig, scope requestScope) errorResponse {
+	reqCtx := scope.ctx
 
 	// Unified processing
-	mapping := getErrorMapping(actualErr)
-	errorKey := string(mapping.Code)
+	details := DetailsOf(err)
+	lt := negotiateLocale(scope.acceptLanguage)
+	for k, v := range causeDetails(err, scope.body, lt) {
+		if _, exists := details[k]; !exists {
+			details[k] = v
+		}
+	}
+	if messages := joinedMessages(err); len(messages) > 0 && !cfg.productionMessages {
+		details["errors"] = messages
+	}
+	r := resolve(err, cfg)
+	mapping := r.mapping
+	errorKey := renderCode(cfg.responseCode(reqCtx, mapping.Code, scope.legacyCodes, scope.route))
 	status := mapping.StatusCode
-	logging.Error(ctx.Request.Context(), err.Error())
+	warnInvalidStatuses(reqCtx, err)
+	message := cfg.message(err, r, lt)
+	errType := TypeOf(err)
+	logFields := []any{"code", errorKey, "class", statusClass(status)}
+	if errType != "" {
+		logFields = append(logFields, "type", string(errType))
+	}
+	var invalidValidationErr *validator.InvalidValidationError
+	if errors.As(err, &invalidValidationErr) {
+		logFields = append(logFields, "validated_type", fmt.Sprint(invalidValidationErr.Type))
+	}
+	if scope.responseStarted {
+		logFields = append(logFields, "response_started", true)
+	}
+	if scope.messageAction != "" {
+		logFields = append(logFields, "message_action", scope.messageAction)
+	}
+	logFields = append(logFields, cfg.logFields...)
+	// A client that went away can't read the response, so there's nothing to alert on
+	clientGone := errors.Is(reqCtx.Err(), context.Canceled)
+	if !cfg.suppressLogging {
+		severity := severityFor(err, mapping)
+		if clientGone {
+			severity = SeverityInfo
+		}
+		msg := truncateString(err.Error(), currentDetailLimits().MaxDetailsBytes)
+		logError(reqCtx, severity, msg, logFields...)
+	}
+	if !r.known {
+		callUnknownErrorHook(reqCtx, err)
+	}
+	if clientGone {
+		return errorResponse{status: status, code: mapping.Code, clientGone: true}
+	}
+
+	// Reserved wrap keys are never sent as details
+	stripReservedKeys(reqCtx, details)
 
-	// Get request ID for tracing
-	requestID := ""
-	if spanCtx := trace.SpanContextFromContext(ctx.Request.Context()); spanCtx.IsValid() && spanCtx.TraceID().IsValid() {
-		requestID = spanCtx.TraceID().String()
+	header := make(http.Header)
+	if challenge := AuthChallenge(err); challenge != "" {
+		header.Set("WWW-Authenticate", challenge)
 	}
 
-	// Send error response
-	ctx.AbortWithStatusJSON(status, HttpError{
-		Code:      errorKey,
-		Message:   actualErr.Error(),
-		Details:   details,
-		RequestID: requestID,
+	if etag := currentETag(err, status); etag != "" {
+		header.Set("ETag", etag)
+	}
+
+	// Signal retryability to the client
+	if isRetryable(err, status) {
+		details["retryable"] = true
+		if retryAfter := RetryAfter(err); retryAfter > 0 {
+			header.Set("Retry-After", formatRetryAfter(retryAfter))
+		}
+	}
+
+	capDetails(details, currentDetailLimits().MaxDetailsBytes)
+
+	return errorResponse{
+		status: status,
+		code:   mapping.Code,
+		header: header,
+		body: HttpError{
+			Code:    errorKey,
+			Type:    string(errType),
+			Message: message,
+			Details: details,
+		},
+	}
+}
+
+// requestIDFromContext returns the trace ID of the OpenTelemetry span in ctx, if any.
+func requestIDFromContext(ctx context.Context) string {
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() && spanCtx.TraceID().IsValid() {
+		return spanCtx.TraceID().String()
+	}
+	return ""
+}
+
+// warnInvalidStatuses logs the WithStatus overrides in err's chain that were ignored
+// for being outside 100-599.
+func warnInvalidStatuses(ctx context.Context, err error) {
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok && appErr.invalidStatus != nil {
+			logging.Warn(ctx, "errors: ignoring invalid status override %d", *appErr.invalidStatus)
+		}
+		return true
 	})
 }
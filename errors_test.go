@@ -0,0 +1,618 @@
+package errors
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/A-pen-app/logging"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMain(m *testing.M) {
+	logging.Initialize(nil)
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// serve runs a request through a gin router serving fn with Handle and returns the
+// recorded response along with its decoded body.
+func serve(t *testing.T, fn HandlerFunc, opts ...Option) (*httptest.ResponseRecorder, HttpError) {
+	t.Helper()
+	return serveRequest(t, httptest.NewRequest(http.MethodGet, "/test", nil), fn, opts...)
+}
+
+// serveRequest is serve for a given request, routed to fn under its method and path.
+func serveRequest(t *testing.T, req *http.Request, fn HandlerFunc, opts ...Option) (*httptest.ResponseRecorder, HttpError) {
+	t.Helper()
+	router := gin.New()
+	router.Handle(req.Method, req.URL.Path, Handle(fn, opts...))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var body HttpError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+	}
+	return w, body
+}
+
+// captureLogs returns what the logging package writes while fn runs.
+func captureLogs(t *testing.T, fn func()) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stderr := os.Stderr
+	os.Stderr = f
+	logging.Initialize(nil)
+	defer func() {
+		os.Stderr = stderr
+		logging.Initialize(nil)
+	}()
+	fn()
+	logging.Finalize()
+	out, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// testTraceID is the trace ID withTrace puts in request contexts.
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+// withTrace returns req with an OpenTelemetry span context carrying testTraceID.
+func withTrace(req *http.Request) *http.Request {
+	traceID, _ := trace.TraceIDFromHex(testTraceID)
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+	return req.WithContext(trace.ContextWithSpanContext(req.Context(), spanCtx))
+}
+
+// returning returns a handler failing with err.
+func returning(err error) HandlerFunc {
+	return func(*gin.Context) error { return err }
+}
+
+func TestWithStatus(t *testing.T) {
+	err := Wrap(WithStatus(ErrorNotAllowed, http.StatusBadRequest), "k", "v")
+	w, body := serve(t, returning(err))
+	if w.Code != http.StatusBadRequest || body.Code != string(KeyNotAllowed) {
+		t.Errorf("response = %d %s, want 400 %s", w.Code, body.Code, KeyNotAllowed)
+	}
+	if body.Details["k"] != "v" {
+		t.Errorf("details = %v, want k=v", body.Details)
+	}
+	if !errors.Is(err, ErrorNotAllowed) {
+		t.Error("errors.Is(err, ErrorNotAllowed) = false, want true")
+	}
+	if got := errorMappings[ErrorNotAllowed].StatusCode; got != http.StatusForbidden {
+		t.Errorf("registered status = %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+func TestWithStatusOutermostWins(t *testing.T) {
+	err := WithStatus(Wrap(WithStatus(ErrorNotFound, http.StatusGone), "k", "v"), http.StatusBadRequest)
+	if w, _ := serve(t, returning(err)); w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestWithStatusInvalid(t *testing.T) {
+	for _, status := range []int{0, 42, 600} {
+		if w, _ := serve(t, returning(WithStatus(ErrorNotFound, status))); w.Code != http.StatusNotFound {
+			t.Errorf("status of WithStatus(ErrorNotFound, %d) = %d, want %d", status, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestWithStatusInvalidLoggedWithRequestContext(t *testing.T) {
+	var err error
+	logs := captureLogs(t, func() {
+		err = WithStatus(ErrorNotFound, 42)
+	})
+	if strings.Contains(logs, "invalid status") {
+		t.Errorf("WithStatus logged %q, want the warning deferred until the error is handled", logs)
+	}
+
+	logs = captureLogs(t, func() {
+		serveRequest(t, withTrace(httptest.NewRequest(http.MethodGet, "/test", nil)), returning(err))
+	})
+	var warning string
+	for _, line := range strings.Split(logs, "\n") {
+		if strings.Contains(line, "ignoring invalid status override 42") {
+			if warning != "" {
+				t.Fatalf("warning logged more than once:\n%s", logs)
+			}
+			warning = line
+		}
+	}
+	if !strings.Contains(warning, testTraceID) {
+		t.Errorf("warning %q doesn't carry the request's trace ID %s", warning, testTraceID)
+	}
+}
+
+func TestHandleCanceledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/feed", nil).WithContext(ctx)
+	err := fmt.Errorf("loading feed: %w", ctx.Err())
+
+	router := gin.New()
+	router.GET("/feed", Handle(returning(err)))
+	w := httptest.NewRecorder()
+	logs := captureLogs(t, func() {
+		router.ServeHTTP(w, req)
+	})
+	if w.Code != StatusClientClosedRequest {
+		t.Errorf("status = %d, want %d", w.Code, StatusClientClosedRequest)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("body = %q, want none for a client that went away", w.Body.String())
+	}
+	if line := logLine(t, logs, "loading feed"); !strings.Contains(line, "INFO") {
+		t.Errorf("log line = %q, want level INFO", line)
+	}
+}
+
+func TestHandleCanceledDownstreamCall(t *testing.T) {
+	w, body := serve(t, returning(fmt.Errorf("calling feed: %w", context.Canceled)), WithLogging(false))
+	if w.Code != StatusClientClosedRequest || body.Code != string(KeyClientClosedRequest) {
+		t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, StatusClientClosedRequest, KeyClientClosedRequest)
+	}
+}
+
+func TestHandleDeadlineExceeded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"fmt wrapped", fmt.Errorf("calling feed: %w", ctx.Err())},
+		{"AppError wrapped", Wrap(ctx.Err(), "dependency", "feed")},
+		{"nested", fmt.Errorf("loading timeline: %w", Wrap(fmt.Errorf("calling feed: %w", context.DeadlineExceeded), "attempt", 2))},
+		{"os deadline", Wrap(fmt.Errorf("reading body: %w", os.ErrDeadlineExceeded), "bytes", 512)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := serve(t, returning(tt.err), WithLogging(false))
+			if w.Code != http.StatusGatewayTimeout || body.Code != string(KeyGatewayTimeout) {
+				t.Errorf("response = %d %s, want 504 %s", w.Code, body.Code, KeyGatewayTimeout)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	err := Wrap(New(KeyConflict, http.StatusConflict, "handle already taken"), "handle", "gopher")
+	w, body := serve(t, returning(err), WithLogging(false))
+	if w.Code != http.StatusConflict || body.Code != string(KeyConflict) {
+		t.Errorf("response = %d %s, want 409 %s", w.Code, body.Code, KeyConflict)
+	}
+	if body.Message != "handle already taken" {
+		t.Errorf("message = %q, want the message given to New", body.Message)
+	}
+	if body.Details["handle"] != "gopher" {
+		t.Errorf("details = %v, want handle=gopher", body.Details)
+	}
+	if !errors.Is(err, ErrorConflict) {
+		t.Error("errors.Is(err, ErrorConflict) = false, want true for the same code")
+	}
+	if errors.Is(err, ErrorNotFound) {
+		t.Error("errors.Is(err, ErrorNotFound) = true, want false for another code")
+	}
+}
+
+func TestNewServerErrorMessage(t *testing.T) {
+	_, body := serve(t, returning(New(KeyServiceUnavailable, http.StatusServiceUnavailable, "down for maintenance")), WithLogging(false))
+	if body.Message != "down for maintenance" {
+		t.Errorf("message = %q, want the message sent as-is for a 5xx", body.Message)
+	}
+}
+
+func TestNewInvalidStatus(t *testing.T) {
+	for _, status := range []int{0, 42, 600} {
+		if got := StatusOf(New("TEST_BROKEN", status, "broken")); got != http.StatusInternalServerError {
+			t.Errorf("StatusOf(New(%d)) = %d, want %d", status, got, http.StatusInternalServerError)
+		}
+	}
+}
+
+func TestWithCode(t *testing.T) {
+	errPostNotFound := WithCode(ErrorNotFound, "TEST_POST_NOT_FOUND")
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"direct", errPostNotFound},
+		{"wrapped", Wrap(errPostNotFound, "post_id", 7)},
+		{"nested wraps", Wrapf(fmt.Errorf("handler: %w", Wrap(fmt.Errorf("service: %w", errPostNotFound), "post_id", 7)), "loading")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(tt.err); got != "TEST_POST_NOT_FOUND" {
+				t.Errorf("Code() = %s, want the override", got)
+			}
+			if got := StatusOf(tt.err); got != http.StatusNotFound {
+				t.Errorf("StatusOf() = %d, want the sentinel's %d", got, http.StatusNotFound)
+			}
+			if !errors.Is(tt.err, ErrorNotFound) {
+				t.Error("errors.Is(err, ErrorNotFound) = false, want the sentinel reachable")
+			}
+		})
+	}
+}
+
+func TestWithCodeOutermostWins(t *testing.T) {
+	err := WithCode(Wrap(WithCode(ErrorNotFound, "TEST_INNER"), "k", "v"), "TEST_OUTER")
+	if _, body := serve(t, returning(err), WithLogging(false)); body.Code != "TEST_OUTER" {
+		t.Errorf("code = %s, want TEST_OUTER", body.Code)
+	}
+}
+
+func TestWrapMapCopiesData(t *testing.T) {
+	data := map[string]any{"post_id": 7, "tenant": "acme"}
+	err := WrapMap(ErrorNotFound, data)
+	data["post_id"] = 8
+	data["secret"] = "leaked"
+	delete(data, "tenant")
+
+	want := map[string]any{"post_id": 7, "tenant": "acme"}
+	if got := DetailsOf(err); !reflect.DeepEqual(got, want) {
+		t.Errorf("DetailsOf() = %v after mutating the input map, want %v", got, want)
+	}
+	if got := err.Error(); got != "data not found: post_id=7 tenant=acme" {
+		t.Errorf("Error() = %q, want the data as given to WrapMap", got)
+	}
+}
+
+func TestWrapMapMergesIntoAppError(t *testing.T) {
+	base := Wrap(ErrorNotFound, "post_id", 7)
+	err := WrapMap(base, map[string]any{"post_id": 8, "tenant": "acme"})
+	if got := DetailsOf(err); got["post_id"] != 8 || got["tenant"] != "acme" {
+		t.Errorf("DetailsOf() = %v, want post_id=8 tenant=acme", got)
+	}
+	if got := DetailsOf(base); got["post_id"] != 7 || len(got) != 1 {
+		t.Errorf("DetailsOf(base) = %v, want the original left unchanged", got)
+	}
+	if WrapMap(nil, map[string]any{"k": "v"}) != nil {
+		t.Error("WrapMap(nil) != nil")
+	}
+}
+
+func TestStatusOfBuiltinMappings(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantCode   ErrorCode
+		wantStatus int
+	}{
+		{ErrorNotFound, KeyNotFound, http.StatusNotFound},
+		{ErrorNotAllowed, KeyNotAllowed, http.StatusForbidden},
+		{ErrorWrongParams, KeyWrongParams, http.StatusBadRequest},
+		{ErrorUnauthorized, KeyUnauthorized, http.StatusUnauthorized},
+		{ErrorPermissionDenied, KeyPermissionDenied, http.StatusForbidden},
+		{ErrorUnprocessableEntity, KeyUnprocessableEntity, http.StatusUnprocessableEntity},
+		{ErrorInternalError, KeyInternalError, http.StatusInternalServerError},
+		{ErrorDuplicateEntry, KeyDuplicateEntry, http.StatusConflict},
+		{ErrorInsufficientQuota, KeyInsufficientQuota, http.StatusPaymentRequired},
+		{ErrorUserNotVerified, KeyUserNotVerified, http.StatusForbidden},
+		{ErrorUnsupported, KeyUnsupported, http.StatusUnprocessableEntity},
+		{ErrorConflict, KeyConflict, http.StatusConflict},
+		{ErrorTooManyRequests, KeyTooManyRequests, http.StatusTooManyRequests},
+		{ErrorUnavailable, KeyServiceUnavailable, http.StatusServiceUnavailable},
+		{ErrorPayloadTooLarge, KeyPayloadTooLarge, http.StatusRequestEntityTooLarge},
+		{ErrorUnsupportedMediaType, KeyUnsupportedMediaType, http.StatusUnsupportedMediaType},
+		{ErrorNotImplemented, KeyNotImplemented, http.StatusNotImplemented},
+		{ErrorGone, KeyGone, http.StatusGone},
+		{ErrorPreconditionFailed, KeyPreconditionFailed, http.StatusPreconditionFailed},
+		{ErrorPreconditionRequired, KeyPreconditionRequired, http.StatusPreconditionRequired},
+		{ErrorMethodNotAllowed, KeyMethodNotAllowed, http.StatusMethodNotAllowed},
+		{ErrorBadGateway, KeyBadGateway, http.StatusBadGateway},
+		{ErrorGatewayTimeout, KeyGatewayTimeout, http.StatusGatewayTimeout},
+		{ErrorDatabaseUnavailable, KeyDatabaseUnavailable, http.StatusServiceUnavailable},
+		{ErrorClientClosedRequest, KeyClientClosedRequest, StatusClientClosedRequest},
+		{sql.ErrNoRows, KeyNotFound, http.StatusNotFound},
+		{http.ErrMissingFile, KeyWrongParams, http.StatusBadRequest},
+		{http.ErrMissingBoundary, KeyWrongParams, http.StatusBadRequest},
+		{http.ErrNotMultipart, KeyUnsupportedMediaType, http.StatusUnsupportedMediaType},
+		{context.Canceled, KeyClientClosedRequest, StatusClientClosedRequest},
+		{context.DeadlineExceeded, KeyGatewayTimeout, http.StatusGatewayTimeout},
+		{os.ErrDeadlineExceeded, KeyGatewayTimeout, http.StatusGatewayTimeout},
+		{driver.ErrBadConn, KeyDatabaseUnavailable, http.StatusServiceUnavailable},
+		{sql.ErrConnDone, KeyDatabaseUnavailable, http.StatusServiceUnavailable},
+		{sql.ErrTxDone, KeyDatabaseUnavailable, http.StatusServiceUnavailable},
+	}
+	covered := make(map[error]bool, len(tests))
+	for _, tt := range tests {
+		covered[tt.err] = true
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := StatusOf(tt.err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+			if got := StatusOf(fmt.Errorf("wrapped: %w", tt.err)); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d when wrapped, want %d", got, tt.wantStatus)
+			}
+			if got := Code(tt.err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+		})
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for err, mapping := range errorMappings {
+		if !strings.HasPrefix(string(mapping.Code), "TEST_") && !covered[err] {
+			t.Errorf("built-in mapping %q -> %d %s is missing from the table", err, mapping.StatusCode, mapping.Code)
+		}
+	}
+	if got := StatusOf(nil); got != http.StatusOK {
+		t.Errorf("StatusOf(nil) = %d, want %d", got, http.StatusOK)
+	}
+	if got := StatusOf(errors.New("unknown")); got != http.StatusInternalServerError {
+		t.Errorf("StatusOf(unknown) = %d, want %d", got, http.StatusInternalServerError)
+	}
+}
+
+func TestDetailsOfChain(t *testing.T) {
+	repo := Wrap(sql.ErrNoRows, "table", "posts", "post_id", 7)
+	service := fmt.Errorf("service: %w", Wrap(fmt.Errorf("repo: %w", repo), "post_id", 8, "user_id", 42))
+	handler := fmt.Errorf("handler: %w", Wrap(service, "route", "/posts/:id"))
+
+	want := map[string]any{"table": "posts", "post_id": 8, "user_id": 42, "route": "/posts/:id"}
+	if got := DetailsOf(handler); !reflect.DeepEqual(got, want) {
+		t.Errorf("DetailsOf() = %v, want %v with the outer post_id winning", got, want)
+	}
+	if got := DetailsOf(errors.New("plain")); len(got) != 0 {
+		t.Errorf("DetailsOf(plain) = %v, want empty", got)
+	}
+	if got := DetailsOf(nil); len(got) != 0 {
+		t.Errorf("DetailsOf(nil) = %v, want empty", got)
+	}
+}
+
+func TestGetData(t *testing.T) {
+	err := fmt.Errorf("handler: %w", Wrap(Wrap(ErrorNotFound, "post_id", 7, "count", int64(300), "negative", -1, "big", uint64(1<<63)), "post_id", 8, "tenant", "acme"))
+
+	if got, ok := GetData[string](err, "tenant"); !ok || got != "acme" {
+		t.Errorf("GetData[string](tenant) = %q, %t, want acme", got, ok)
+	}
+	if got, ok := GetData[int](err, "post_id"); !ok || got != 8 {
+		t.Errorf("GetData[int](post_id) = %d, %t, want the outer 8", got, ok)
+	}
+	if _, ok := GetData[int](err, "tenant"); ok {
+		t.Error("GetData[int](tenant) = ok, want a type mismatch")
+	}
+	if _, ok := GetData[string](err, "missing"); ok {
+		t.Error("GetData(missing) = ok, want not found")
+	}
+	if _, ok := GetData[string](errors.New("plain"), "tenant"); ok {
+		t.Error("GetData(plain error) = ok, want not found")
+	}
+
+	conversions := []struct {
+		name string
+		got  func() (any, bool)
+		want any
+		ok   bool
+	}{
+		{"int to int64", func() (any, bool) { return GetData[int64](err, "post_id") }, int64(8), true},
+		{"int to uint8", func() (any, bool) { return GetData[uint8](err, "post_id") }, uint8(8), true},
+		{"int64 to int16", func() (any, bool) { return GetData[int16](err, "count") }, int16(300), true},
+		{"int64 overflowing int8", func() (any, bool) { return GetData[int8](err, "count") }, int8(0), false},
+		{"negative to uint", func() (any, bool) { return GetData[uint](err, "negative") }, uint(0), false},
+		{"uint64 to uint32 overflow", func() (any, bool) { return GetData[uint32](err, "big") }, uint32(0), false},
+		{"uint64 to int64 overflow", func() (any, bool) { return GetData[int64](err, "big") }, int64(0), false},
+		{"uint64 to uint", func() (any, bool) { return GetData[uint](err, "big") }, uint(1 << 63), true},
+		{"int to float64", func() (any, bool) { return GetData[float64](err, "post_id") }, float64(0), false},
+	}
+	for _, tt := range conversions {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := tt.got(); got != tt.want || ok != tt.ok {
+				t.Errorf("GetData() = %v, %t, want %v, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestReservedKeys(t *testing.T) {
+	err := Wrap(ErrorNotFound,
+		WrapKeyHTTPStatus, http.StatusGone,
+		WrapKeyPublicMessage, "post was deleted",
+		WrapKeyRetryAfter, 30,
+		WrapKeyAuthChallenge, `Bearer realm="api"`,
+		"post_id", 7,
+	)
+	w, body := serve(t, returning(err), WithLogging(false))
+	if w.Code != http.StatusGone || body.Message != "post was deleted" {
+		t.Errorf("response = %d %q, want 410 with the public message", w.Code, body.Message)
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("WWW-Authenticate") != `Bearer realm="api"` {
+		t.Errorf("headers = %v, want Retry-After and WWW-Authenticate set", w.Header())
+	}
+	if want := map[string]any{"post_id": float64(7), "retryable": true}; !reflect.DeepEqual(body.Details, want) {
+		t.Errorf("details = %v, want the reserved keys stripped", body.Details)
+	}
+}
+
+func TestReservedKeysInvalidValues(t *testing.T) {
+	err := Wrap(ErrorNotFound, WrapKeyHTTPStatus, "410", WrapKeyPublicMessage, 42, WrapKeyRetryAfter, "soon", WrapKeyAuthChallenge, true)
+	var w *httptest.ResponseRecorder
+	var body HttpError
+	logs := captureLogs(t, func() {
+		w, body = serve(t, returning(err))
+	})
+	if w.Code != http.StatusNotFound || body.Message != "data not found" || w.Header().Get("Retry-After") != "" {
+		t.Errorf("response = %d %q %v, want the invalid values ignored", w.Code, body.Message, w.Header())
+	}
+	if len(body.Details) != 0 {
+		t.Errorf("details = %v, want the reserved keys stripped", body.Details)
+	}
+	for _, key := range []string{WrapKeyHTTPStatus, WrapKeyPublicMessage, WrapKeyRetryAfter, WrapKeyAuthChallenge} {
+		if !strings.Contains(logs, "ignoring invalid "+key) {
+			t.Errorf("logs = %q, want a warning for %s", logs, key)
+		}
+	}
+}
+
+func TestDetailsCantOverrideResponseFields(t *testing.T) {
+	req := withTrace(httptest.NewRequest(http.MethodGet, "/test", nil))
+	err := Wrap(ErrorNotFound, "code", "TEST_FORGED", "message", "forged", "request_id", "forged-id", "details", "forged")
+	w, body := serveRequest(t, req, returning(err), WithLogging(false))
+	if body.Code != string(KeyNotFound) || body.Message != "data not found" || body.RequestID != testTraceID {
+		t.Errorf("response = %s %q %s, want the resolved code, message and trace ID", body.Code, body.Message, body.RequestID)
+	}
+	if body.Details["code"] != "TEST_FORGED" || body.Details["request_id"] != "forged-id" {
+		t.Errorf("details = %v, want the keys kept as plain details", body.Details)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if string(raw["code"]) != `"NOT_FOUND"` {
+		t.Errorf("top-level code = %s, want \"NOT_FOUND\"", raw["code"])
+	}
+}
+
+func TestWithType(t *testing.T) {
+	err := fmt.Errorf("loading feed: %w", WithType(Wrap(WithType(ErrorNotFound, ErrorTypeUser), "post_id", 7), ErrorTypePost))
+	if got := TypeOf(err); got != ErrorTypePost {
+		t.Errorf("TypeOf() = %q, want the outermost type %q", got, ErrorTypePost)
+	}
+	if got := TypeOf(ErrorNotFound); got != "" {
+		t.Errorf("TypeOf(untyped) = %q, want empty", got)
+	}
+	if WithType(nil, ErrorTypePost) != nil {
+		t.Error("WithType(nil) != nil")
+	}
+	var w *httptest.ResponseRecorder
+	var body HttpError
+	logs := captureLogs(t, func() {
+		w, body = serve(t, returning(err))
+	})
+	if w.Code != http.StatusNotFound || body.Type != string(ErrorTypePost) {
+		t.Errorf("response = %d type %q, want 404 type %q", w.Code, body.Type, ErrorTypePost)
+	}
+	if line := logLine(t, logs, "loading feed"); !strings.Contains(line, "type=post") {
+		t.Errorf("log line = %s, want the type logged", line)
+	}
+}
+
+func TestRegisterTypeMapping(t *testing.T) {
+	const billing ErrorType = "test_billing"
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(typeMappings, billing)
+	})
+	if err := RegisterTypeMapping(billing, "TEST_BILLING_FAILED", http.StatusPaymentRequired); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		err    error
+		code   ErrorCode
+		status int
+	}{
+		{"unmapped cause", WithType(fmt.Errorf("card declined"), billing), "TEST_BILLING_FAILED", http.StatusPaymentRequired},
+		{"wrapped", fmt.Errorf("charging: %w", Wrap(WithType(fmt.Errorf("card declined"), billing), "plan", "pro")), "TEST_BILLING_FAILED", http.StatusPaymentRequired},
+		{"mapped cause wins", WithType(ErrorNotFound, billing), KeyNotFound, http.StatusNotFound},
+		{"unregistered type", WithType(fmt.Errorf("card declined"), "test_unregistered"), KeyInternalError, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := serve(t, returning(tt.err), WithLogging(false))
+			if w.Code != tt.status || body.Code != string(tt.code) {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, tt.status, tt.code)
+			}
+		})
+	}
+	if err := RegisterTypeMapping("", "TEST_EMPTY", http.StatusBadRequest); err == nil {
+		t.Error("RegisterTypeMapping(\"\") = nil, want an error")
+	}
+	if err := RegisterTypeMapping(billing, "TEST_BILLING_FAILED", 42); err == nil {
+		t.Error("RegisterTypeMapping() with an invalid status = nil, want an error")
+	}
+}
+
+func TestIsClientServerError(t *testing.T) {
+	var syntaxErr *json.SyntaxError
+	bindErr := json.Unmarshal([]byte(`{"title":`), &struct{}{})
+	if !errors.As(bindErr, &syntaxErr) {
+		t.Fatalf("Unmarshal() = %v, want a *json.SyntaxError", bindErr)
+	}
+	tests := []struct {
+		name       string
+		err        error
+		wantClient bool
+		wantServer bool
+		wantClass  string
+	}{
+		{"nil", nil, false, false, ""},
+		{"sentinel", ErrorNotFound, true, false, "client"},
+		{"wrapped", fmt.Errorf("loading post: %w", Wrap(ErrorPermissionDenied, "post_id", 7)), true, false, "client"},
+		{"binding error", fmt.Errorf("decoding body: %w", bindErr), true, false, "client"},
+		{"unknown", fmt.Errorf("connection reset"), false, true, "server"},
+		{"server sentinel", ErrorUnavailable, false, true, "server"},
+		{"explicit status", WithStatus(ErrorNotFound, http.StatusBadGateway), false, true, "server"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsClientError(tt.err); got != tt.wantClient {
+				t.Errorf("IsClientError() = %t, want %t", got, tt.wantClient)
+			}
+			if got := IsServerError(tt.err); got != tt.wantServer {
+				t.Errorf("IsServerError() = %t, want %t", got, tt.wantServer)
+			}
+			if tt.err == nil {
+				return
+			}
+			logs := captureLogs(t, func() {
+				serve(t, returning(tt.err))
+			})
+			if !strings.Contains(logs, "class="+tt.wantClass) && !strings.Contains(logs, `"labels.class": "`+tt.wantClass+`"`) {
+				t.Errorf("logs = %s, want class %s", logs, tt.wantClass)
+			}
+		})
+	}
+}
+
+func TestDatabaseConnectivityErrors(t *testing.T) {
+	for _, sentinel := range []error{driver.ErrBadConn, sql.ErrConnDone, sql.ErrTxDone} {
+		for _, tt := range []struct {
+			name string
+			err  error
+		}{
+			{"bare", sentinel},
+			{"fmt wrapped", fmt.Errorf("querying posts: %w", sentinel)},
+			{"AppError wrapped", Wrap(fmt.Errorf("committing: %w", sentinel), "tx", "create_post")},
+		} {
+			t.Run(sentinel.Error()+"/"+tt.name, func(t *testing.T) {
+				if !IsRetryable(tt.err) {
+					t.Error("IsRetryable() = false, want true")
+				}
+				w, body := serve(t, returning(tt.err), WithLogging(false))
+				if w.Code != http.StatusServiceUnavailable || body.Code != string(KeyDatabaseUnavailable) {
+					t.Errorf("response = %d %s, want 503 %s", w.Code, body.Code, KeyDatabaseUnavailable)
+				}
+				if body.Details["retryable"] != true {
+					t.Errorf("details = %v, want retryable=true", body.Details)
+				}
+			})
+		}
+	}
+}
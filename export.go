@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MappingInfo describes one mapping known to the registry.
+type MappingInfo struct {
+	Code    ErrorCode `json:"code"`
+	Status  int       `json:"status"`
+	Message string    `json:"message"`
+	// Type is set for the default mappings registered per ErrorType.
+	Type ErrorType `json:"type,omitempty"`
+}
+
+// Mappings returns every mapping known to the registry, built-in and registered at
+// runtime, sorted by code, status, type and message. Registered sentinels report their
+// own message; type and built-in detection mappings report the default message.
+func Mappings() []MappingInfo {
+	registryMu.RLock()
+	infos := make([]MappingInfo, 0, len(errorMappings)+len(typeMappings)+len(heuristicMappings))
+	for err, mapping := range errorMappings {
+		infos = append(infos, MappingInfo{Code: mapping.Code, Status: mapping.StatusCode, Message: err.Error()})
+	}
+	for t, mapping := range typeMappings {
+		infos = append(infos, MappingInfo{Code: mapping.Code, Status: mapping.StatusCode, Message: defaultMessage(mapping), Type: t})
+	}
+	registryMu.RUnlock()
+	for _, mapping := range heuristicMappings {
+		infos = append(infos, MappingInfo{Code: mapping.Code, Status: mapping.StatusCode, Message: defaultMessage(mapping)})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		a, b := infos[i], infos[j]
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		if a.Status != b.Status {
+			return a.Status < b.Status
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Message < b.Message
+	})
+	return infos
+}
+
+// ExportMappings returns Mappings as a JSON array.
+func ExportMappings() ([]byte, error) {
+	return json.Marshal(Mappings())
+}
+
+// MappingsHandler returns a gin handler serving Mappings as JSON, e.g. for an
+// internal endpoint documenting the live set of error codes.
+func MappingsHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, Mappings())
+	}
+}
@@ -0,0 +1,57 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// builtinMappings returns Mappings without those registered by tests, whose codes start
+// with TEST_.
+func builtinMappings() []MappingInfo {
+	var infos []MappingInfo
+	for _, info := range Mappings() {
+		if !strings.HasPrefix(string(info.Code), "TEST_") {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+func TestExportMappingsGolden(t *testing.T) {
+	got, err := json.MarshalIndent(builtinMappings(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "mappings.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, append(got, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(append(got, '\n'), want) {
+		t.Errorf("exported mappings differ from %s; run go test -update if the change is intended:\n%s", golden, got)
+	}
+
+	exported, err := ExportMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []MappingInfo
+	if err := json.Unmarshal(exported, &decoded); err != nil {
+		t.Fatalf("decoding ExportMappings() output: %v", err)
+	}
+	if len(decoded) != len(Mappings()) {
+		t.Errorf("ExportMappings() has %d entries, want %d", len(decoded), len(Mappings()))
+	}
+}
@@ -0,0 +1,78 @@
+// Package fiberadapter serves errors returned by Fiber handlers in the same HttpError format
+// and with the same resolution and logging as the gin handlers of the errors package.
+package fiberadapter
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/A-pen-app/errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// ErrorHandler returns a fiber.ErrorHandler that writes errors as an HttpError:
+//
+//	app := fiber.New(fiber.Config{ErrorHandler: fiberadapter.ErrorHandler()})
+//
+// Errors resolve through the errors registry, except that a *fiber.Error, such as Fiber's
+// own 404 and 405, keeps its status, with its message sent for 4xx statuses. As fasthttp
+// has no request context, the request ID is the trace ID of the OpenTelemetry span in
+// the user context set with Ctx.SetUserContext, as by otelfiber, falling back to the
+// X-Request-ID header. Options work as they do for errors.Handle, except for those taking
+// a *gin.Context.
+func ErrorHandler(opts ...errors.Option) fiber.ErrorHandler {
+	responder := errors.NewResponder(opts...)
+	return func(c *fiber.Ctx, err error) error {
+		req, convertErr := request(c)
+		if convertErr != nil {
+			return convertErr
+		}
+		resp := responder.ResponseFor(req, fromFiberError(err))
+		for key, values := range resp.Header {
+			for _, value := range values {
+				c.Set(key, value)
+			}
+		}
+		if resp.Body == nil || c.Method() == fiber.MethodHead {
+			return c.SendStatus(resp.Status)
+		}
+		if resp.Body.RequestID == "" {
+			resp.Body.RequestID = requestID(c)
+		}
+		return c.Status(resp.Status).JSON(resp.Body, fiber.MIMEApplicationJSONCharsetUTF8)
+	}
+}
+
+// request converts the fasthttp request of c to an *http.Request carrying c's user
+// context, so that spans and cancellation reach the errors package.
+func request(c *fiber.Ctx) (*http.Request, error) {
+	var req http.Request
+	if err := fasthttpadaptor.ConvertRequest(c.Context(), &req, true); err != nil {
+		return nil, err
+	}
+	return req.WithContext(c.UserContext()), nil
+}
+
+// fromFiberError pins the status of a *fiber.Error in err's chain.
+func fromFiberError(err error) error {
+	var fiberErr *fiber.Error
+	if !stderrors.As(err, &fiberErr) {
+		return err
+	}
+	status := fiberErr.Code
+	err = errors.WithStatus(errors.WithCode(err, errors.CodeForStatus(status)), status)
+	if status < http.StatusInternalServerError {
+		err = errors.WithPublicMessage(err, fiberErr.Message)
+	}
+	return err
+}
+
+// requestID returns the X-Request-ID set by Fiber's requestid middleware or sent by the
+// client.
+func requestID(c *fiber.Ctx) string {
+	if id := c.GetRespHeader(fiber.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Get(fiber.HeaderXRequestID)
+}
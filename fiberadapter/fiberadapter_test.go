@@ -0,0 +1,83 @@
+package fiberadapter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/A-pen-app/errors"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newApp returns a Fiber app using ErrorHandler, with a /posts/:id route failing with err.
+func newApp(err error) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler(errors.WithLogging(false))})
+	app.Get("/posts/:id", func(*fiber.Ctx) error { return err })
+	return app
+}
+
+// do sends req to app and returns the response and its decoded HttpError.
+func do(t *testing.T, app *fiber.App, req *http.Request) (*http.Response, errors.HttpError) {
+	t.Helper()
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body errors.HttpError
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		path        string
+		method      string
+		wantStatus  int
+		wantCode    errors.ErrorCode
+		wantMessage string
+	}{
+		{"sentinel", errors.Wrap(errors.ErrorNotFound, "post_id", 7), "/posts/7", http.MethodGet, http.StatusNotFound, errors.KeyNotFound, "data not found"},
+		{"fiber error", fiber.NewError(http.StatusBadRequest, "id must be numeric"), "/posts/x", http.MethodGet, http.StatusBadRequest, errors.KeyWrongParams, "id must be numeric"},
+		{"unknown route", nil, "/users", http.MethodGet, http.StatusNotFound, errors.KeyNotFound, "Cannot GET /users"},
+		{"wrong method", nil, "/posts/7", http.MethodDelete, http.StatusMethodNotAllowed, errors.CodeForStatus(http.StatusMethodNotAllowed), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := do(t, newApp(tt.err), httptest.NewRequest(tt.method, tt.path, nil))
+			if resp.StatusCode != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Errorf("response = %d %s, want %d %s", resp.StatusCode, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantMessage != "" && body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Message, tt.wantMessage)
+			}
+			if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+				t.Errorf("Content-Type = %q, want JSON", got)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerRequestID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/posts/7", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "req-1")
+	_, body := do(t, newApp(errors.ErrorNotFound), req)
+	if body.RequestID != "req-1" {
+		t.Errorf("request_id = %q, want %q", body.RequestID, "req-1")
+	}
+}
+
+func TestErrorHandlerRetryAfter(t *testing.T) {
+	err := errors.WithRetryAfter(errors.ErrorTooManyRequests, 30*time.Second)
+	resp, _ := do(t, newApp(err), httptest.NewRequest(http.MethodGet, "/posts/7", nil))
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "30" {
+		t.Errorf("response = %d Retry-After=%q, want 429 with Retry-After 30", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+}
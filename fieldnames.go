@@ -0,0 +1,156 @@
+package errors
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// fieldNamer resolves validator namespaces to the field names clients sent, using the
+// struct tags of the bound type.
+type fieldNamer struct {
+	t    reflect.Type
+	tags []string
+}
+
+// bindingTags lists the struct tags naming fields for each binding, in order of preference.
+var bindingTags = map[string][]string{
+	"query":               {"form", "json"},
+	"form":                {"form", "json"},
+	"form-urlencoded":     {"form", "json"},
+	"multipart/form-data": {"form", "json"},
+	"uri":                 {"uri", "json"},
+	"header":              {"header"},
+	"xml":                 {"xml", "json"},
+	"yaml":                {"yaml", "json"},
+	"toml":                {"toml", "json"},
+}
+
+// fieldNamerFor returns the namer for the type bound by the Bind helpers in err's chain,
+// or nil when err didn't come from them.
+func fieldNamerFor(err error) *fieldNamer {
+	var bindingErr *BindingError
+	if !errors.As(err, &bindingErr) || bindingErr.boundType == nil {
+		return nil
+	}
+	tags, exists := bindingTags[bindingErr.Source]
+	if !exists {
+		tags = []string{"json"}
+	}
+	return &fieldNamer{t: bindingErr.boundType, tags: tags}
+}
+
+// path returns the client-facing path of the field fe failed on, e.g. "author.user_id"
+// for the namespace "Post.Author.UserID" or "items[0].qty" for "Order.Items[0].Qty",
+// and false when a field on the path is hidden from clients by a "-" tag name. Without a
+// namer, or when the namespace doesn't match the bound type, it falls back to the
+// namespace known to the validator without the top-level struct name, made of Go field
+// names unless the application registered a tag name func with the validator; fields
+// that func names "-" are hidden as well.
+func (n *fieldNamer) path(fe validator.FieldError) (string, bool) {
+	if n != nil {
+		if path, visible, ok := n.resolve(fe.StructNamespace()); ok {
+			return path, visible
+		}
+	}
+	path := fe.Field()
+	if _, rest, found := strings.Cut(fe.Namespace(), "."); found {
+		path = rest
+	}
+	for _, segment := range strings.Split(path, ".") {
+		if name, _, _ := strings.Cut(segment, "["); name == "-" {
+			return "", false
+		}
+	}
+	return path, true
+}
+
+// leaf returns the last field name of a path, without indices, e.g. "qty" for
+// "items[0].qty" and "tags" for "tags[2]".
+func leaf(path string) string {
+	if i := strings.LastIndexByte(path, '.'); i >= 0 {
+		path = path[i+1:]
+	}
+	name, _, _ := strings.Cut(path, "[")
+	return name
+}
+
+// resolve converts a validator struct namespace into a path of tag names, keeping slice
+// indices and map keys and following pointers. Embedded structs without a tag name of
+// their own are flattened, like encoding/json does. visible is false when a field on the
+// path has a "-" tag name, which clients can't send.
+func (n *fieldNamer) resolve(namespace string) (path string, visible, ok bool) {
+	segments := strings.Split(namespace, ".")
+	if len(segments) < 2 {
+		return "", false, false
+	}
+
+	t := n.t
+	var names []string
+	for _, segment := range segments[1:] {
+		name, indices, _ := strings.Cut(segment, "[")
+		t = indirect(t)
+		if t.Kind() != reflect.Struct {
+			return "", false, false
+		}
+		field, found := t.FieldByName(name)
+		if !found {
+			return "", false, false
+		}
+		t = field.Type
+
+		tagName, hidden := n.tagName(field)
+		if hidden {
+			return "", false, true
+		}
+		if field.Anonymous && tagName == "" {
+			continue
+		}
+		if tagName == "" {
+			tagName = field.Name
+		}
+		if indices != "" {
+			tagName += "[" + indices
+			for range strings.Count(indices, "[") + 1 {
+				t = elem(t)
+			}
+		}
+		names = append(names, tagName)
+	}
+	return strings.Join(names, "."), true, true
+}
+
+// tagName returns the name given to field by the first of the namer's tags that names
+// it, ignoring options such as omitempty, and reports hidden when that name is "-".
+func (n *fieldNamer) tagName(field reflect.StructField) (name string, hidden bool) {
+	for _, tag := range n.tags {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return "", true
+		}
+		if name != "" {
+			return name, false
+		}
+	}
+	return "", false
+}
+
+// indirect dereferences pointer types.
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// elem returns the element type of a slice, array or map type, dereferencing pointers.
+func elem(t reflect.Type) reflect.Type {
+	t = indirect(t)
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return t.Elem()
+	}
+	return t
+}
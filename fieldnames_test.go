@@ -0,0 +1,87 @@
+package errors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shipment nests structs three levels deep, with slices, maps, pointers and hidden fields.
+type shipment struct {
+	Order struct {
+		Lines []*struct {
+			Qty   int               `json:"qty" binding:"gte=1"`
+			Attrs map[string]string `json:"attrs" binding:"dive,max=3"`
+		} `json:"lines" binding:"dive"`
+	} `json:"order"`
+	Meta struct {
+		Tags []string `json:"tags" binding:"dive,max=3"`
+	}
+	Internal struct {
+		Checksum string `json:"checksum" binding:"required"`
+	} `json:"-"`
+	CreatedBy string `json:"-" binding:"required"`
+}
+
+func TestNestedFieldPaths(t *testing.T) {
+	const payload = `{
+		"order": {"lines": [{"qty": 1}, {"qty": 0, "attrs": {"color": "crimson"}}]},
+		"Meta": {"tags": ["ok", "toolong"]}
+	}`
+	w, body := serveRequest(t, postJSON(payload), func(ctx *gin.Context) error {
+		var req shipment
+		return Bind(ctx, &req)
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	want := []struct{ path, field string }{
+		{"order.lines[1].qty", "qty"},
+		{"order.lines[1].attrs[color]", "attrs"},
+		{"Meta.tags[1]", "tags"},
+	}
+	fields := violationsOf(t, body)
+	if len(fields) != len(want) {
+		t.Fatalf("fields = %+v, want %d entries", fields, len(want))
+	}
+	for i, f := range fields {
+		if f.Path != want[i].path || f.Field != want[i].field {
+			t.Errorf("fields[%d] = %s (%s), want %s (%s)", i, f.Path, f.Field, want[i].path, want[i].field)
+		}
+	}
+}
+
+func TestHiddenFieldsLeftOut(t *testing.T) {
+	type signupWithAudit struct {
+		Email     string `json:"email" binding:"required"`
+		CreatedBy string `json:"-" binding:"required"`
+	}
+	w, body := serveRequest(t, postJSON(`{}`), func(ctx *gin.Context) error {
+		var req signupWithAudit
+		return Bind(ctx, &req)
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	fields := violationsOf(t, body)
+	if len(fields) != 1 || fields[0].Path != "email" {
+		t.Errorf("fields = %+v, want only email", fields)
+	}
+	if body.Message != "email is required" {
+		t.Errorf("message = %q, want the message of the only visible field", body.Message)
+	}
+}
+
+func TestShouldBindKeepsValidatorNames(t *testing.T) {
+	type signup struct {
+		Email string `json:"email" binding:"required"`
+	}
+	_, body := serveRequest(t, postJSON(`{}`), func(ctx *gin.Context) error {
+		var req signup
+		return ctx.ShouldBindJSON(&req)
+	})
+	if fields := violationsOf(t, body); len(fields) != 1 || fields[0].Path != "Email" {
+		t.Errorf("fields = %+v, want the Go name Email, as gin's validator is left as configured", fields)
+	}
+}
@@ -0,0 +1,94 @@
+// Package gcserr maps Google Cloud Storage errors to semantic HTTP errors, so that a
+// missing object is a 404 rather than a 500.
+package gcserr
+
+import (
+	stderrors "errors"
+	"net/http"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/A-pen-app/errors"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+)
+
+// DetailKeyObjectKey is the detail holding the object name set by WithObject, named like
+// the s3err one so that clients see the same details from either store.
+const DetailKeyObjectKey = "object_key"
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about the Cloud Storage errors, anywhere in the
+// chain:
+//
+//	storage.ErrObjectNotExist       -> 404 NOT_FOUND
+//	storage.ErrBucketNotExist       -> 404 NOT_FOUND
+//	412 / FAILED_PRECONDITION       -> 412 PRECONDITION_FAILED
+//	401, 403 / UNAUTHENTICATED, PERMISSION_DENIED -> 500 INTERNAL_ERROR
+//
+// Authorization failures stay 500, as they mean the service account is misconfigured
+// rather than that the client may not see the object. Calling Register more than once
+// has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		registerErr = errors.RegisterTypeClassifier(classify)
+	})
+	return registerErr
+}
+
+// WithObject adds the object name to err's details, so that not-found and precondition
+// responses say which object was meant. Bucket names and signed URLs should not be passed.
+func WithObject(err error, object string) error {
+	return errors.Wrap(err, DetailKeyObjectKey, object)
+}
+
+// classify maps the Cloud Storage errors in a chain. It is registered for the error
+// interface, as the SDK returns sentinels as well as API errors.
+func classify(err error) (errors.Classification, bool) {
+	if stderrors.Is(err, storage.ErrObjectNotExist) || stderrors.Is(err, storage.ErrBucketNotExist) {
+		return errors.Classification{
+			Code:    errors.KeyNotFound,
+			Status:  http.StatusNotFound,
+			Message: "object not found",
+		}, true
+	}
+	switch httpCode, grpcCode := apiCodes(err); {
+	case httpCode == http.StatusPreconditionFailed, grpcCode == codes.FailedPrecondition:
+		return errors.Classification{
+			Code:    errors.KeyPreconditionFailed,
+			Status:  http.StatusPreconditionFailed,
+			Message: "object was modified",
+		}, true
+	case httpCode == http.StatusUnauthorized, httpCode == http.StatusForbidden,
+		grpcCode == codes.Unauthenticated, grpcCode == codes.PermissionDenied:
+		return errors.Classification{
+			Code:   errors.KeyInternalError,
+			Status: http.StatusInternalServerError,
+		}, true
+	}
+	return errors.Classification{}, false
+}
+
+// apiCodes returns the HTTP status or gRPC code of the API error in err's chain, with zero
+// values when there is none.
+func apiCodes(err error) (int, codes.Code) {
+	var apiErr *apierror.APIError
+	if stderrors.As(err, &apiErr) {
+		if s := apiErr.GRPCStatus(); s != nil {
+			return 0, s.Code()
+		}
+		if code := apiErr.HTTPCode(); code > 0 {
+			return code, codes.OK
+		}
+	}
+	var googleErr *googleapi.Error
+	if stderrors.As(err, &googleErr) {
+		return googleErr.Code, codes.OK
+	}
+	return 0, codes.OK
+}
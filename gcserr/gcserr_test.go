@@ -0,0 +1,71 @@
+package gcserr
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/A-pen-app/errors"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+// apiError returns err as the *apierror.APIError the SDK returns for it.
+func apiError(t *testing.T, err error) error {
+	t.Helper()
+	apiErr, ok := apierror.FromError(err)
+	if !ok {
+		t.Fatalf("apierror.FromError(%v) failed", err)
+	}
+	return apiErr
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   errors.ErrorCode
+		wantStatus int
+	}{
+		{"object not exist", storage.ErrObjectNotExist, errors.KeyNotFound, http.StatusNotFound},
+		{"bucket not exist", storage.ErrBucketNotExist, errors.KeyNotFound, http.StatusNotFound},
+		{"HTTP precondition failed", &googleapi.Error{Code: http.StatusPreconditionFailed, Message: "conditionNotMet"}, errors.KeyPreconditionFailed, http.StatusPreconditionFailed},
+		{"HTTP API precondition failed", apiError(t, &googleapi.Error{Code: http.StatusPreconditionFailed}), errors.KeyPreconditionFailed, http.StatusPreconditionFailed},
+		{"gRPC failed precondition", apiError(t, status.Error(codes.FailedPrecondition, "generation mismatch")), errors.KeyPreconditionFailed, http.StatusPreconditionFailed},
+		{"HTTP forbidden", &googleapi.Error{Code: http.StatusForbidden}, errors.KeyInternalError, http.StatusInternalServerError},
+		{"gRPC permission denied", apiError(t, status.Error(codes.PermissionDenied, "denied")), errors.KeyInternalError, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("reading avatar: %w", tt.err))
+			if got := errors.Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := errors.StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWithObject(t *testing.T) {
+	err := WithObject(fmt.Errorf("reading avatar: %w", storage.ErrObjectNotExist), "avatars/7.png")
+	resp := errors.ResponseFor(httptest.NewRequest(http.MethodGet, "/avatars/7", nil), err, errors.WithLogging(false))
+	if resp.Status != http.StatusNotFound || resp.Body.Message != "object not found" {
+		t.Errorf("response = %d %q, want 404 %q", resp.Status, resp.Body.Message, "object not found")
+	}
+	if got := resp.Body.Details[DetailKeyObjectKey]; got != "avatars/7.png" {
+		t.Errorf("%s = %v, want %q", DetailKeyObjectKey, got, "avatars/7.png")
+	}
+}
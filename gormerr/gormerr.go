@@ -0,0 +1,57 @@
+// Package gormerr maps the GORM sentinel errors to semantic HTTP errors, so that a
+// missing record is a 404 like sql.ErrNoRows rather than a 500.
+package gormerr
+
+import (
+	stderrors "errors"
+	"net/http"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"gorm.io/gorm"
+)
+
+// ReasonInvalidTransaction is the "reason" detail of gorm.ErrInvalidTransaction, which
+// tells misused transactions apart from other internal errors.
+const ReasonInvalidTransaction = "invalid_transaction"
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about the GORM sentinels, anywhere in the chain:
+//
+//	gorm.ErrRecordNotFound          -> 404 NOT_FOUND
+//	gorm.ErrDuplicatedKey           -> 409 CONFLICT
+//	gorm.ErrForeignKeyViolated      -> 400 WRONG_PARAMETER
+//	gorm.ErrCheckConstraintViolated -> 400 WRONG_PARAMETER
+//	gorm.ErrInvalidTransaction      -> 500 INTERNAL_ERROR, with reason "invalid_transaction"
+//
+// GORM only returns ErrDuplicatedKey and the constraint errors when opened with
+// TranslateError. Calling Register more than once has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		registerErr = stderrors.Join(
+			errors.Register(gorm.ErrRecordNotFound, errors.KeyNotFound, http.StatusNotFound),
+			errors.Register(gorm.ErrDuplicatedKey, errors.KeyConflict, http.StatusConflict),
+			errors.Register(gorm.ErrForeignKeyViolated, errors.KeyWrongParams, http.StatusBadRequest),
+			errors.Register(gorm.ErrCheckConstraintViolated, errors.KeyWrongParams, http.StatusBadRequest),
+			errors.RegisterTypeClassifier(classifyInvalidTransaction),
+		)
+	})
+	return registerErr
+}
+
+// classifyInvalidTransaction classifies chains holding gorm.ErrInvalidTransaction. It is
+// registered for the error interface, so it sees the whole chain.
+func classifyInvalidTransaction(err error) (errors.Classification, bool) {
+	if !stderrors.Is(err, gorm.ErrInvalidTransaction) {
+		return errors.Classification{}, false
+	}
+	return errors.Classification{
+		Code:    errors.KeyInternalError,
+		Status:  http.StatusInternalServerError,
+		Details: map[string]any{"reason": ReasonInvalidTransaction},
+	}, true
+}
@@ -0,0 +1,58 @@
+package gormerr
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/A-pen-app/errors"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   errors.ErrorCode
+		wantStatus int
+	}{
+		{"record not found", gorm.ErrRecordNotFound, errors.KeyNotFound, http.StatusNotFound},
+		{"duplicated key", gorm.ErrDuplicatedKey, errors.KeyConflict, http.StatusConflict},
+		{"foreign key violated", gorm.ErrForeignKeyViolated, errors.KeyWrongParams, http.StatusBadRequest},
+		{"check constraint violated", gorm.ErrCheckConstraintViolated, errors.KeyWrongParams, http.StatusBadRequest},
+		{"invalid transaction", gorm.ErrInvalidTransaction, errors.KeyInternalError, http.StatusInternalServerError},
+		{"unregistered sentinel", gorm.ErrMissingWhereClause, errors.KeyInternalError, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("loading post: %w", tt.err), "post_id", 7)
+			if got := errors.Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := errors.StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRegisterInvalidTransactionReason(t *testing.T) {
+	err := errors.Wrap(fmt.Errorf("committing: %w", gorm.ErrInvalidTransaction))
+	resp := errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/posts", nil), err, errors.WithLogging(false))
+	if got := resp.Body.Details["reason"]; got != ReasonInvalidTransaction {
+		t.Errorf("reason = %v, want %q", got, ReasonInvalidTransaction)
+	}
+
+	resp = errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/posts", nil), gorm.ErrMissingWhereClause, errors.WithLogging(false))
+	if _, exists := resp.Body.Details["reason"]; exists {
+		t.Errorf("details = %v, want no reason for other internal errors", resp.Body.Details)
+	}
+}
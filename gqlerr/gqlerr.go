@@ -0,0 +1,89 @@
+// Package gqlerr presents errors returned by gqlgen resolvers with the code, details and
+// request ID of an HttpError in their extensions.
+package gqlerr
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/A-pen-app/errors"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Extension keys set by ErrorPresenter.
+const (
+	ExtensionKeyCode      = "code"
+	ExtensionKeyType      = "type"
+	ExtensionKeyDetails   = "details"
+	ExtensionKeyRequestID = "request_id"
+)
+
+// ErrorPresenter returns a gqlgen error presenter that resolves resolver errors through
+// the errors registry and logs them as errors.Handle does, with the operation name as the
+// "operation" log field:
+//
+//	srv := handler.NewDefaultServer(schema)
+//	srv.SetErrorPresenter(gqlerr.ErrorPresenter(errors.WithProductionMessages(true)))
+//
+// The GraphQL error's message is the response message, so it is generic for internal
+// errors, and its extensions carry the code, type, details and request ID. Errors raised
+// by gqlgen itself, such as query validation errors, keep gqlgen's presentation. Options
+// taking a *gin.Context don't apply.
+func ErrorPresenter(opts ...errors.Option) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		cause := gqlErr.Unwrap()
+		if cause == nil {
+			return gqlErr
+		}
+		callOpts := opts
+		if name := operationName(ctx); name != "" {
+			callOpts = append(opts[:len(opts):len(opts)], errors.WithLogFields("operation", name))
+		}
+		resp := errors.ResponseForContext(ctx, cause, callOpts...)
+		if resp.Body == nil {
+			return gqlErr
+		}
+		gqlErr.Message = resp.Body.Message
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = make(map[string]any)
+		}
+		gqlErr.Extensions[ExtensionKeyCode] = resp.Body.Code
+		if resp.Body.Type != "" {
+			gqlErr.Extensions[ExtensionKeyType] = resp.Body.Type
+		}
+		if len(resp.Body.Details) > 0 {
+			gqlErr.Extensions[ExtensionKeyDetails] = resp.Body.Details
+		}
+		if resp.Body.RequestID != "" {
+			gqlErr.Extensions[ExtensionKeyRequestID] = resp.Body.RequestID
+		}
+		return gqlErr
+	}
+}
+
+// PanicRecover is a gqlgen recover func that turns a resolver panic into an internal
+// error carrying the stack trace, which ErrorPresenter logs and presents as a generic
+// internal error:
+//
+//	srv.SetRecoverFunc(gqlerr.PanicRecover)
+func PanicRecover(_ context.Context, p any) error {
+	return fmt.Errorf("panic: %v\n%s", p, debug.Stack())
+}
+
+// operationName returns the name of the GraphQL operation being executed in ctx.
+func operationName(ctx context.Context) string {
+	if !graphql.HasOperationContext(ctx) {
+		return ""
+	}
+	oc := graphql.GetOperationContext(ctx)
+	if oc.OperationName != "" {
+		return oc.OperationName
+	}
+	if oc.Operation != nil {
+		return oc.Operation.Name
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package gqlerr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/A-pen-app/errors"
+	"github.com/A-pen-app/logging"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestMain(m *testing.M) {
+	logging.Initialize(nil)
+	m.Run()
+}
+
+// schema is an executable schema whose only field, Query.post, is served by resolve, as
+// gqlgen's generated code would serve it.
+type schema struct {
+	resolve func(context.Context) error
+}
+
+var postSchema = gqlparser.MustLoadSchema(&ast.Source{Input: "type Query { post(id: ID!): String }"})
+
+func (s schema) Schema() *ast.Schema { return postSchema }
+
+func (s schema) Complexity(string, string, int, map[string]any) (int, bool) { return 0, false }
+
+func (s schema) Exec(ctx context.Context) graphql.ResponseHandler {
+	done := false
+	return func(ctx context.Context) *graphql.Response {
+		if done {
+			return nil
+		}
+		done = true
+		oc := graphql.GetOperationContext(ctx)
+		field := oc.Operation.SelectionSet[0].(*ast.Field)
+		ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+			Object:     "Query",
+			Field:      graphql.CollectedField{Field: field},
+			IsResolver: true,
+		})
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					graphql.AddError(ctx, graphql.Recover(ctx, r))
+				}
+			}()
+			if err := s.resolve(ctx); err != nil {
+				graphql.AddError(ctx, err)
+			}
+		}()
+		return &graphql.Response{Data: []byte(`{"post":null}`)}
+	}
+}
+
+// execute runs query against a schema served by resolve, with ErrorPresenter and
+// PanicRecover installed, and returns the errors of the response.
+func execute(t *testing.T, query string, resolve func(context.Context) error, opts ...errors.Option) gqlerror.List {
+	t.Helper()
+	exec := executor.New(schema{resolve: resolve})
+	exec.SetErrorPresenter(ErrorPresenter(opts...))
+	exec.SetRecoverFunc(PanicRecover)
+
+	ctx := graphql.StartOperationTrace(context.Background())
+	params := &graphql.RawParams{Query: query, ReadTime: graphql.TraceTiming{Start: time.Now(), End: time.Now()}}
+	rc, errs := exec.CreateOperationContext(ctx, params)
+	if errs != nil {
+		return exec.DispatchError(graphql.WithOperationContext(ctx, rc), errs).Errors
+	}
+	handler, ctx := exec.DispatchOperation(ctx, rc)
+	return handler(ctx).Errors
+}
+
+func TestErrorPresenter(t *testing.T) {
+	errs := execute(t, `query GetPost { post(id: 7) }`, func(context.Context) error {
+		return errors.Wrap(errors.ErrorNotFound, "post_id", 7)
+	}, errors.WithLogging(false))
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v, want one", errs)
+	}
+	err := errs[0]
+	if err.Message != "data not found" {
+		t.Errorf("message = %q, want %q", err.Message, "data not found")
+	}
+	if got := err.Extensions[ExtensionKeyCode]; got != string(errors.KeyNotFound) {
+		t.Errorf("code = %v, want %s", got, errors.KeyNotFound)
+	}
+	if details, _ := err.Extensions[ExtensionKeyDetails].(map[string]any); details["post_id"] != 7 {
+		t.Errorf("details = %v, want post_id=7", err.Extensions[ExtensionKeyDetails])
+	}
+	if got := err.Path.String(); got != "post" {
+		t.Errorf("path = %q, want %q", got, "post")
+	}
+}
+
+func TestErrorPresenterHidesInternalErrors(t *testing.T) {
+	errs := execute(t, `{ post(id: 7) }`, func(context.Context) error {
+		return fmt.Errorf("querying posts: db password rejected")
+	}, errors.WithLogging(false))
+	if len(errs) != 1 || strings.Contains(errs[0].Message, "password") {
+		t.Fatalf("errors = %v, want one generic internal error", errs)
+	}
+	if got := errs[0].Extensions[ExtensionKeyCode]; got != string(errors.KeyInternalError) {
+		t.Errorf("code = %v, want %s", got, errors.KeyInternalError)
+	}
+}
+
+func TestPanicRecover(t *testing.T) {
+	errs := execute(t, `{ post(id: 7) }`, func(context.Context) error {
+		panic("nil map")
+	}, errors.WithLogging(false))
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v, want one", errs)
+	}
+	if got := errs[0].Extensions[ExtensionKeyCode]; got != string(errors.KeyInternalError) {
+		t.Errorf("code = %v, want %s", got, errors.KeyInternalError)
+	}
+	if msg := errs[0].Message; msg == "" || msg == "nil map" {
+		t.Errorf("message = %q, want a generic message", msg)
+	}
+}
+
+func TestErrorPresenterKeepsValidationErrors(t *testing.T) {
+	errs := execute(t, `{ author }`, func(context.Context) error { return nil }, errors.WithLogging(false))
+	if len(errs) == 0 {
+		t.Fatal("errors = none, want a validation error")
+	}
+	if got := errs[0].Extensions["code"]; got != "GRAPHQL_VALIDATION_FAILED" {
+		t.Errorf("code = %v, want gqlgen's GRAPHQL_VALIDATION_FAILED", got)
+	}
+}
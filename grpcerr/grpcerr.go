@@ -0,0 +1,102 @@
+// Package grpcerr translates gRPC status errors returned by backends into HTTP responses.
+package grpcerr
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DetailKeyGRPCCode is the detail holding the name of the backend's gRPC code, e.g.
+// "NotFound".
+const DetailKeyGRPCCode = "grpc_code"
+
+// codeMappings maps gRPC codes to HTTP, following the table used by grpc-gateway.
+var codeMappings = map[codes.Code]errors.ErrorMapping{
+	codes.Canceled:           {Code: errors.KeyClientClosedRequest, StatusCode: errors.StatusClientClosedRequest},
+	codes.Unknown:            {Code: errors.KeyInternalError, StatusCode: http.StatusInternalServerError},
+	codes.InvalidArgument:    {Code: errors.KeyWrongParams, StatusCode: http.StatusBadRequest},
+	codes.DeadlineExceeded:   {Code: errors.KeyGatewayTimeout, StatusCode: http.StatusGatewayTimeout},
+	codes.NotFound:           {Code: errors.KeyNotFound, StatusCode: http.StatusNotFound},
+	codes.AlreadyExists:      {Code: errors.KeyDuplicateEntry, StatusCode: http.StatusConflict},
+	codes.PermissionDenied:   {Code: errors.KeyPermissionDenied, StatusCode: http.StatusForbidden},
+	codes.ResourceExhausted:  {Code: errors.KeyTooManyRequests, StatusCode: http.StatusTooManyRequests},
+	codes.FailedPrecondition: {Code: errors.KeyNotAllowed, StatusCode: http.StatusBadRequest},
+	codes.Aborted:            {Code: errors.KeyConflict, StatusCode: http.StatusConflict},
+	codes.OutOfRange:         {Code: errors.KeyWrongParams, StatusCode: http.StatusBadRequest},
+	codes.Unimplemented:      {Code: errors.KeyNotImplemented, StatusCode: http.StatusNotImplemented},
+	codes.Internal:           {Code: errors.KeyInternalError, StatusCode: http.StatusInternalServerError},
+	codes.Unavailable:        {Code: errors.KeyServiceUnavailable, StatusCode: http.StatusServiceUnavailable},
+	codes.DataLoss:           {Code: errors.KeyInternalError, StatusCode: http.StatusInternalServerError},
+	codes.Unauthenticated:    {Code: errors.KeyUnauthorized, StatusCode: http.StatusUnauthorized},
+}
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about gRPC status errors anywhere in the chain, as
+// returned by generated clients:
+//
+//	Canceled           -> 499 CLIENT_CLOSED_REQUEST
+//	InvalidArgument    -> 400 WRONG_PARAMETER
+//	OutOfRange         -> 400 WRONG_PARAMETER
+//	FailedPrecondition -> 400 ACTION_NOT_ALLOWED
+//	Unauthenticated    -> 401 UNAUTHORIZED
+//	PermissionDenied   -> 403 PERMISSION_DENIED
+//	NotFound           -> 404 NOT_FOUND
+//	AlreadyExists      -> 409 DUPLICATE_ENTRY
+//	Aborted            -> 409 CONFLICT, retryable
+//	ResourceExhausted  -> 429 TOO_MANY_REQUESTS
+//	Unknown, Internal, DataLoss -> 500 INTERNAL_ERROR
+//	Unimplemented      -> 501 NOT_IMPLEMENTED
+//	Unavailable        -> 503 SERVICE_UNAVAILABLE
+//	DeadlineExceeded   -> 504 GATEWAY_TIMEOUT
+//
+// The backend's status message is the response message for 4xx codes only; for 5xx codes
+// it is logged with the error but not sent. Every response carries the gRPC code name in
+// the "grpc_code" detail. Calling Register more than once has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		registerErr = errors.RegisterTypeClassifier(classify)
+	})
+	return registerErr
+}
+
+// grpcStatus is implemented by the errors of status.Error and status.Errorf.
+type grpcStatus interface {
+	GRPCStatus() *status.Status
+}
+
+// classify maps the outermost gRPC status error in a chain by its code. It is registered for
+// the error interface, so it sees the whole chain.
+func classify(err error) (errors.Classification, bool) {
+	var statusErr grpcStatus
+	if !stderrors.As(err, &statusErr) {
+		return errors.Classification{}, false
+	}
+	s := statusErr.GRPCStatus()
+	mapping, exists := codeMappings[s.Code()]
+	if !exists {
+		return errors.Classification{}, false
+	}
+	c := errors.Classification{
+		Code:      mapping.Code,
+		Status:    mapping.StatusCode,
+		Details:   map[string]any{DetailKeyGRPCCode: s.Code().String()},
+		Retryable: s.Code() == codes.Aborted,
+	}
+	if mapping.StatusCode < http.StatusInternalServerError {
+		c.Message = s.Message()
+		if c.Message == "" {
+			c.Message = strings.ToLower(http.StatusText(mapping.StatusCode))
+		}
+	}
+	return c, true
+}
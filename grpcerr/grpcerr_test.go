@@ -0,0 +1,74 @@
+package grpcerr
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/A-pen-app/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRegister(t *testing.T) {
+	if err := Register(); err != nil {
+		t.Fatal(err)
+	}
+	const backendMessage = "backend says: post 7"
+	tests := []struct {
+		code       codes.Code
+		wantStatus int
+		wantCode   errors.ErrorCode
+	}{
+		{codes.Canceled, errors.StatusClientClosedRequest, errors.KeyClientClosedRequest},
+		{codes.Unknown, http.StatusInternalServerError, errors.KeyInternalError},
+		{codes.InvalidArgument, http.StatusBadRequest, errors.KeyWrongParams},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout, errors.KeyGatewayTimeout},
+		{codes.NotFound, http.StatusNotFound, errors.KeyNotFound},
+		{codes.AlreadyExists, http.StatusConflict, errors.KeyDuplicateEntry},
+		{codes.PermissionDenied, http.StatusForbidden, errors.KeyPermissionDenied},
+		{codes.ResourceExhausted, http.StatusTooManyRequests, errors.KeyTooManyRequests},
+		{codes.FailedPrecondition, http.StatusBadRequest, errors.KeyNotAllowed},
+		{codes.Aborted, http.StatusConflict, errors.KeyConflict},
+		{codes.OutOfRange, http.StatusBadRequest, errors.KeyWrongParams},
+		{codes.Unimplemented, http.StatusNotImplemented, errors.KeyNotImplemented},
+		{codes.Internal, http.StatusInternalServerError, errors.KeyInternalError},
+		{codes.Unavailable, http.StatusServiceUnavailable, errors.KeyServiceUnavailable},
+		{codes.DataLoss, http.StatusInternalServerError, errors.KeyInternalError},
+		{codes.Unauthenticated, http.StatusUnauthorized, errors.KeyUnauthorized},
+	}
+	if len(tests) != len(codeMappings) {
+		t.Fatalf("%d codes tested, want all %d mapped codes", len(tests), len(codeMappings))
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("calling posts: %w", status.Error(tt.code, backendMessage)), "post_id", 7)
+			resp := errors.ResponseFor(httptest.NewRequest(http.MethodGet, "/posts/7", nil), err, errors.WithLogging(false))
+			if resp.Status != tt.wantStatus || resp.Code != tt.wantCode {
+				t.Fatalf("response = %d %s, want %d %s", resp.Status, resp.Code, tt.wantStatus, tt.wantCode)
+			}
+			if resp.Body == nil {
+				return
+			}
+			if got := resp.Body.Details[DetailKeyGRPCCode]; got != tt.code.String() {
+				t.Errorf("%s = %v, want %s", DetailKeyGRPCCode, got, tt.code)
+			}
+			if sent := resp.Body.Message == backendMessage; sent != (tt.wantStatus < http.StatusInternalServerError) {
+				t.Errorf("message = %q, want the backend message only for 4xx codes", resp.Body.Message)
+			}
+		})
+	}
+}
+
+func TestRegisterLeavesOtherErrors(t *testing.T) {
+	if err := Register(); err != nil {
+		t.Fatal(err)
+	}
+	if got := errors.StatusOf(fmt.Errorf("not a gRPC error")); got != http.StatusInternalServerError {
+		t.Errorf("StatusOf() = %d, want %d", got, http.StatusInternalServerError)
+	}
+	if got := errors.StatusOf(errors.Wrap(errors.ErrorConflict, "k", "v")); got != http.StatusConflict {
+		t.Errorf("StatusOf() = %d, want %d", got, http.StatusConflict)
+	}
+}
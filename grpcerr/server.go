@@ -0,0 +1,82 @@
+package grpcerr
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/A-pen-app/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// MetadataKeyRequestID is the ErrorInfo metadata key holding the request ID.
+const MetadataKeyRequestID = errors.RPCMetadataKeyRequestID
+
+// UnaryServerInterceptor returns an interceptor that converts errors returned by unary
+// handlers into gRPC status errors, so that gRPC services share the error taxonomy of
+// the HTTP ones. Errors are resolved and logged as errors.Handle does, and their code is
+// converted with errors.RPCCodeFor, e.g. PERMISSION_DENIED to PermissionDenied.
+//
+// The status message is the response message, so 5xx messages are generic and, with
+// WithProductionMessages, 4xx ones are too. The status carries an errdetails.ErrorInfo
+// whose reason is the error code and whose metadata holds the details, with non-string
+// values JSON-encoded, and the request ID; retryable errors with a retry delay carry an
+// errdetails.RetryInfo as well. Errors that already are gRPC status errors are logged
+// but returned unchanged. Options taking a *gin.Context don't apply.
+func UnaryServerInterceptor(opts ...errors.Option) grpc.UnaryServerInterceptor {
+	responder := errors.NewResponder(opts...)
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, toStatusError(ctx, err, responder)
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming handlers. An error
+// returned after messages were sent still ends the stream with the converted status in
+// its trailer, and a stream the client canceled ends with Canceled, logged at info level.
+func StreamServerInterceptor(opts ...errors.Option) grpc.StreamServerInterceptor {
+	responder := errors.NewResponder(opts...)
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return toStatusError(ss.Context(), err, responder)
+		}
+		return nil
+	}
+}
+
+// toStatusError resolves and logs err, and returns the status error sent for it.
+func toStatusError(ctx context.Context, err error, responder *errors.Responder) error {
+	resp := responder.ResponseForContext(ctx, err)
+	if _, ok := err.(grpcStatus); ok {
+		return err
+	}
+	if resp.Body == nil {
+		return status.Error(codes.Canceled, context.Canceled.Error())
+	}
+	code := codes.Code(errors.RPCCodeFor(resp.Code, resp.Status))
+	s := status.New(code, resp.Body.Message)
+	if withDetails, detailsErr := s.WithDetails(statusDetails(resp)...); detailsErr == nil {
+		s = withDetails
+	}
+	return s.Err()
+}
+
+// statusDetails returns the status details for resp: an ErrorInfo, and a RetryInfo when
+// resp has a Retry-After header.
+func statusDetails(resp errors.Response) []protoadapt.MessageV1 {
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: resp.Body.Code, Metadata: errors.RPCMetadata(resp.Body)}}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		details = append(details, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(time.Duration(seconds) * time.Second),
+		})
+	}
+	return details
+}
@@ -0,0 +1,166 @@
+package grpcerr
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/A-pen-app/errors"
+	"github.com/A-pen-app/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestMain(m *testing.M) {
+	logging.Initialize(nil)
+	m.Run()
+}
+
+// healthServer fails every call with err.
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	err error
+}
+
+func (s healthServer) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	return nil, s.err
+}
+
+func (s healthServer) Watch(_ *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+		return err
+	}
+	return s.err
+}
+
+// dial serves a healthServer failing with err over an in-memory listener, behind the
+// interceptors, and returns a client for it.
+func dial(t *testing.T, err error, opts ...errors.Option) grpc_health_v1.HealthClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(opts...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(opts...)),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, healthServer{err: err})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, dialErr := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if dialErr != nil {
+		t.Fatal(dialErr)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+// errorInfo returns the ErrorInfo detail of s.
+func errorInfo(t *testing.T, s *status.Status) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, d := range s.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatalf("status %v has no ErrorInfo", s)
+	return nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{"not found", errors.Wrap(errors.ErrorNotFound, "post_id", 7), codes.NotFound, "NOT_FOUND"},
+		{"wrong params", errors.ErrorWrongParams, codes.InvalidArgument, "WRONG_PARAMETER"},
+		{"deadline", context.DeadlineExceeded, codes.DeadlineExceeded, "GATEWAY_TIMEOUT"},
+		{"status error", status.Error(codes.Aborted, "aborted"), codes.Aborted, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dial(t, tt.err)
+			_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			s := status.Convert(err)
+			if s.Code() != tt.wantCode {
+				t.Fatalf("code = %v, want %v", s.Code(), tt.wantCode)
+			}
+			if tt.wantReason == "" {
+				return
+			}
+			if info := errorInfo(t, s); info.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", info.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptorDetails(t *testing.T) {
+	err := errors.Wrap(errors.New(errors.KeyTooManyRequests, http.StatusTooManyRequests, "slow down"), "user_id", 123)
+	client := dial(t, errors.WithRetryAfter(err, 30*time.Second))
+	_, callErr := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	s := status.Convert(callErr)
+	if s.Code() != codes.ResourceExhausted {
+		t.Fatalf("code = %v, want %v", s.Code(), codes.ResourceExhausted)
+	}
+	info := errorInfo(t, s)
+	if info.Metadata["user_id"] != "123" {
+		t.Errorf("metadata = %v, want user_id=123", info.Metadata)
+	}
+	for _, d := range s.Details() {
+		if retry, ok := d.(*errdetails.RetryInfo); ok {
+			if got := retry.RetryDelay.AsDuration(); got != 30*time.Second {
+				t.Errorf("retry delay = %v, want 30s", got)
+			}
+			return
+		}
+	}
+	t.Error("status has no RetryInfo")
+}
+
+func TestUnaryServerInterceptorCodePrefix(t *testing.T) {
+	errors.SetCodePrefix("FEED")
+	t.Cleanup(func() { errors.SetCodePrefix("") })
+
+	// DUPLICATE_ENTRY maps to AlreadyExists by code, where its 409 status would give
+	// Aborted, so the prefixed code sent must not be what is mapped.
+	client := dial(t, errors.WithCode(errors.ErrorConflict, errors.KeyDuplicateEntry))
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	s := status.Convert(err)
+	if s.Code() != codes.AlreadyExists {
+		t.Errorf("code = %v, want %v", s.Code(), codes.AlreadyExists)
+	}
+	if info := errorInfo(t, s); info.Reason != "FEED.DUPLICATE_ENTRY" {
+		t.Errorf("reason = %q, want %q", info.Reason, "FEED.DUPLICATE_ENTRY")
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	client := dial(t, errors.ErrorConflict)
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("first Recv() = %v, want a message", err)
+	}
+	_, err = stream.Recv()
+	s := status.Convert(err)
+	if s.Code() != codes.Aborted {
+		t.Errorf("code = %v, want %v", s.Code(), codes.Aborted)
+	}
+	if info := errorInfo(t, s); info.Reason != "CONFLICT" {
+		t.Errorf("reason = %q, want %q", info.Reason, "CONFLICT")
+	}
+}
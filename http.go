@@ -0,0 +1,196 @@
+package errors
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/A-pen-app/logging"
+)
+
+// StdHandlerFunc defines a net/http handler function that returns an error.
+type StdHandlerFunc func(http.ResponseWriter, *http.Request) error
+
+// HandleHTTP wraps a StdHandlerFunc so that plain net/http services get the same error
+// responses and logging as Handle. Options work as they do for Handle, except for those
+// taking a *gin.Context: WithRequestIDFunc, WithLegacyCodesFunc and WithEncoder don't
+// apply, so request IDs come from the OpenTelemetry span and the response format is
+// always negotiated from the Accept header. If the handler already started its response
+// before returning the error, the error is only logged.
+func HandleHTTP(fn StdHandlerFunc, opts ...Option) http.Handler {
+	return WrapHandler(fn, opts...)
+}
+
+// WrapHandler is HandleHTTP returning an http.HandlerFunc, for routers such as chi whose
+// routing methods take one.
+func WrapHandler(fn StdHandlerFunc, opts ...Option) http.HandlerFunc {
+	configs := newConfigCache(opts)
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		if err := fn(rw, r); err != nil {
+			handleHTTPError(rw, r, err, configs.get())
+		}
+	}
+}
+
+// handleHTTPError is handleError for net/http handlers.
+func handleHTTPError(w *responseWriter, r *http.Request, err error, cfg *handlerConfig) {
+	scope := httpScope(r, cfg)
+	scope.responseStarted = w.written
+	resp := newResponse(err, cfg, scope)
+	if w.written {
+		return
+	}
+	if resp.Body == nil {
+		w.WriteHeader(resp.Status)
+		return
+	}
+
+	format := negotiateFormat(r.Header.Get("Accept"))
+	contentType, body, marshalErr := cfg.encodeBody(format, resp.Status, *resp.Body, r.URL.Path)
+	if marshalErr != nil {
+		logging.Error(r.Context(), "errors: encoding response: %v", marshalErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(resp.Status)
+	w.Write(body)
+}
+
+// Response is the error response for a failed request, built by ResponseFor for adapters
+// to frameworks other than gin and net/http.
+type Response struct {
+	Status int
+	// Code is the resolved code, before code aliases, prefixes and legacy codes are
+	// applied to the one sent in Body, for mapping it onto other protocols' codes.
+	Code ErrorCode
+	// Header holds the headers to send, such as Retry-After and WWW-Authenticate.
+	Header http.Header
+	// Body is nil when the client has gone away, in which case there is no one to read
+	// it and only the status is sent.
+	Body *HttpError
+}
+
+// ResponseFor resolves err like Handle does, logs it against r and returns the response
+// to send. The request ID is the trace ID of the OpenTelemetry span in r's context, and
+// options taking a *gin.Context don't apply. A nil err yields a 200 without a body.
+// Adapters responding with the same options every time should use a Responder instead.
+func ResponseFor(r *http.Request, err error, opts ...Option) Response {
+	if err == nil {
+		return Response{Status: http.StatusOK}
+	}
+	cfg := effectiveConfig(opts)
+	return newResponse(err, cfg, httpScope(r, cfg))
+}
+
+// httpScope returns the requestScope of a net/http request.
+func httpScope(r *http.Request, cfg *handlerConfig) requestScope {
+	return requestScope{
+		ctx:            r.Context(),
+		acceptLanguage: r.Header.Get("Accept-Language"),
+		route:          r.Pattern,
+		legacyCodes:    cfg.legacyCodesEnabled,
+	}
+}
+
+// ResponseForContext is ResponseFor for transports other than HTTP, such as gRPC, logging
+// against ctx. Validation messages are not translated, as there is no Accept-Language
+// header to pick a locale from.
+func ResponseForContext(ctx context.Context, err error, opts ...Option) Response {
+	if err == nil {
+		return Response{Status: http.StatusOK}
+	}
+	cfg := effectiveConfig(opts)
+	return newResponse(err, cfg, contextScope(ctx, cfg))
+}
+
+// contextScope returns the requestScope of a request on another transport.
+func contextScope(ctx context.Context, cfg *handlerConfig) requestScope {
+	return requestScope{ctx: ctx, legacyCodes: cfg.legacyCodesEnabled}
+}
+
+// Responder builds responses like ResponseFor and ResponseForContext with options given
+// once, for adapters to create when they are set up. Like Handle, it builds the config
+// from the options once instead of for every error, and again only after Configure.
+type Responder struct {
+	configs *configCache
+}
+
+// NewResponder returns a Responder for opts.
+func NewResponder(opts ...Option) *Responder {
+	return &Responder{configs: newConfigCache(opts)}
+}
+
+// ResponseFor is ResponseFor with the Responder's options.
+func (rs *Responder) ResponseFor(r *http.Request, err error) Response {
+	if err == nil {
+		return Response{Status: http.StatusOK}
+	}
+	cfg := rs.configs.get()
+	return newResponse(err, cfg, httpScope(r, cfg))
+}
+
+// ResponseForContext is ResponseForContext with the Responder's options.
+func (rs *Responder) ResponseForContext(ctx context.Context, err error) Response {
+	if err == nil {
+		return Response{Status: http.StatusOK}
+	}
+	cfg := rs.configs.get()
+	return newResponse(err, cfg, contextScope(ctx, cfg))
+}
+
+// newResponse builds the Response for err within scope.
+func newResponse(err error, cfg *handlerConfig, scope requestScope) Response {
+	resp := buildResponse(err, cfg, scope)
+	if resp.clientGone {
+		return Response{Status: resp.status, Code: resp.code}
+	}
+	resp.body.RequestID = requestIDFromContext(scope.ctx)
+	return Response{Status: resp.status, Code: resp.code, Header: resp.header, Body: &resp.body}
+}
+
+// responseWriter records whether a handler has started its response, after which an
+// error response can no longer be sent.
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// Informational responses such as 103 Early Hints don't commit the final status
+	if status >= http.StatusOK {
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush flushes the wrapped writer if it supports flushing.
+func (w *responseWriter) Flush() {
+	w.written = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack hijacks the wrapped writer's connection, e.g. for a WebSocket upgrade, after
+// which an error response can no longer be sent.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,175 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// serveHTTP starts a server for fn wrapped with HandleHTTP, with requests traced as by
+// withTrace, and sends it a POST of body.
+func serveHTTP(t *testing.T, fn StdHandlerFunc, body string, opts ...Option) (*http.Response, HttpError) {
+	t.Helper()
+	handler := HandleHTTP(fn, opts...)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, withTrace(r))
+	}))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var httpErr HttpError
+	if resp.StatusCode >= http.StatusBadRequest && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(data, &httpErr); err != nil {
+			t.Fatalf("decoding response %q: %v", data, err)
+		}
+	}
+	return resp, httpErr
+}
+
+func TestHandleHTTP(t *testing.T) {
+	decode := func(_ http.ResponseWriter, r *http.Request) error {
+		var post struct {
+			Title string `json:"title"`
+		}
+		return json.NewDecoder(r.Body).Decode(&post)
+	}
+	tests := []struct {
+		name       string
+		fn         StdHandlerFunc
+		body       string
+		wantStatus int
+		wantCode   ErrorCode
+		wantDetail any
+	}{
+		{"sentinel", func(http.ResponseWriter, *http.Request) error { return ErrorNotFound }, "", http.StatusNotFound, KeyNotFound, nil},
+		{"wrapped", func(http.ResponseWriter, *http.Request) error {
+			return Wrap(fmt.Errorf("loading post: %w", ErrorPermissionDenied), "post_id", 7)
+		}, "", http.StatusForbidden, KeyPermissionDenied, float64(7)},
+		{"binding", decode, `{"title": }`, http.StatusBadRequest, KeyWrongParams, nil},
+		{"type mismatch", decode, `{"title": 1}`, http.StatusBadRequest, KeyWrongParams, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := serveHTTP(t, tt.fn, tt.body, WithLogging(false))
+			if resp.StatusCode != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Errorf("response = %d %s, want %d %s", resp.StatusCode, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantDetail != nil && body.Details["post_id"] != tt.wantDetail {
+				t.Errorf("details = %v, want post_id=%v", body.Details, tt.wantDetail)
+			}
+			if body.RequestID != testTraceID {
+				t.Errorf("request_id = %q, want %q", body.RequestID, testTraceID)
+			}
+		})
+	}
+}
+
+func TestHandleHTTPAfterWrite(t *testing.T) {
+	logs := captureLogs(t, func() {
+		resp, _ := serveHTTP(t, func(w http.ResponseWriter, _ *http.Request) error {
+			w.WriteHeader(http.StatusAccepted)
+			io.WriteString(w, "partial")
+			return ErrorConflict
+		}, "")
+		if resp.StatusCode != http.StatusAccepted {
+			t.Errorf("status = %d, want the handler's %d", resp.StatusCode, http.StatusAccepted)
+		}
+	})
+	if !strings.Contains(logs, "conflict") {
+		t.Errorf("logs = %q, want the error logged", logs)
+	}
+}
+
+func TestResponder(t *testing.T) {
+	t.Cleanup(func() { Configure() })
+	responder := NewResponder(WithLogging(false))
+	req := httptest.NewRequest(http.MethodGet, "/posts/7", nil)
+	err := Wrap(ErrorNotFound, "post_id", 7)
+
+	got, want := responder.ResponseFor(req, err), ResponseFor(req, err, WithLogging(false))
+	if got.Status != want.Status || got.Code != want.Code || got.Body.Message != want.Body.Message || got.Body.Details["post_id"] != want.Body.Details["post_id"] {
+		t.Errorf("ResponseFor() = %+v, want %+v as from the package-level ResponseFor", got, want)
+	}
+	if got := responder.ResponseForContext(req.Context(), err); got.Status != http.StatusNotFound || got.Body.Code != string(KeyNotFound) {
+		t.Errorf("ResponseForContext() = %d %s, want 404 %s", got.Status, got.Body.Code, KeyNotFound)
+	}
+	if got := responder.ResponseFor(req, nil); got.Status != http.StatusOK || got.Body != nil {
+		t.Errorf("ResponseFor(nil) = %+v, want a 200 without a body", got)
+	}
+
+	Configure(WithProductionMessages(true))
+	if got := responder.ResponseFor(req, fmt.Errorf("post 7: %w", ErrorNotFound)).Body.Message; got != "data not found" {
+		t.Errorf("message = %q after Configure, want the production message", got)
+	}
+}
+
+func TestWrapHandlerChi(t *testing.T) {
+	router := chi.NewRouter()
+	MountRouteHandlers(router, WithLogging(false))
+	router.Route("/users/{userID}", func(r chi.Router) {
+		r.Route("/posts", func(r chi.Router) {
+			r.Get("/{postID}", WrapHandler(func(w http.ResponseWriter, r *http.Request) error {
+				if chi.URLParam(r, "postID") != "7" {
+					return Wrap(ErrorNotFound, "user_id", chi.URLParam(r, "userID"), "post_id", chi.URLParam(r, "postID"))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return nil
+			}, WithLogging(false)))
+		})
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		wantStatus  int
+		wantCode    ErrorCode
+		wantDetails map[string]any
+		wantAllow   string
+	}{
+		{"found", http.MethodGet, "/users/42/posts/7", http.StatusNoContent, "", nil, ""},
+		{"handler error", http.MethodGet, "/users/42/posts/8", http.StatusNotFound, KeyNotFound, map[string]any{"user_id": "42", "post_id": "8"}, ""},
+		{"unknown route", http.MethodGet, "/users/42/comments", http.StatusNotFound, KeyNotFound, map[string]any{"path": "/users/42/comments"}, ""},
+		// chi doesn't tell custom 405 handlers the allowed methods.
+		{"wrong method", http.MethodDelete, "/users/42/posts/7", http.StatusMethodNotAllowed, KeyMethodNotAllowed, map[string]any{"method": "DELETE"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, withTrace(httptest.NewRequest(tt.method, tt.target, nil)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var body HttpError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+			}
+			if body.Code != string(tt.wantCode) || body.RequestID != testTraceID {
+				t.Errorf("body = %s %q, want %s with request_id %s", body.Code, body.RequestID, tt.wantCode, testTraceID)
+			}
+			if !reflect.DeepEqual(body.Details, tt.wantDetails) {
+				t.Errorf("details = %v, want %v", body.Details, tt.wantDetails)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+		})
+	}
+}
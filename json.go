@@ -0,0 +1,49 @@
+package errors
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleJSON wraps a handler that returns a payload or an error: errors are handled as by
+// Handle, and payloads are written as JSON with status 200, or the status set with
+// WithSuccessStatus. Handlers that have already written a response themselves are left
+// alone.
+func HandleJSON[T any](fn func(*gin.Context) (T, error), opts ...Option) gin.HandlerFunc {
+	configs := newConfigCache(opts)
+	return func(ctx *gin.Context) {
+		payload, err := fn(ctx)
+		cfg := configs.get()
+		if err != nil {
+			handleError(ctx, err, cfg)
+			return
+		}
+		if ctx.Writer.Written() {
+			return
+		}
+		status := cfg.successStatus
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status == http.StatusNoContent || (cfg.noContentForNil && isNil(payload)) {
+			ctx.Status(http.StatusNoContent)
+			ctx.Writer.WriteHeaderNow()
+			return
+		}
+		ctx.JSON(status, payload)
+	}
+}
+
+// isNil reports whether v is a nil pointer, slice, map, channel, func or interface.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testPost struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+func TestHandleJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		fn         func(*gin.Context) (*testPost, error)
+		opts       []Option
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "payload",
+			fn:         func(*gin.Context) (*testPost, error) { return &testPost{ID: 7, Title: "hello"}, nil },
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":7,"title":"hello"}`,
+		},
+		{
+			name:       "success status",
+			fn:         func(*gin.Context) (*testPost, error) { return &testPost{ID: 7, Title: "hello"}, nil },
+			opts:       []Option{WithSuccessStatus(http.StatusCreated)},
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":7,"title":"hello"}`,
+		},
+		{
+			name:       "error",
+			fn:         func(*gin.Context) (*testPost, error) { return nil, Wrap(ErrorNotFound, "post_id", 7) },
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":"NOT_FOUND","message":"data not found","details":{"post_id":7},"request_id":""}`,
+		},
+		{
+			name:       "error with a payload",
+			fn:         func(*gin.Context) (*testPost, error) { return &testPost{ID: 7}, ErrorConflict },
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"code":"CONFLICT","message":"conflict","request_id":""}`,
+		},
+		{
+			name:       "nil payload",
+			fn:         func(*gin.Context) (*testPost, error) { return nil, nil },
+			wantStatus: http.StatusOK,
+			wantBody:   `null`,
+		},
+		{
+			name:       "nil payload with no content",
+			fn:         func(*gin.Context) (*testPost, error) { return nil, nil },
+			opts:       []Option{WithNoContentForNil(true)},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "response written by the handler",
+			fn: func(ctx *gin.Context) (*testPost, error) {
+				ctx.String(http.StatusAccepted, "queued")
+				return nil, nil
+			},
+			wantStatus: http.StatusAccepted,
+			wantBody:   "queued",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/posts/7", HandleJSON(tt.fn, append(tt.opts, WithLogging(false))...))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/posts/7", nil))
+			if w.Code != tt.wantStatus || w.Body.String() != tt.wantBody {
+				t.Errorf("response = %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
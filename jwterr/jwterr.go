@@ -0,0 +1,107 @@
+// Package jwterr maps token validation errors from golang-jwt to 401 responses, so that
+// middlewares don't each have to translate them.
+package jwterr
+
+import (
+	stderrors "errors"
+	"net/http"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DetailKeyExpired is the details key flagging expired tokens, which clients should
+	// refresh rather than asking the user to sign in again.
+	DetailKeyExpired = "expired"
+	// DetailKeyKeyID is the details key holding the key ID set by WithToken.
+	DetailKeyKeyID = "kid"
+)
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about the golang-jwt v5 validation errors, anywhere
+// in the chain. They all map to 401 UNAUTHORIZED, with an "auth_reason" detail:
+//
+//	jwt.ErrTokenMalformed        -> token_malformed
+//	jwt.ErrTokenSignatureInvalid -> signature_invalid, logged at Warn
+//	jwt.ErrTokenExpired          -> token_expired, with "expired": true
+//	other claim errors           -> token_invalid
+//
+// Apart from signature failures, which may mean forged tokens, these are logged at Info.
+// Key errors such as jwt.ErrInvalidKeyType are left to the default 500, as they mean the
+// verifier is misconfigured. Calling Register more than once has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		registerErr = errors.RegisterTypeClassifier(classify)
+	})
+	return registerErr
+}
+
+// WithToken adds the key ID from the header of the token that failed validation to err's
+// details and log line. The parsed token is returned by jwt.Parse even on failure; a nil
+// token or one without a "kid" header leaves err unchanged.
+func WithToken(err error, token *jwt.Token) error {
+	if token == nil {
+		return err
+	}
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return err
+	}
+	return errors.Wrap(err, DetailKeyKeyID, kid)
+}
+
+// claimErrors are the claim validation errors reported as token_invalid.
+var claimErrors = []error{
+	jwt.ErrTokenUnverifiable,
+	jwt.ErrTokenRequiredClaimMissing,
+	jwt.ErrTokenInvalidAudience,
+	jwt.ErrTokenUsedBeforeIssued,
+	jwt.ErrTokenInvalidIssuer,
+	jwt.ErrTokenInvalidSubject,
+	jwt.ErrTokenNotValidYet,
+	jwt.ErrTokenInvalidId,
+	jwt.ErrTokenInvalidClaims,
+	jwt.ErrInvalidType,
+}
+
+// classify maps the jwt errors in a chain. It is registered for the error interface, as
+// jwt reports several sentinels joined in one error.
+func classify(err error) (errors.Classification, bool) {
+	if stderrors.Is(err, jwt.ErrInvalidKey) || stderrors.Is(err, jwt.ErrInvalidKeyType) ||
+		stderrors.Is(err, jwt.ErrHashUnavailable) {
+		return errors.Classification{}, false
+	}
+	switch {
+	case stderrors.Is(err, jwt.ErrTokenMalformed):
+		return unauthorized(errors.AuthReasonTokenMalformed, "malformed token", errors.SeverityInfo), true
+	case stderrors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return unauthorized(errors.AuthReasonSignatureInvalid, "invalid token signature", errors.SeverityWarn), true
+	case stderrors.Is(err, jwt.ErrTokenExpired):
+		c := unauthorized(errors.AuthReasonTokenExpired, "token expired", errors.SeverityInfo)
+		c.Details[DetailKeyExpired] = true
+		return c, true
+	}
+	for _, claimErr := range claimErrors {
+		if stderrors.Is(err, claimErr) {
+			return unauthorized(errors.AuthReasonTokenInvalid, "invalid token", errors.SeverityInfo), true
+		}
+	}
+	return errors.Classification{}, false
+}
+
+// unauthorized returns a 401 classification with the given auth reason.
+func unauthorized(reason, message string, severity errors.Severity) errors.Classification {
+	return errors.Classification{
+		Code:     errors.KeyUnauthorized,
+		Status:   http.StatusUnauthorized,
+		Message:  message,
+		Details:  map[string]any{errors.DetailKeyAuthReason: reason},
+		Severity: severity,
+	}
+}
@@ -0,0 +1,94 @@
+package jwterr
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/A-pen-app/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+var secret = []byte("test secret")
+
+// sign returns a token with claims signed with key and "kid" set to "k1".
+func sign(t *testing.T, claims jwt.MapClaims, key []byte) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = "k1"
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// parse validates s against secret, or against key when it is set, as a service would.
+func parse(s string, key any) (*jwt.Token, error) {
+	if key == nil {
+		key = secret
+	}
+	return jwt.Parse(s, func(*jwt.Token) (any, error) { return key, nil }, jwt.WithIssuer("a-pen"))
+}
+
+func TestRegister(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		token      string
+		key        any
+		wantStatus int
+		wantReason any
+	}{
+		{"malformed", "not.a.token", nil, http.StatusUnauthorized, errors.AuthReasonTokenMalformed},
+		{"signature invalid", sign(t, jwt.MapClaims{"iss": "a-pen"}, []byte("other secret")), nil, http.StatusUnauthorized, errors.AuthReasonSignatureInvalid},
+		{"expired", sign(t, jwt.MapClaims{"iss": "a-pen", "exp": now.Add(-time.Hour).Unix()}, secret), nil, http.StatusUnauthorized, errors.AuthReasonTokenExpired},
+		{"not valid yet", sign(t, jwt.MapClaims{"iss": "a-pen", "nbf": now.Add(time.Hour).Unix()}, secret), nil, http.StatusUnauthorized, errors.AuthReasonTokenInvalid},
+		{"wrong issuer", sign(t, jwt.MapClaims{"iss": "other"}, secret), nil, http.StatusUnauthorized, errors.AuthReasonTokenInvalid},
+		{"invalid key type", sign(t, jwt.MapClaims{"iss": "a-pen"}, secret), "not a []byte", http.StatusInternalServerError, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.token, tt.key)
+			if err == nil {
+				t.Fatal("Parse() = nil, want an error")
+			}
+			err = fmt.Errorf("authenticating: %w", err)
+			resp := errors.ResponseFor(httptest.NewRequest(http.MethodGet, "/me", nil), err, errors.WithLogging(false))
+			if resp.Status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.Status, tt.wantStatus)
+			}
+			if got := resp.Body.Details[errors.DetailKeyAuthReason]; got != tt.wantReason {
+				t.Errorf("%s = %v, want %v", errors.DetailKeyAuthReason, got, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestRegisterExpiredFlag(t *testing.T) {
+	_, err := parse(sign(t, jwt.MapClaims{"iss": "a-pen", "exp": time.Now().Add(-time.Hour).Unix()}, secret), nil)
+	resp := errors.ResponseFor(httptest.NewRequest(http.MethodGet, "/me", nil), err, errors.WithLogging(false))
+	if resp.Body.Details[DetailKeyExpired] != true {
+		t.Errorf("details = %v, want %s=true", resp.Body.Details, DetailKeyExpired)
+	}
+}
+
+func TestWithToken(t *testing.T) {
+	token, err := parse(sign(t, jwt.MapClaims{"iss": "a-pen"}, []byte("other secret")), nil)
+	err = WithToken(err, token)
+	if got, _ := errors.GetData[string](err, DetailKeyKeyID); got != "k1" {
+		t.Errorf("%s = %q, want %q", DetailKeyKeyID, got, "k1")
+	}
+	if got := WithToken(err, nil); got != err {
+		t.Errorf("WithToken(err, nil) = %v, want err unchanged", got)
+	}
+}
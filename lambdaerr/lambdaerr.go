@@ -0,0 +1,102 @@
+// Package lambdaerr serves errors returned by AWS Lambda functions behind API Gateway in
+// the same HttpError format and with the same resolution and logging as the gin handlers
+// of the errors package.
+package lambdaerr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/A-pen-app/errors"
+	"github.com/A-pen-app/logging"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// HandlerFunc is a Lambda function serving API Gateway proxy requests.
+type HandlerFunc func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// HandleLambda wraps a HandlerFunc so that its errors are sent as an HttpError response
+// instead of failing the invocation, which API Gateway would report as a bare 502:
+//
+//	lambda.Start(lambdaerr.HandleLambda(getPost, errors.WithProductionMessages(true)))
+//
+// Errors resolve through the errors registry and are logged as errors.Handle does, with
+// the resource as route and validation messages translated for the Accept-Language
+// header. Headers such as Retry-After and WWW-Authenticate are set on the response as
+// multi-value headers, so that repeated values are kept. The request ID is the trace ID
+// of the OpenTelemetry span in the context, falling back to the Lambda request ID and then
+// to the X-Amzn-Trace-Id header. Options work as they do for errors.Handle, except for
+// those taking a *gin.Context.
+func HandleLambda(fn HandlerFunc, opts ...errors.Option) HandlerFunc {
+	responder := errors.NewResponder(opts...)
+	return func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		resp, err := fn(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		return errorResponse(ctx, req, err, responder), nil
+	}
+}
+
+// errorResponse resolves and logs err, and returns the response sent for it.
+func errorResponse(ctx context.Context, req events.APIGatewayProxyRequest, err error, responder *errors.Responder) events.APIGatewayProxyResponse {
+	r := httpRequest(ctx, req)
+	resp := responder.ResponseFor(r, err)
+	out := events.APIGatewayProxyResponse{StatusCode: resp.Status, MultiValueHeaders: multiValueHeaders(resp.Header)}
+	if resp.Body == nil || req.HTTPMethod == http.MethodHead {
+		return out
+	}
+	if resp.Body.RequestID == "" {
+		resp.Body.RequestID = requestID(r)
+	}
+	body, marshalErr := json.Marshal(resp.Body)
+	if marshalErr != nil {
+		logging.Error(ctx, "errors: encoding response: %v", marshalErr)
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError}
+	}
+	out.MultiValueHeaders["Content-Type"] = []string{"application/json; charset=utf-8"}
+	out.Body = string(body)
+	return out
+}
+
+// multiValueHeaders returns h as API Gateway multi-value headers, which unlike the
+// single-value ones keep repeated values, such as several WWW-Authenticate challenges.
+func multiValueHeaders(h http.Header) map[string][]string {
+	headers := make(map[string][]string, len(h)+1)
+	for key, values := range h {
+		headers[key] = append([]string(nil), values...)
+	}
+	return headers
+}
+
+// httpRequest returns req as an *http.Request carrying ctx, its headers and its resource
+// as pattern, for errors.ResponseFor.
+func httpRequest(ctx context.Context, req events.APIGatewayProxyRequest) *http.Request {
+	r := &http.Request{
+		Method:  req.HTTPMethod,
+		Header:  make(http.Header),
+		Pattern: req.Resource,
+	}
+	for key, values := range req.MultiValueHeaders {
+		for _, value := range values {
+			r.Header.Add(key, value)
+		}
+	}
+	for key, value := range req.Headers {
+		if r.Header.Get(key) == "" {
+			r.Header.Set(key, value)
+		}
+	}
+	return r.WithContext(ctx)
+}
+
+// requestID returns the Lambda request ID of r's context, else its X-Amzn-Trace-Id
+// header.
+func requestID(r *http.Request) string {
+	if lc, ok := lambdacontext.FromContext(r.Context()); ok && lc.AwsRequestID != "" {
+		return lc.AwsRequestID
+	}
+	return r.Header.Get("X-Amzn-Trace-Id")
+}
@@ -0,0 +1,136 @@
+package lambdaerr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/A-pen-app/errors"
+	"github.com/A-pen-app/logging"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+func TestMain(m *testing.M) {
+	logging.Initialize(nil)
+	m.Run()
+}
+
+// event is an API Gateway proxy event as Lambda delivers it.
+const event = `{
+	"resource": "/posts/{id}",
+	"path": "/posts/7",
+	"httpMethod": "GET",
+	"headers": {"Accept-Language": "en", "X-Amzn-Trace-Id": "Root=1-67891233-abcdef012345678912345678"},
+	"multiValueHeaders": {"Accept-Language": ["en"], "X-Amzn-Trace-Id": ["Root=1-67891233-abcdef012345678912345678"]},
+	"pathParameters": {"id": "7"},
+	"requestContext": {"resourcePath": "/posts/{id}", "httpMethod": "GET", "requestId": "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"},
+	"body": null,
+	"isBase64Encoded": false
+}`
+
+// invoke runs fn wrapped with HandleLambda through the Lambda runtime's handler on event,
+// and returns the response it serializes.
+func invoke(t *testing.T, ctx context.Context, fn HandlerFunc, opts ...errors.Option) events.APIGatewayProxyResponse {
+	t.Helper()
+	payload, err := lambda.NewHandler(HandleLambda(fn, opts...)).Invoke(ctx, []byte(event))
+	if err != nil {
+		t.Fatalf("Invoke() = %v, want the error served as a response", err)
+	}
+	var resp events.APIGatewayProxyResponse
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// failing returns a HandlerFunc failing with err.
+func failing(err error) HandlerFunc {
+	return func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{}, err
+	}
+}
+
+// decode returns the HttpError of resp.
+func decode(t *testing.T, resp events.APIGatewayProxyResponse) errors.HttpError {
+	t.Helper()
+	var body errors.HttpError
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+		t.Fatalf("decoding body %q: %v", resp.Body, err)
+	}
+	return body
+}
+
+func TestHandleLambda(t *testing.T) {
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "aws-req-1"})
+	resp := invoke(t, ctx, failing(errors.Wrap(errors.ErrorNotFound, "post_id", 7)), errors.WithLogging(false))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if got := resp.MultiValueHeaders["Content-Type"]; !reflect.DeepEqual(got, []string{"application/json; charset=utf-8"}) {
+		t.Errorf("Content-Type = %q, want JSON", got)
+	}
+	body := decode(t, resp)
+	if body.Code != string(errors.KeyNotFound) || body.Details["post_id"] != float64(7) {
+		t.Errorf("body = %+v, want NOT_FOUND with post_id=7", body)
+	}
+	if body.RequestID != "aws-req-1" {
+		t.Errorf("request_id = %q, want the Lambda request ID", body.RequestID)
+	}
+}
+
+func TestHandleLambdaTraceHeaderRequestID(t *testing.T) {
+	body := decode(t, invoke(t, context.Background(), failing(errors.ErrorConflict), errors.WithLogging(false)))
+	if want := "Root=1-67891233-abcdef012345678912345678"; body.RequestID != want {
+		t.Errorf("request_id = %q, want %q", body.RequestID, want)
+	}
+}
+
+func TestHandleLambdaRetryAfter(t *testing.T) {
+	err := errors.WithRetryAfter(errors.ErrorTooManyRequests, 30*time.Second)
+	resp := invoke(t, context.Background(), failing(err), errors.WithLogging(false))
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if got := resp.MultiValueHeaders["Retry-After"]; !reflect.DeepEqual(got, []string{"30"}) {
+		t.Errorf("headers = %v, want Retry-After 30", resp.MultiValueHeaders)
+	}
+}
+
+func TestMultiValueHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Add("WWW-Authenticate", `Bearer realm="api"`)
+	h.Add("WWW-Authenticate", `Basic realm="api"`)
+	h.Set("Retry-After", "30")
+	want := map[string][]string{
+		"Www-Authenticate": {`Bearer realm="api"`, `Basic realm="api"`},
+		"Retry-After":      {"30"},
+	}
+	if got := multiValueHeaders(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("multiValueHeaders() = %v, want %v", got, want)
+	}
+}
+
+func TestHandleLambdaAuthChallenge(t *testing.T) {
+	err := errors.WithAuthChallenge(errors.ErrorUnauthorized, `Bearer realm="api", error="invalid_token"`)
+	resp := invoke(t, context.Background(), failing(err), errors.WithLogging(false))
+	if got := resp.MultiValueHeaders["Www-Authenticate"]; !reflect.DeepEqual(got, []string{`Bearer realm="api", error="invalid_token"`}) {
+		t.Errorf("WWW-Authenticate = %q, want the challenge", got)
+	}
+	if len(resp.Headers) > 0 {
+		t.Errorf("single-value headers = %v, want every header in MultiValueHeaders", resp.Headers)
+	}
+}
+
+func TestHandleLambdaSuccess(t *testing.T) {
+	resp := invoke(t, context.Background(), func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: `{"id":7}`}, nil
+	})
+	if resp.StatusCode != http.StatusOK || resp.Body != `{"id":7}` {
+		t.Errorf("response = %d %q, want the handler's", resp.StatusCode, resp.Body)
+	}
+}
@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// LazyValue is a detail value that is computed only when the error is rendered.
+// Values of type func() any passed to Wrap are treated as LazyValues too.
+type LazyValue interface {
+	Value() any
+}
+
+// Lazy returns a LazyValue backed by fn, for attaching expensive context to an error.
+func Lazy(fn func() any) LazyValue {
+	return &lazyValue{fn: fn}
+}
+
+// lazyValue evaluates fn at most once, recovering from panics inside it.
+type lazyValue struct {
+	once  sync.Once
+	fn    func() any
+	value any
+}
+
+func (l *lazyValue) Value() any {
+	l.once.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				l.value = fmt.Sprintf("[lazy value panicked: %v]", r)
+			}
+		}()
+		l.value = l.fn()
+	})
+	return l.value
+}
+
+func (l *lazyValue) String() string {
+	return fmt.Sprint(l.Value())
+}
+
+func (l *lazyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Value())
+}
+
+// wrapLazy stores lazy values in a form that is evaluated at most once.
+func wrapLazy(value any) any {
+	switch v := value.(type) {
+	case *lazyValue:
+		return v
+	case func() any:
+		return &lazyValue{fn: v}
+	case LazyValue:
+		return &lazyValue{fn: v.Value}
+	}
+	return value
+}
+
+// resolveValue evaluates value if it is lazy.
+func resolveValue(value any) any {
+	if lazy, ok := value.(*lazyValue); ok {
+		return lazy.Value()
+	}
+	return value
+}
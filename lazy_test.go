@@ -0,0 +1,63 @@
+package errors
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLazyEvaluatedAtMostOnce(t *testing.T) {
+	var calls atomic.Int32
+	err := Wrap(ErrorNotFound, "payload", func() any {
+		calls.Add(1)
+		return "expensive"
+	})
+	if got := calls.Load(); got != 0 {
+		t.Fatalf("evaluated %d times by Wrap, want 0", got)
+	}
+
+	if !strings.Contains(err.Error(), "payload=expensive") {
+		t.Errorf("Error() = %q, want the lazy value rendered", err.Error())
+	}
+	_ = err.Error()
+	if _, body := serve(t, returning(err), WithLogging(false)); body.Details["payload"] != "expensive" {
+		t.Errorf("details = %v, want payload=expensive", body.Details)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("evaluated %d times, want 1", got)
+	}
+}
+
+func TestLazyPanicRecovered(t *testing.T) {
+	err := Wrap(ErrorNotFound, "diff", Lazy(func() any { panic("boom") }))
+	const placeholder = "[lazy value panicked: boom]"
+	if !strings.Contains(err.Error(), placeholder) {
+		t.Errorf("Error() = %q, want the placeholder %q", err.Error(), placeholder)
+	}
+	w, body := serve(t, returning(err), WithLogging(false))
+	if w.Code != http.StatusNotFound || body.Details["diff"] != placeholder {
+		t.Errorf("response = %d %v, want 404 with diff=%q", w.Code, body.Details, placeholder)
+	}
+}
+
+// BenchmarkLazyUnhandled wraps with an expensive lazy value that is never evaluated, as
+// for errors that are swallowed or retried.
+func BenchmarkLazyUnhandled(b *testing.B) {
+	expensive := func() any {
+		b.Fatal("lazy value evaluated for an error that was never handled")
+		return nil
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = Wrap(ErrorNotFound, "payload", expensive)
+	}
+}
+
+// BenchmarkEagerUnhandled is BenchmarkLazyUnhandled with the value computed up front.
+func BenchmarkEagerUnhandled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = Wrap(ErrorNotFound, "payload", strings.Repeat("x", 4096))
+	}
+}
@@ -0,0 +1,94 @@
+package errors
+
+import (
+	"encoding/json"
+	"reflect"
+	"unicode/utf8"
+)
+
+// truncationMarker is appended to strings shortened to fit a limit.
+const truncationMarker = "…"
+
+// truncateString shortens s to at most max bytes, marker included, cutting at a rune
+// boundary. Non-positive limits leave s unchanged, and limits too small for the marker
+// cut s without one.
+func truncateString(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	marker := truncationMarker
+	if max < len(marker) {
+		marker = ""
+	}
+	cut := max - len(marker)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + marker
+}
+
+// limitValue applies the value length limit to a value echoed back in details: strings
+// are truncated, and composite values whose JSON form exceeds the limit are dropped.
+func limitValue(value any, max int) any {
+	if max <= 0 || value == nil {
+		return value
+	}
+	if s, ok := value.(string); ok {
+		return truncateString(s, max)
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Pointer, reflect.Interface:
+		encoded, err := json.Marshal(value)
+		if err != nil || len(encoded) > max {
+			return nil
+		}
+	}
+	return value
+}
+
+// capDetails shrinks details, in place, until their JSON form fits in max bytes. Field
+// violations are dropped from the end first, updating the fields_shown counter; if that
+// isn't enough, the remaining keys are dropped in reverse sorted order. Either way
+// "truncated" is set. Non-positive limits leave details unchanged.
+func capDetails(details map[string]any, max int) {
+	if max <= 0 || fitsIn(details, max) {
+		return
+	}
+	details["truncated"] = true
+
+	if violations, ok := details["fields"].([]FieldViolation); ok {
+		if _, exists := details["fields_total"]; !exists {
+			details["fields_total"] = len(violations)
+		}
+		// Binary search for the largest prefix that fits
+		lo, hi := 0, len(violations)
+		for lo < hi {
+			mid := (lo + hi + 1) / 2
+			details["fields"] = violations[:mid]
+			if fitsIn(details, max) {
+				lo = mid
+			} else {
+				hi = mid - 1
+			}
+		}
+		details["fields"] = violations[:lo]
+		details["fields_shown"] = lo
+		if fitsIn(details, max) {
+			return
+		}
+	}
+
+	keys := sortedKeys(details)
+	for i := len(keys) - 1; i >= 0 && !fitsIn(details, max); i-- {
+		if keys[i] != "truncated" {
+			delete(details, keys[i])
+		}
+	}
+}
+
+// fitsIn reports whether the JSON form of details is at most max bytes. Details that
+// can't be encoded are left to the encoder to report.
+func fitsIn(details map[string]any, max int) bool {
+	encoded, err := json.Marshal(details)
+	return err != nil || len(encoded) <= max
+}
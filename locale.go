@@ -0,0 +1,104 @@
+package errors
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	ut "github.com/go-playground/universal-translator"
+)
+
+// localeMu guards the translator registry below.
+var localeMu sync.RWMutex
+
+var (
+	translators   = map[string]ut.Translator{}
+	defaultLocale string
+)
+
+// localeTranslator is the translator picked for a request, with the locale it serves.
+type localeTranslator struct {
+	locale     string
+	translator ut.Translator
+}
+
+// RegisterTranslator registers the translator used for validation messages of requests
+// preferring locale, e.g. "ja", according to their Accept-Language header. The translator
+// needs the validator's translations registered with it, e.g. via
+// ja_translations.RegisterDefaultTranslations. Tags it has no translation for use the
+// built-in English messages.
+func RegisterTranslator(locale string, translator ut.Translator) {
+	localeMu.Lock()
+	defer localeMu.Unlock()
+	translators[strings.ToLower(locale)] = translator
+}
+
+// SetDefaultLocale sets the locale used for requests without an Accept-Language header
+// or preferring only unregistered locales. An empty locale, the default, falls back to
+// the built-in English messages.
+func SetDefaultLocale(locale string) {
+	localeMu.Lock()
+	defer localeMu.Unlock()
+	defaultLocale = strings.ToLower(locale)
+}
+
+// negotiateLocale picks the registered translator best matching an Accept-Language
+// header, trying each language range in preference order and then its base language,
+// e.g. "ja" for "ja-JP". It returns nil when no translator applies.
+func negotiateLocale(acceptLanguage string) *localeTranslator {
+	localeMu.RLock()
+	defer localeMu.RUnlock()
+	if len(translators) == 0 {
+		return nil
+	}
+	for _, locale := range parseAcceptLanguage(acceptLanguage) {
+		if translator, exists := translators[locale]; exists {
+			return &localeTranslator{locale, translator}
+		}
+		if base, _, found := strings.Cut(locale, "-"); found {
+			if translator, exists := translators[base]; exists {
+				return &localeTranslator{base, translator}
+			}
+		}
+	}
+	if translator, exists := translators[defaultLocale]; exists {
+		return &localeTranslator{defaultLocale, translator}
+	}
+	return nil
+}
+
+// parseAcceptLanguage returns the lowercased language ranges of an Accept-Language
+// header ordered by quality, dropping wildcards and ranges with q=0.
+func parseAcceptLanguage(header string) []string {
+	type languageRange struct {
+		locale  string
+		quality float64
+	}
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		locale, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+		if locale == "" || locale == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if q, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = q
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, languageRange{locale, quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	locales := make([]string, len(ranges))
+	for i, r := range ranges {
+		locales[i] = r.locale
+	}
+	return locales
+}
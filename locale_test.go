@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ja"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	ja_translations "github.com/go-playground/validator/v10/translations/ja"
+)
+
+func TestRegisterTranslator(t *testing.T) {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		t.Fatalf("binding.Validator.Engine() = %T, want *validator.Validate", binding.Validator.Engine())
+	}
+	uni := ut.New(en.New(), en.New(), ja.New())
+	enTrans, _ := uni.GetTranslator("en")
+	jaTrans, _ := uni.GetTranslator("ja")
+	if err := en_translations.RegisterDefaultTranslations(validate, enTrans); err != nil {
+		t.Fatal(err)
+	}
+	if err := ja_translations.RegisterDefaultTranslations(validate, jaTrans); err != nil {
+		t.Fatal(err)
+	}
+	RegisterTranslator("en", enTrans)
+	RegisterTranslator("ja", jaTrans)
+	t.Cleanup(func() {
+		localeMu.Lock()
+		defer localeMu.Unlock()
+		delete(translators, "en")
+		delete(translators, "ja")
+		defaultLocale = ""
+	})
+
+	type createPost struct {
+		Title string `json:"title" binding:"required"`
+	}
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		defaultLocale  string
+		wantMessage    string
+		wantLocale     any
+	}{
+		{"ja", "ja-JP,ja;q=0.9,en;q=0.8", "", "Titleは必須フィールドです", "ja"},
+		{"en", "en-US", "", "Title is a required field", "en"},
+		{"preferred by quality", "en;q=0.5, ja", "", "Titleは必須フィールドです", "ja"},
+		{"unsupported", "fr-FR", "", "Title is required", nil},
+		{"unsupported with default", "fr-FR", "ja", "Titleは必須フィールドです", "ja"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetDefaultLocale(tt.defaultLocale)
+			req := postJSON(`{}`)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			w, body := serveRequest(t, req, func(ctx *gin.Context) error {
+				var req createPost
+				return ctx.ShouldBindJSON(&req)
+			}, WithLogging(false))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			if got := violationsOf(t, body)[0].Message; got != tt.wantMessage {
+				t.Errorf("field message = %q, want %q", got, tt.wantMessage)
+			}
+			if body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Message, tt.wantMessage)
+			}
+			if got := body.Details["locale"]; got != tt.wantLocale {
+				t.Errorf("locale = %v, want %v", got, tt.wantLocale)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/A-pen-app/logging"
+)
+
+// Severity controls the log level an error is reported at.
+type Severity int
+
+const (
+	// SeverityDefault leaves the severity to be inferred from the HTTP status.
+	SeverityDefault Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityDebug:
+		return "debug"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarn:
+		return "warn"
+	case SeverityError:
+		return "error"
+	}
+	return "default"
+}
+
+// WithSeverity wraps an error with an explicit severity that overrides the status-based default.
+// The severity survives further wrapping, with the outermost severity winning.
+func WithSeverity(err error, severity Severity) error {
+	if err == nil {
+		return nil
+	}
+	return &AppError{
+		cause:    err,
+		severity: severity,
+		data:     make(map[string]any),
+	}
+}
+
+// SeverityOf returns the severity err is logged at: the outermost explicit severity in
+// its chain, else the severity of its classification, else the severity set for its code
+// with SetCodeSeverity, or else Error for 5xx statuses, Warn for 4xx statuses and Info
+// otherwise.
+func SeverityOf(err error) Severity {
+	if err == nil {
+		return SeverityDefault
+	}
+	_, mapping := resolveError(err)
+	return severityFor(err, mapping)
+}
+
+// severityFor returns the outermost explicit severity in err's chain, or else the severity
+// of its classification, or the default severity for the resolved mapping.
+func severityFor(err error, mapping ErrorMapping) Severity {
+	severity := SeverityDefault
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok {
+			severity = appErr.severity
+		}
+		return severity == SeverityDefault
+	})
+	if severity != SeverityDefault {
+		return severity
+	}
+	if c, ok := classification(err); ok && c.Severity != SeverityDefault {
+		return c.Severity
+	}
+	if severity := codeSeverity(mapping.Code); severity != SeverityDefault {
+		return severity
+	}
+	return severityForStatus(mapping.StatusCode)
+}
+
+// severityForStatus returns the default severity for an HTTP status.
+func severityForStatus(status int) Severity {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return SeverityError
+	case status >= http.StatusBadRequest:
+		return SeverityWarn
+	}
+	return SeverityInfo
+}
+
+// logError logs msg at the given severity with structured key-value fields.
+// The logging package only has structured variants for Error and Info, so Warn and Debug
+// get the fields appended to the message until it grows Warnw and Debugw.
+func logError(ctx context.Context, severity Severity, msg string, keysAndValues ...any) {
+	format := strings.ReplaceAll(msg, "%", "%%")
+	switch severity {
+	case SeverityError, SeverityDefault:
+		logging.Errorw(ctx, format, keysAndValues...)
+	case SeverityInfo:
+		logging.Infow(ctx, format, keysAndValues...)
+	case SeverityWarn:
+		logging.Warn(ctx, "%s", appendFields(msg, keysAndValues))
+	case SeverityDebug:
+		logging.Debug(ctx, "%s", appendFields(msg, keysAndValues))
+	}
+}
+
+// appendFields renders key-value fields after msg as key=value pairs.
+func appendFields(msg string, keysAndValues []any) string {
+	if len(keysAndValues) < 2 {
+		return msg
+	}
+	parts := []string{msg}
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		parts = append(parts, fmt.Sprintf("%v=%v", keysAndValues[i], keysAndValues[i+1]))
+	}
+	return strings.Join(parts, " ")
+}
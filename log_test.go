@@ -0,0 +1,51 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// logLine returns the line of logs that logged msg.
+func logLine(t *testing.T, logs, msg string) string {
+	t.Helper()
+	for _, line := range strings.Split(logs, "\n") {
+		if strings.Contains(line, msg) {
+			return line
+		}
+	}
+	t.Fatalf("logs = %q, want a line logging %q", logs, msg)
+	return ""
+}
+
+func TestLogSeverity(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      Severity
+		wantLevel string
+		wantCode  ErrorCode
+	}{
+		{"wrong params", Wrap(fmt.Errorf("parsing page: %w", ErrorWrongParams), "page", "x"), SeverityWarn, "WARN", KeyWrongParams},
+		{"unknown", fmt.Errorf("querying posts: connection reset"), SeverityError, "ERROR", KeyInternalError},
+		{"nested explicit", fmt.Errorf("listing: %w", Wrap(WithSeverity(ErrorWrongParams, SeverityError), "page", "x")), SeverityError, "ERROR", KeyWrongParams},
+		{"outermost wins", WithSeverity(Wrap(WithSeverity(ErrorNotFound, SeverityDebug), "post_id", 7), SeverityInfo), SeverityInfo, "INFO", KeyNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SeverityOf(tt.err); got != tt.want {
+				t.Errorf("SeverityOf() = %s, want %s", got, tt.want)
+			}
+			logs := captureLogs(t, func() {
+				serve(t, returning(tt.err))
+			})
+			line := logLine(t, logs, tt.err.Error())
+			if !strings.Contains(line, tt.wantLevel) {
+				t.Errorf("log line = %q, want level %s", line, tt.wantLevel)
+			}
+			if !strings.Contains(line, string(tt.wantCode)) {
+				t.Errorf("log line = %q, want code %s logged", line, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnsupportedMediaTypeError reports a request body whose content type the endpoint doesn't
+// accept. It unwraps to ErrorUnsupportedMediaType, so it maps to UNSUPPORTED_MEDIA_TYPE
+// (415) with the received and expected content types in the details.
+type UnsupportedMediaTypeError struct {
+	Received string
+	Expected []string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported media type %q, expected %s", e.Received, strings.Join(e.Expected, " or "))
+}
+
+func (e *UnsupportedMediaTypeError) Unwrap() error {
+	return ErrorUnsupportedMediaType
+}
+
+// RequireContentType returns an *UnsupportedMediaTypeError unless the request's content
+// type, ignoring parameters such as charset, is one of expected. Call it before binding,
+// since gin's binding picks a decoder from the content type rather than rejecting it.
+func RequireContentType(ctx *gin.Context, expected ...string) error {
+	received := ctx.ContentType()
+	for _, contentType := range expected {
+		if strings.EqualFold(received, contentType) {
+			return nil
+		}
+	}
+	return &UnsupportedMediaTypeError{Received: received, Expected: expected}
+}
@@ -0,0 +1,66 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireContentType(t *testing.T) {
+	handler := func(ctx *gin.Context) error {
+		if err := RequireContentType(ctx, "application/json"); err != nil {
+			return err
+		}
+		var u upload
+		if err := Bind(ctx, &u); err != nil {
+			return err
+		}
+		return ErrorConflict
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    ErrorCode
+	}{
+		{"XML", "application/xml", `<upload><name>a</name></upload>`, http.StatusUnsupportedMediaType, KeyUnsupportedMediaType},
+		{"form", "application/x-www-form-urlencoded", `name=a`, http.StatusUnsupportedMediaType, KeyUnsupportedMediaType},
+		{"JSON with charset", "application/json; charset=utf-8", `{"name":"a"}`, http.StatusConflict, KeyConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w, body := serveRequest(t, req, handler, WithLogging(false))
+			if w.Code != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Fatalf("response = %d %s, want %d %s", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			if tt.wantStatus != http.StatusUnsupportedMediaType {
+				return
+			}
+			received, _, _ := strings.Cut(tt.contentType, ";")
+			if body.Details["content_type"] != received {
+				t.Errorf("content_type = %v, want %q", body.Details["content_type"], received)
+			}
+			if got := fmt.Sprint(body.Details["expected_content_types"]); got != "[application/json]" {
+				t.Errorf("expected_content_types = %s, want [application/json]", got)
+			}
+		})
+	}
+}
+
+func TestNotMultipartIsUnsupportedMediaType(t *testing.T) {
+	handler := func(ctx *gin.Context) error {
+		_, err := FormFile(ctx, "avatar")
+		return err
+	}
+	w, body := serveRequest(t, postJSON(`{"avatar":"a"}`), handler, WithLogging(false))
+	if w.Code != http.StatusUnsupportedMediaType || body.Code != string(KeyUnsupportedMediaType) {
+		t.Errorf("response = %d %s, want 415 %s rather than a binding 400", w.Code, body.Code, KeyUnsupportedMediaType)
+	}
+}
@@ -0,0 +1,92 @@
+package errors
+
+import (
+	"net/http"
+	"strings"
+)
+
+// defaultMessages holds the client-facing message for 5xx codes, used instead of the
+// raw error string so that internal details never reach the response.
+var defaultMessages = map[ErrorCode]string{
+	KeyInternalError:       "internal system error",
+	KeyGatewayTimeout:      "gateway timeout",
+	KeyServiceUnavailable:  "service unavailable",
+	KeyDatabaseUnavailable: "service unavailable",
+}
+
+// WithPublicMessage wraps an error with a client-safe message used as the response
+// Message, while the raw error is still logged. The outermost public message wins.
+func WithPublicMessage(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &AppError{
+		cause:     err,
+		publicMsg: msg,
+		data:      make(map[string]any),
+	}
+}
+
+// MessageOf returns the message handleError would send for err: the outermost public
+// message in its chain, or else the cause's message for 4xx errors and a default
+// per-code message for 5xx errors.
+func MessageOf(err error) string {
+	if err == nil {
+		return ""
+	}
+	cause, mapping := resolveError(err)
+	return publicMessage(err, cause, mapping, nil)
+}
+
+// publicMessage picks the client-facing message for err given its resolved cause and
+// mapping, translating validation messages with lt when it is not nil.
+func publicMessage(err, cause error, mapping ErrorMapping, lt *localeTranslator) string {
+	if msg := explicitPublicMessage(err); msg != "" {
+		return msg
+	}
+	if c, ok := classification(err); ok && c.Message != "" {
+		return c.Message
+	}
+	if mapping.StatusCode < http.StatusInternalServerError {
+		if msg, ok := bindingMessage(err, lt); ok {
+			return msg
+		}
+		return redactURLs(cause, cause.Error())
+	}
+	return defaultMessage(mapping)
+}
+
+// explicitPublicMessage returns the outermost public message set in err's chain.
+func explicitPublicMessage(err error) string {
+	var msg string
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok {
+			msg = appErr.publicMsg
+			if msg == "" {
+				msg, _ = appErr.data[WrapKeyPublicMessage].(string)
+			}
+		}
+		return msg == ""
+	})
+	return msg
+}
+
+// codeMessage returns the message for a code chosen by a route mapping: that of the
+// package sentinel for mapping's code, or the default message when the code has no
+// package sentinel, such as a code made up for the route, or the status is 5xx.
+func codeMessage(mapping ErrorMapping) string {
+	if mapping.StatusCode < http.StatusInternalServerError {
+		if sentinel, ok := packageSentinelFor(mapping.Code); ok {
+			return sentinel.Error()
+		}
+	}
+	return defaultMessage(mapping)
+}
+
+// defaultMessage returns the generic message for mapping's code or status.
+func defaultMessage(mapping ErrorMapping) string {
+	if msg, exists := defaultMessages[mapping.Code]; exists {
+		return msg
+	}
+	return strings.ToLower(http.StatusText(mapping.StatusCode))
+}
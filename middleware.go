@@ -0,0 +1,59 @@
+package errors
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DetailKeyAdditionalErrors is the details key listing the messages of the other distinct
+// errors a request attached to its gin context.
+const DetailKeyAdditionalErrors = "additional_errors"
+
+// Middleware returns a gin middleware that reports the errors handlers attach with
+// ctx.Error or ctx.AbortWithError, as gin's binding shortcuts do, like returned errors.
+// After the handlers have run, the last attached error is handled if no response body
+// has been written; the messages of the other distinct errors are listed in the
+// "additional_errors" detail unless production messages are on. Errors attached with
+// gin.ErrorTypeBind are reported as 400 WRONG_PARAMETER unless they resolve to a 4xx of
+// their own. When AbortWithError already sent a status, that status is kept, with the
+// code derived from it if the error resolves to a different one. Options work as they do
+// for Handle.
+func Middleware(opts ...Option) gin.HandlerFunc {
+	configs := newConfigCache(opts)
+	return func(ctx *gin.Context) {
+		ctx.Next()
+		if len(ctx.Errors) == 0 || ctx.Writer.Size() > 0 {
+			return
+		}
+		cfg := configs.get()
+		last := ctx.Errors.Last()
+		err := last.Err
+		if last.IsType(gin.ErrorTypeBind) && StatusOf(err) >= http.StatusInternalServerError {
+			err = WithStatus(WithCode(err, KeyWrongParams), http.StatusBadRequest)
+		}
+		if ctx.Writer.Written() {
+			if status := ctx.Writer.Status(); status != StatusOf(err) {
+				err = WithStatus(WithCode(err, CodeForStatus(status)), status)
+			}
+		}
+		if others := otherErrorMessages(ctx.Errors, last); len(others) > 0 && !cfg.productionMessages {
+			err = Wrap(err, DetailKeyAdditionalErrors, others)
+		}
+		// A status sent by AbortWithError is still followed by the error body
+		respondError(ctx, err, cfg, false)
+	}
+}
+
+// otherErrorMessages returns the distinct messages of errs other than primary's.
+func otherErrorMessages(errs []*gin.Error, primary *gin.Error) []string {
+	seen := map[string]bool{primary.Error(): true}
+	var messages []string
+	for _, err := range errs {
+		if msg := err.Error(); !seen[msg] {
+			seen[msg] = true
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,116 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveMiddleware runs a GET request through a router using Middleware in front of fn.
+func serveMiddleware(fn gin.HandlerFunc, opts ...Option) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(Middleware(opts...))
+	router.GET("/test", fn)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		fn          gin.HandlerFunc
+		wantStatus  int
+		wantCode    ErrorCode
+		wantOthers  []any
+		wantMessage string
+	}{
+		{
+			name:       "last attached error",
+			fn:         func(ctx *gin.Context) { ctx.Error(errors.New("cache miss")); ctx.Error(ErrorNotFound) },
+			wantStatus: http.StatusNotFound,
+			wantCode:   KeyNotFound,
+			wantOthers: []any{"cache miss"},
+		},
+		{
+			name: "duplicate messages listed once",
+			fn: func(ctx *gin.Context) {
+				ctx.Error(errors.New("cache miss"))
+				ctx.Error(errors.New("cache miss"))
+				ctx.Error(ErrorConflict)
+			},
+			wantStatus: http.StatusConflict,
+			wantCode:   KeyConflict,
+			wantOthers: []any{"cache miss"},
+		},
+		{
+			name:       "bind error",
+			fn:         func(ctx *gin.Context) { ctx.Error(errors.New("invalid character")).SetType(gin.ErrorTypeBind) },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   KeyWrongParams,
+		},
+		{
+			name:       "bind error with a 4xx of its own",
+			fn:         func(ctx *gin.Context) { ctx.Error(ErrorNotFound).SetType(gin.ErrorTypeBind) },
+			wantStatus: http.StatusNotFound,
+			wantCode:   KeyNotFound,
+		},
+		{
+			name:       "status sent by AbortWithError",
+			fn:         func(ctx *gin.Context) { ctx.AbortWithError(http.StatusConflict, errors.New("duplicate")) },
+			wantStatus: http.StatusConflict,
+			wantCode:   KeyConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveMiddleware(tt.fn, WithLogging(false))
+			var body HttpError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+			}
+			if w.Code != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			if got := body.Details[DetailKeyAdditionalErrors]; tt.wantOthers != nil && !reflect.DeepEqual(got, tt.wantOthers) {
+				t.Errorf("%s = %v, want %v", DetailKeyAdditionalErrors, got, tt.wantOthers)
+			}
+		})
+	}
+}
+
+func TestMiddlewareAdditionalErrorsHiddenInProduction(t *testing.T) {
+	w := serveMiddleware(func(ctx *gin.Context) {
+		ctx.Error(errors.New("cache miss"))
+		ctx.Error(ErrorNotFound)
+	}, WithLogging(false), WithProductionMessages(true))
+	var body HttpError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := body.Details[DetailKeyAdditionalErrors]; exists {
+		t.Errorf("details = %v, want no %s with production messages", body.Details, DetailKeyAdditionalErrors)
+	}
+}
+
+func TestMiddlewareLeavesWrittenResponse(t *testing.T) {
+	w := serveMiddleware(func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+		ctx.Error(ErrorNotFound)
+	}, WithLogging(false))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("response = %d %q, want the handler's 200 %q", w.Code, w.Body.String(), "ok")
+	}
+}
+
+func TestMiddlewareWithoutErrors(t *testing.T) {
+	w := serveMiddleware(func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("response = %d %q, want an empty 204", w.Code, w.Body.String())
+	}
+}
@@ -1,12 +1,20 @@
 package errors
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -14,40 +22,102 @@ import (
 type ErrorType string
 
 const (
-	ErrorTypePost  ErrorType = "post"
-	ErrorTypeLogin ErrorType = "login"
+	ErrorTypePost       ErrorType = "post"
+	ErrorTypeLogin      ErrorType = "login"
+	ErrorTypeUser       ErrorType = "user"
+	ErrorTypeSystem     ErrorType = "system"
+	ErrorTypeValidation ErrorType = "validation"
+	ErrorTypeUpstream   ErrorType = "upstream"
 )
 
 type ErrorCode string
 
+func (c ErrorCode) String() string {
+	return string(c)
+}
+
 const (
-	KeyNotFound            ErrorCode = "NOT_FOUND"
-	KeyNotAllowed          ErrorCode = "ACTION_NOT_ALLOWED"
-	KeyWrongParams         ErrorCode = "WRONG_PARAMETER"
-	KeyUnauthorized        ErrorCode = "UNAUTHORIZED"
-	KeyPermissionDenied    ErrorCode = "PERMISSION_DENIED"
-	KeyUnprocessableEntity ErrorCode = "UNPROCESSABLE_ENTITY"
-	KeyInternalError       ErrorCode = "INTERNAL_ERROR"
-	KeyDuplicateEntry      ErrorCode = "DUPLICATE_ENTRY"
-	KeyInsufficientQuota   ErrorCode = "INSUFFICIENT_QUOTA"
-	KeyUserNotVerified     ErrorCode = "USER_NOT_VERIFIED"
-	KeyUnsupported         ErrorCode = "UNSUPPORTED"
-	KeyConflict            ErrorCode = "CONFLICT"
+	KeyNotFound             ErrorCode = "NOT_FOUND"
+	KeyNotAllowed           ErrorCode = "ACTION_NOT_ALLOWED"
+	KeyWrongParams          ErrorCode = "WRONG_PARAMETER"
+	KeyUnauthorized         ErrorCode = "UNAUTHORIZED"
+	KeyPermissionDenied     ErrorCode = "PERMISSION_DENIED"
+	KeyUnprocessableEntity  ErrorCode = "UNPROCESSABLE_ENTITY"
+	KeyInternalError        ErrorCode = "INTERNAL_ERROR"
+	KeyDuplicateEntry       ErrorCode = "DUPLICATE_ENTRY"
+	KeyInsufficientQuota    ErrorCode = "INSUFFICIENT_QUOTA"
+	KeyUserNotVerified      ErrorCode = "USER_NOT_VERIFIED"
+	KeyUnsupported          ErrorCode = "UNSUPPORTED"
+	KeyConflict             ErrorCode = "CONFLICT"
+	KeyGatewayTimeout       ErrorCode = "GATEWAY_TIMEOUT"
+	KeyServiceUnavailable   ErrorCode = "SERVICE_UNAVAILABLE"
+	KeyTooManyRequests      ErrorCode = "TOO_MANY_REQUESTS"
+	KeyClientClosedRequest  ErrorCode = "CLIENT_CLOSED_REQUEST"
+	KeyPayloadTooLarge      ErrorCode = "PAYLOAD_TOO_LARGE"
+	KeyUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	KeyNotImplemented       ErrorCode = "NOT_IMPLEMENTED"
+	KeyGone                 ErrorCode = "GONE"
+	KeyPreconditionFailed   ErrorCode = "PRECONDITION_FAILED"
+	KeyPreconditionRequired ErrorCode = "PRECONDITION_REQUIRED"
+	KeyMethodNotAllowed     ErrorCode = "METHOD_NOT_ALLOWED"
+	KeyBadGateway           ErrorCode = "BAD_GATEWAY"
+	KeyDatabaseUnavailable  ErrorCode = "DATABASE_UNAVAILABLE"
 )
 
 var (
-	ErrorNotFound            = errors.New("data not found")
-	ErrorNotAllowed          = errors.New("action not allowed")
-	ErrorWrongParams         = errors.New("wrong parameters")
-	ErrorUnauthorized        = errors.New("unauthorized")
-	ErrorPermissionDenied    = errors.New("permission denied")
-	ErrorUnprocessableEntity = errors.New("unprocessable entity")
-	ErrorInternalError       = errors.New("internal system error")
-	ErrorDuplicateEntry      = errors.New("duplicate entry")
-	ErrorInsufficientQuota   = errors.New("insufficient quota")
-	ErrorUserNotVerified     = errors.New("user not verified")
-	ErrorUnsupported         = errors.New("unsupported")
-	ErrorConflict            = errors.New("conflict")
+	ErrorNotFound             = errors.New("data not found")
+	ErrorNotAllowed           = errors.New("action not allowed")
+	ErrorWrongParams          = errors.New("wrong parameters")
+	ErrorUnauthorized         = errors.New("unauthorized")
+	ErrorPermissionDenied     = errors.New("permission denied")
+	ErrorUnprocessableEntity  = errors.New("unprocessable entity")
+	ErrorInternalError        = errors.New("internal system error")
+	ErrorDuplicateEntry       = errors.New("duplicate entry")
+	ErrorInsufficientQuota    = errors.New("insufficient quota")
+	ErrorUserNotVerified      = errors.New("user not verified")
+	ErrorUnsupported          = errors.New("unsupported")
+	ErrorConflict             = errors.New("conflict")
+	ErrorTooManyRequests      = errors.New("too many requests")
+	ErrorUnavailable          = errors.New("service unavailable")
+	ErrorPayloadTooLarge      = errors.New("payload too large")
+	ErrorUnsupportedMediaType = errors.New("unsupported media type")
+	ErrorNotImplemented       = errors.New("not implemented")
+	ErrorGone                 = errors.New("resource gone")
+	ErrorPreconditionFailed   = errors.New("precondition failed")
+	ErrorPreconditionRequired = errors.New("precondition required")
+	ErrorMethodNotAllowed     = errors.New("method not allowed")
+	ErrorBadGateway           = errors.New("bad gateway")
+	// ErrorGatewayTimeout, ErrorDatabaseUnavailable and ErrorClientClosedRequest are the
+	// canonical errors for codes that standard library errors such as
+	// context.DeadlineExceeded, driver.ErrBadConn and context.Canceled map to, so that
+	// FromCode returns errors owned by this package. Use CodeEquals to compare them with
+	// the errors they stand for.
+	ErrorGatewayTimeout      = errors.New("gateway timeout")
+	ErrorDatabaseUnavailable = errors.New("database unavailable")
+	ErrorClientClosedRequest = errors.New("client closed request")
+)
+
+// ErrorUnprocessable is an alias of ErrorUnprocessableEntity for requests that parse and
+// validate fine but violate a business rule, e.g. scheduling a post in the past. Binding
+// and validator failures keep mapping to WRONG_PARAMETER (400).
+var ErrorUnprocessable = ErrorUnprocessableEntity
+
+// StatusClientClosedRequest is the non-standard status, popularized by nginx, reported
+// when the client closed the connection before a response was written.
+const StatusClientClosedRequest = 499
+
+// Reserved wrap keys change the HTTP response instead of being sent as details.
+// They are stripped from the response details but kept in the logged error.
+const (
+	// WrapKeyHTTPStatus overrides the response status. The value must be an int in 100-599.
+	WrapKeyHTTPStatus = "http_status"
+	// WrapKeyPublicMessage overrides the response message. The value must be a string.
+	WrapKeyPublicMessage = "public_message"
+	// WrapKeyRetryAfter sets the Retry-After header. The value must be a time.Duration
+	// or an int number of seconds.
+	WrapKeyRetryAfter = "retry_after"
+	// WrapKeyAuthChallenge sets the WWW-Authenticate header. The value must be a string.
+	WrapKeyAuthChallenge = "www_authenticate"
 )
 
 type ErrorMapping struct {
@@ -56,82 +126,689 @@ type ErrorMapping struct {
 }
 
 var errorMappings = map[error]ErrorMapping{
-	ErrorNotFound:            {KeyNotFound, http.StatusNotFound},
-	ErrorNotAllowed:          {KeyNotAllowed, http.StatusForbidden},
-	ErrorWrongParams:         {KeyWrongParams, http.StatusBadRequest},
-	ErrorUnauthorized:        {KeyUnauthorized, http.StatusUnauthorized},
-	ErrorPermissionDenied:    {KeyPermissionDenied, http.StatusForbidden},
-	ErrorUnprocessableEntity: {KeyUnprocessableEntity, http.StatusUnprocessableEntity},
-	ErrorInternalError:       {KeyInternalError, http.StatusInternalServerError},
-	ErrorDuplicateEntry:      {KeyDuplicateEntry, http.StatusConflict},
-	ErrorInsufficientQuota:   {KeyInsufficientQuota, http.StatusPaymentRequired},
-	ErrorUserNotVerified:     {KeyUserNotVerified, http.StatusForbidden},
-	ErrorUnsupported:         {KeyUnsupported, http.StatusUnprocessableEntity},
-	ErrorConflict:            {KeyConflict, http.StatusConflict},
-	sql.ErrNoRows:            {KeyNotFound, http.StatusNotFound},
+	ErrorNotFound:             {KeyNotFound, http.StatusNotFound},
+	ErrorNotAllowed:           {KeyNotAllowed, http.StatusForbidden},
+	ErrorWrongParams:          {KeyWrongParams, http.StatusBadRequest},
+	ErrorUnauthorized:         {KeyUnauthorized, http.StatusUnauthorized},
+	ErrorPermissionDenied:     {KeyPermissionDenied, http.StatusForbidden},
+	ErrorUnprocessableEntity:  {KeyUnprocessableEntity, http.StatusUnprocessableEntity},
+	ErrorInternalError:        {KeyInternalError, http.StatusInternalServerError},
+	ErrorDuplicateEntry:       {KeyDuplicateEntry, http.StatusConflict},
+	ErrorInsufficientQuota:    {KeyInsufficientQuota, http.StatusPaymentRequired},
+	ErrorUserNotVerified:      {KeyUserNotVerified, http.StatusForbidden},
+	ErrorUnsupported:          {KeyUnsupported, http.StatusUnprocessableEntity},
+	ErrorConflict:             {KeyConflict, http.StatusConflict},
+	ErrorTooManyRequests:      {KeyTooManyRequests, http.StatusTooManyRequests},
+	ErrorUnavailable:          {KeyServiceUnavailable, http.StatusServiceUnavailable},
+	ErrorPayloadTooLarge:      {KeyPayloadTooLarge, http.StatusRequestEntityTooLarge},
+	ErrorUnsupportedMediaType: {KeyUnsupportedMediaType, http.StatusUnsupportedMediaType},
+	ErrorNotImplemented:       {KeyNotImplemented, http.StatusNotImplemented},
+	ErrorGone:                 {KeyGone, http.StatusGone},
+	ErrorPreconditionFailed:   {KeyPreconditionFailed, http.StatusPreconditionFailed},
+	ErrorPreconditionRequired: {KeyPreconditionRequired, http.StatusPreconditionRequired},
+	ErrorMethodNotAllowed:     {KeyMethodNotAllowed, http.StatusMethodNotAllowed},
+	ErrorBadGateway:           {KeyBadGateway, http.StatusBadGateway},
+	ErrorGatewayTimeout:       {KeyGatewayTimeout, http.StatusGatewayTimeout},
+	ErrorDatabaseUnavailable:  {KeyDatabaseUnavailable, http.StatusServiceUnavailable},
+	ErrorClientClosedRequest:  {KeyClientClosedRequest, StatusClientClosedRequest},
+	sql.ErrNoRows:             {KeyNotFound, http.StatusNotFound},
+	http.ErrMissingFile:       {KeyWrongParams, http.StatusBadRequest},
+	http.ErrMissingBoundary:   {KeyWrongParams, http.StatusBadRequest},
+	http.ErrNotMultipart:      {KeyUnsupportedMediaType, http.StatusUnsupportedMediaType},
+	context.Canceled:          {KeyClientClosedRequest, StatusClientClosedRequest},
+	context.DeadlineExceeded:  {KeyGatewayTimeout, http.StatusGatewayTimeout},
+	os.ErrDeadlineExceeded:    {KeyGatewayTimeout, http.StatusGatewayTimeout},
+	driver.ErrBadConn:         {KeyDatabaseUnavailable, http.StatusServiceUnavailable},
+	sql.ErrConnDone:           {KeyDatabaseUnavailable, http.StatusServiceUnavailable},
+	sql.ErrTxDone:             {KeyDatabaseUnavailable, http.StatusServiceUnavailable},
 }
 
 type AppError struct {
-	cause error
-	data  map[string]any
+	cause  error
+	msg    string
+	code   ErrorCode
+	status int
+	// invalidStatus is nil unless a WithStatus value was rejected; it is reported when
+	// the error is handled.
+	invalidStatus *int
+	errType       ErrorType
+	severity      Severity
+	// publicMsg replaces the response message when set.
+	publicMsg string
+	// retryable is nil unless the retry behavior was set explicitly.
+	retryable  *bool
+	retryAfter time.Duration
+	data       map[string]any
+	// keys holds the data keys in insertion order, so output is stable.
+	keys []string
+	// origin is the AppError this value was copied from by With/WithAll,
+	// so that errors.Is keeps matching the original instance.
+	origin *AppError
+	// stack is where New or Wrap created the error, nil when the cause already had one.
+	stack stack
 }
 
 func (e *AppError) Error() string {
+	errStr := e.cause.Error()
+	if e.msg != "" {
+		errStr = fmt.Sprintf("%s: %s", e.msg, errStr)
+	}
 	if len(e.data) == 0 {
-		return e.cause.Error()
+		return errStr
 	}
+	return fmt.Sprintf("%s: %s", errStr, e.formatData())
+}
 
+// formatData renders the error's data as space-separated key=value pairs in insertion order.
+func (e *AppError) formatData() string {
 	dataStr := make([]string, 0, len(e.data))
-	for k, v := range e.data {
-		dataStr = append(dataStr, fmt.Sprintf("%s=%v", k, v))
+	for _, k := range e.keys {
+		dataStr = append(dataStr, fmt.Sprintf("%s=%v", k, resolveValue(e.data[k])))
+	}
+	return strings.Join(dataStr, " ")
+}
+
+// Format implements fmt.Formatter. %s, %v and %q print the compact Error() string,
+// while %+v prints every layer of the cause chain on its own line with its attached data,
+// followed by the stack recorded by the innermost New or Wrap.
+func (e *AppError) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if f.Flag('+') {
+			io.WriteString(f, e.detailedString())
+			io.WriteString(f, stackOf(e).String())
+			return
+		}
+		io.WriteString(f, e.Error())
+	case 's':
+		io.WriteString(f, e.Error())
+	case 'q':
+		fmt.Fprintf(f, "%q", e.Error())
+	default:
+		fmt.Fprintf(f, "%%!%c(*errors.AppError=%s)", verb, e.Error())
+	}
+}
+
+// detailedString renders the cause chain outermost first, one layer per line.
+func (e *AppError) detailedString() string {
+	var lines []string
+	var err error = e
+	for depth := 0; err != nil && depth < maxUnwrapDepth; depth++ {
+		appErr, ok := err.(*AppError)
+		if !ok {
+			line := err.Error()
+			if formatter, ok := err.(fmt.Formatter); ok {
+				line = fmt.Sprintf("%+v", formatter)
+			}
+			lines = append(lines, line)
+			break
+		}
+
+		parts := make([]string, 0, 4)
+		if appErr.msg != "" {
+			parts = append(parts, appErr.msg)
+		}
+		if appErr.code != "" {
+			parts = append(parts, fmt.Sprintf("code=%s", appErr.code))
+		}
+		if appErr.status != 0 {
+			parts = append(parts, fmt.Sprintf("status=%d", appErr.status))
+		}
+		if appErr.errType != "" {
+			parts = append(parts, fmt.Sprintf("type=%s", appErr.errType))
+		}
+		if appErr.severity != SeverityDefault {
+			parts = append(parts, fmt.Sprintf("severity=%s", appErr.severity))
+		}
+		if appErr.retryable != nil {
+			parts = append(parts, fmt.Sprintf("retryable=%t", *appErr.retryable))
+		}
+		if appErr.retryAfter > 0 {
+			parts = append(parts, fmt.Sprintf("retry_after=%s", appErr.retryAfter))
+		}
+		if len(appErr.data) > 0 {
+			parts = append(parts, appErr.formatData())
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "(wrapped)")
+		}
+		lines = append(lines, strings.Join(parts, " "))
+		err = appErr.cause
 	}
-	return fmt.Sprintf("%s: %s", e.cause.Error(), strings.Join(dataStr, " "))
+	return strings.Join(lines, "\ncaused by: ")
 }
 
+// Data returns a shallow copy of the error's data. An AppError is immutable once
+// created, so changes to the returned map never affect the error itself.
 func (e *AppError) Data() map[string]any {
-	if e.data == nil {
-		return make(map[string]any)
+	data := make(map[string]any, len(e.data))
+	for k, v := range e.data {
+		data[k] = resolveValue(v)
+	}
+	return data
+}
+
+// Field is a single key-value pair of error context.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Fields returns the data of every AppError in the chain as ordered fields. Inner layers
+// come first, in the order their keys were given to Wrap, and outer wraps append their new
+// keys after them. For duplicate keys the position of the first occurrence is kept and the
+// outermost value wins, matching DetailsOf.
+func (e *AppError) Fields() []Field {
+	var layers []*AppError
+	walkErrors(e, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok {
+			layers = append(layers, appErr)
+		}
+		return true
+	})
+
+	details := DetailsOf(e)
+	fields := make([]Field, 0, len(details))
+	seen := make(map[string]bool, len(details))
+	for i := len(layers) - 1; i >= 0; i-- {
+		for _, k := range layers[i].keys {
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			fields = append(fields, Field{Key: k, Value: details[k]})
+		}
 	}
-	return e.data
+	return fields
 }
 
 func (e *AppError) Unwrap() error {
 	return e.cause
 }
 
+// With returns a copy of the error with key set to value in its data.
+// The receiver is never modified, so it is safe to call on shared errors.
+func (e *AppError) With(key string, value any) *AppError {
+	return e.WithAll(map[string]any{key: value})
+}
+
+// WithAll returns a copy of the error with data merged in, overriding existing keys.
+// The receiver is never modified, so it is safe to call on shared errors.
+func (e *AppError) WithAll(data map[string]any) *AppError {
+	if e == nil {
+		return nil
+	}
+	merged := make(map[string]any, len(e.data)+len(data))
+	for k, v := range e.data {
+		merged[k] = v
+	}
+	keys := append(make([]string, 0, len(e.keys)+len(data)), e.keys...)
+	for _, k := range sortedKeys(data) {
+		if _, exists := merged[k]; !exists {
+			keys = append(keys, k)
+		}
+		merged[k] = wrapLazy(data[k])
+	}
+
+	clone := *e
+	clone.data = merged
+	clone.keys = keys
+	if clone.origin == nil {
+		clone.origin = e
+	}
+	return &clone
+}
+
+// Is reports whether target is the AppError this error was copied from, or whether this
+// error carries an explicit code (via New or WithCode) that target carries too: either as
+// an explicit code of its own or as one of this package's sentinels, such as ErrorNotFound.
+// Other errors mapping to the same code, such as sql.ErrNoRows, never match, nor does
+// KeyInternalError; use CodeEquals to compare errors by their resolved codes.
+func (e *AppError) Is(target error) bool {
+	if t, ok := target.(*AppError); ok && e.origin != nil && e.origin == t {
+		return true
+	}
+	if e.code == "" || e.code == KeyInternalError {
+		return false
+	}
+	if code := explicitCode(target); code != "" {
+		return code == e.code
+	}
+	if isPackageSentinel(target) {
+		mapping, _ := lookupMapping(target)
+		return mapping.Code == e.code
+	}
+	return false
+}
+
+// explicitCode returns the outermost code set with New or WithCode in err's chain.
+func explicitCode(err error) ErrorCode {
+	var code ErrorCode
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok {
+			code = appErr.code
+		}
+		return code == ""
+	})
+	return code
+}
+
+// appErrorDocument is the stable JSON representation of an AppError.
+type appErrorDocument struct {
+	Message string         `json:"message"`
+	Code    ErrorCode      `json:"code"`
+	Status  int            `json:"status"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The document holds the cause message, the
+// resolved code and status, and the data merged from the whole chain.
+func (e *AppError) MarshalJSON() ([]byte, error) {
+	cause, mapping := resolveError(e)
+	return json.Marshal(appErrorDocument{
+		Message: cause.Error(),
+		Code:    mapping.Code,
+		Status:  mapping.StatusCode,
+		Data:    DetailsOf(e),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, reconstructing an AppError that resolves
+// to the same code and status as the one that was marshalled. Unknown fields are ignored.
+func (e *AppError) UnmarshalJSON(b []byte) error {
+	var doc appErrorDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	if !isValidStatus(doc.Status) {
+		doc.Status = 0
+	}
+	if doc.Data == nil {
+		doc.Data = make(map[string]any)
+	}
+	*e = AppError{
+		cause:  errors.New(doc.Message),
+		code:   doc.Code,
+		status: doc.Status,
+		data:   doc.Data,
+		keys:   sortedKeys(doc.Data),
+	}
+	return nil
+}
+
 type HttpError struct {
 	Code      string         `json:"code"`
+	Type      string         `json:"type,omitempty"`
 	Message   string         `json:"message"`
 	Details   map[string]any `json:"details,omitempty"`
 	RequestID string         `json:"request_id"`
 }
 
-// parseKeyValues converts logging-style key-value pairs into a map.
-func parseKeyValues(keyValues []any) map[string]any {
+// parseKeyValues converts logging-style key-value pairs into a map, along with the keys
+// in the order they were first given. Later values win for duplicate keys.
+func parseKeyValues(keyValues []any) (map[string]any, []string) {
 	if len(keyValues) == 0 {
-		return make(map[string]any)
+		return make(map[string]any), nil
 	}
 
 	data := make(map[string]any)
+	keys := make([]string, 0, len(keyValues)/2)
 	for i := 0; i < len(keyValues)-1; i += 2 {
 		if key, ok := keyValues[i].(string); ok {
-			data[key] = keyValues[i+1]
+			if _, exists := data[key]; !exists {
+				keys = append(keys, key)
+			}
+			data[key] = wrapLazy(keyValues[i+1])
 		}
 	}
-	return data
+	return data, keys
+}
+
+// sortedKeys returns the keys of data in sorted order.
+func sortedKeys(data map[string]any) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isValidStatus reports whether status is a usable HTTP status code.
+func isValidStatus(status int) bool {
+	return status >= 100 && status <= 599
+}
+
+// resolution is the outcome of resolving an error against the mapping registry.
+type resolution struct {
+	// cause is the error the mapping was resolved from.
+	cause   error
+	mapping ErrorMapping
+	// known is false when the INTERNAL_ERROR fallback was used.
+	known bool
+	// routed is true when a route mapping of the handler chose the code.
+	routed bool
+}
+
+// resolveError walks err's Unwrap chain and returns the cause of the innermost AppError
+// (or err itself when the chain holds none) together with its mapping.
+func resolveError(err error) (error, ErrorMapping) {
+	r := resolve(err, nil)
+	return r.cause, r.mapping
+}
+
+// resolve walks err's Unwrap chain and resolves the cause of the innermost AppError
+// (or err itself when the chain holds none). Codes and statuses carried by AppError
+// layers take precedence over the mapped values, with the outermost layer winning.
+// Route mappings from cfg take precedence over the registry; unmapped causes fall back
+// to the default mapping of the error's type. cfg may be nil.
+func resolve(err error, cfg *handlerConfig) resolution {
+	cause := err
+	var code ErrorCode
+	var status int
+	var errType ErrorType
+	current := err
+	for depth := 0; current != nil && depth < maxUnwrapDepth; depth++ {
+		if appErr, ok := current.(*AppError); ok {
+			if code == "" {
+				code = appErr.code
+			}
+			if status == 0 {
+				status = appErr.status
+			}
+			if status == 0 {
+				if reserved, ok := appErr.data[WrapKeyHTTPStatus].(int); ok && isValidStatus(reserved) {
+					status = reserved
+				}
+			}
+			if errType == "" {
+				errType = appErr.errType
+			}
+			cause = appErr.cause
+		}
+		current = errors.Unwrap(current)
+	}
+
+	var r resolution
+	if joined := wrappedJoin(cause); joined != nil {
+		// A classified member speaks for the whole join, see RegisterTypeClassifier.
+		if c, ok := registeredClassification(cause); ok {
+			r = resolution{cause: cause, mapping: ErrorMapping{c.Code, c.Status}, known: true}
+		} else {
+			r = resolveJoined(multiErrors(joined), cfg)
+		}
+	} else {
+		r.cause = cause
+		r.mapping, r.known = cfg.routeMappingFor(err)
+		r.routed = r.known
+		if !r.known {
+			r.mapping, r.known = findMapping(err, cause)
+		}
+		if !r.known {
+			r.mapping, r.known = lookupTypeMapping(errType)
+			if !r.known {
+				r.mapping = ErrorMapping{KeyInternalError, http.StatusInternalServerError}
+			}
+		}
+	}
+	if code != "" {
+		r.mapping.Code = code
+		r.known = true
+		r.routed = false
+	}
+	if status != 0 {
+		r.mapping.StatusCode = status
+		r.known = true
+	}
+	return r
+}
+
+// resolveJoined resolves each member of a multi-error and returns the one with the most
+// severe (highest) HTTP status. Ties go to the earliest member. resolve only gets here when
+// no registered classifier claims the join.
+func resolveJoined(errs []error, cfg *handlerConfig) resolution {
+	var chosen resolution
+	for _, member := range errs {
+		if member == nil {
+			continue
+		}
+		r := resolve(member, cfg)
+		if chosen.cause == nil || r.mapping.StatusCode > chosen.mapping.StatusCode {
+			chosen = r
+		}
+	}
+	if chosen.cause == nil {
+		return resolution{
+			cause:   errors.Join(errs...),
+			mapping: ErrorMapping{KeyInternalError, http.StatusInternalServerError},
+		}
+	}
+	return chosen
+}
+
+// multiErrors returns the members of err if it exposes Unwrap() []error.
+func multiErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return nil
+}
+
+// wrappedJoin returns err if it is a multi-error, else the multi-error that err wraps
+// through single-error Unwraps, so that fmt.Errorf("loading: %w", joined) resolves as
+// joined does. It returns nil when there is none or when a registered error comes first,
+// as the outermost registered error wins.
+func wrappedJoin(err error) error {
+	for depth := 0; err != nil && depth < maxUnwrapDepth; depth++ {
+		if len(multiErrors(err)) > 0 {
+			return err
+		}
+		if _, found := lookupMapping(err); found {
+			return nil
+		}
+		err = errors.Unwrap(err)
+	}
+	return nil
+}
+
+// walkErrors calls fn for err and every error reachable from it through Unwrap, depth-first
+// with outer errors visited before inner ones. The walk stops as soon as fn returns false.
+func walkErrors(err error, fn func(error) bool) {
+	var walk func(err error, depth int) bool
+	walk = func(err error, depth int) bool {
+		if err == nil || depth >= maxUnwrapDepth {
+			return true
+		}
+		if !fn(err) {
+			return false
+		}
+		if errs := multiErrors(err); errs != nil {
+			for _, member := range errs {
+				if !walk(member, depth+1) {
+					return false
+				}
+			}
+			return true
+		}
+		return walk(errors.Unwrap(err), depth+1)
+	}
+	walk(err, 0)
 }
 
 // getErrorMapping returns the unified error mapping for a given error.
 func getErrorMapping(err error) ErrorMapping {
+	mapping, _ := findMapping(err, err)
+	return mapping
+}
+
+// findMapping returns the mapping for cause, the unwrapped cause of err, and whether one
+// was found. When none is found the INTERNAL_ERROR fallback mapping is returned.
+func findMapping(err, cause error) (ErrorMapping, bool) {
 	// Check for binding errors first
-	if isBindingError(err) {
-		return ErrorMapping{KeyWrongParams, http.StatusBadRequest}
+	if isBindingError(cause) {
+		return ErrorMapping{KeyWrongParams, http.StatusBadRequest}, true
+	}
+	// Passing a non-struct to the validator is a bug on our side, not the client's
+	var invalidValidationErr *validator.InvalidValidationError
+	if errors.As(cause, &invalidValidationErr) {
+		return ErrorMapping{KeyInternalError, http.StatusInternalServerError}, true
 	}
 
-	if mapping, exists := errorMappings[err]; exists {
-		return mapping
+	// Walk the chain so sentinels wrapped with fmt.Errorf("%w") still resolve.
+	// The outermost registered error wins, as it is the most specific.
+	var mapping ErrorMapping
+	found := false
+	walkErrors(cause, func(err error) bool {
+		mapping, found = lookupMapping(err)
+		return !found
+	})
+	if found {
+		return mapping, true
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(cause, &maxBytesErr) || errors.Is(cause, multipart.ErrMessageTooLarge) {
+		return getErrorMapping(ErrorPayloadTooLarge), true
+	}
+	var bindingErr *BindingError
+	if errors.As(err, &bindingErr) {
+		return ErrorMapping{KeyWrongParams, http.StatusBadRequest}, true
+	}
+	if c, ok := upstreamClassification(cause); ok {
+		return ErrorMapping{c.Code, c.Status}, true
+	}
+	var unknownCodeErr *UnknownCodeError
+	if errors.As(cause, &unknownCodeErr) {
+		return ErrorMapping{unknownCodeErr.Code, http.StatusInternalServerError}, true
 	}
-	return ErrorMapping{KeyInternalError, http.StatusInternalServerError}
+	if mapping, ok := runMatchers(err, cause); ok {
+		return mapping, true
+	}
+	if c, ok := networkClassification(cause); ok {
+		return ErrorMapping{c.Code, c.Status}, true
+	}
+	if mapping, ok := statusCoderMapping(cause); ok {
+		return mapping, true
+	}
+	if mapping, ok := transientErrorMapping(cause); ok {
+		return mapping, true
+	}
+	return ErrorMapping{KeyInternalError, http.StatusInternalServerError}, false
+}
+
+// statusCodes maps HTTP statuses to the code used for errors that carry only a status.
+var statusCodes = map[int]ErrorCode{
+	http.StatusBadRequest:          KeyWrongParams,
+	http.StatusUnauthorized:        KeyUnauthorized,
+	http.StatusPaymentRequired:     KeyInsufficientQuota,
+	http.StatusForbidden:           KeyPermissionDenied,
+	http.StatusNotFound:            KeyNotFound,
+	http.StatusConflict:            KeyConflict,
+	http.StatusUnprocessableEntity: KeyUnprocessableEntity,
+	http.StatusInternalServerError: KeyInternalError,
+	http.StatusBadGateway:          KeyBadGateway,
+	http.StatusServiceUnavailable:  KeyServiceUnavailable,
+	http.StatusGatewayTimeout:      KeyGatewayTimeout,
+	StatusClientClosedRequest:      KeyClientClosedRequest,
+}
+
+// CodeForStatus returns the code used for errors that carry only an HTTP status, such as
+// the error types of other frameworks. Statuses without a dedicated code derive one from
+// the status text, e.g. 423 becomes "LOCKED".
+func CodeForStatus(status int) ErrorCode {
+	if code, exists := statusCodes[status]; exists {
+		return code
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return KeyInternalError
+	}
+	var b strings.Builder
+	for _, r := range strings.ToUpper(text) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-':
+			b.WriteRune('_')
+		}
+	}
+	return ErrorCode(b.String())
+}
+
+// statusCoderMapping maps errors anywhere in the chain that implement StatusCode() int
+// or HTTPStatus() int, as many libraries' error types do.
+func statusCoderMapping(err error) (ErrorMapping, bool) {
+	status := 0
+	walkErrors(err, func(err error) bool {
+		switch x := err.(type) {
+		case interface{ StatusCode() int }:
+			status = x.StatusCode()
+		case interface{ HTTPStatus() int }:
+			status = x.HTTPStatus()
+		}
+		if !isValidStatus(status) {
+			status = 0
+		}
+		return status == 0
+	})
+	if status == 0 {
+		return ErrorMapping{}, false
+	}
+	return ErrorMapping{CodeForStatus(status), status}, true
+}
+
+// transientErrorMapping maps errors implementing Timeout() bool to 504 and errors
+// implementing Temporary() bool to 503. Client-initiated cancellations are never matched.
+func transientErrorMapping(err error) (ErrorMapping, bool) {
+	if errors.Is(err, context.Canceled) {
+		return ErrorMapping{}, false
+	}
+	var timeoutErr interface{ Timeout() bool }
+	if errors.As(err, &timeoutErr) && timeoutErr.Timeout() {
+		return ErrorMapping{KeyGatewayTimeout, http.StatusGatewayTimeout}, true
+	}
+	var temporaryErr interface{ Temporary() bool }
+	if errors.As(err, &temporaryErr) && temporaryErr.Temporary() {
+		return ErrorMapping{KeyServiceUnavailable, http.StatusServiceUnavailable}, true
+	}
+	return ErrorMapping{}, false
+}
+
+// lookupMapping looks err up in errorMappings, skipping errors that can't be map keys,
+// such as comparable structs holding a map or slice in an interface field.
+func lookupMapping(err error) (ErrorMapping, bool) {
+	if err == nil || !reflect.ValueOf(err).Comparable() {
+		return ErrorMapping{}, false
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	mapping, exists := errorMappings[err]
+	return mapping, exists
+}
+
+// maxUnwrapDepth bounds chain walks so that self-referencing Unwrap methods can't loop forever.
+const maxUnwrapDepth = 100
+
+// RootCause follows err's Unwrap chain to the deepest error, skipping AppError wrappers.
+// For multi-errors exposing Unwrap() []error the first error is followed. It is meant for
+// logs, metrics and tests; mapping resolution doesn't need it, as it already looks up
+// every error in the chain, root cause included.
+func RootCause(err error) error {
+	for depth := 0; err != nil && depth < maxUnwrapDepth; depth++ {
+		var next error
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			next = x.Unwrap()
+		case interface{ Unwrap() []error }:
+			if errs := x.Unwrap(); len(errs) > 0 {
+				next = errs[0]
+			}
+		}
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+	return err
 }
 func isBindingError(err error) bool {
 	if err == nil {
@@ -141,10 +818,8 @@ func isBindingError(err error) bool {
 	var syntaxErr *json.SyntaxError
 	var unmarshalErr *json.UnmarshalTypeError
 	var validationErr validator.ValidationErrors
-	var invalidValidationErr *validator.InvalidValidationError
 
 	return errors.As(err, &syntaxErr) ||
 		errors.As(err, &unmarshalErr) ||
-		errors.As(err, &validationErr) ||
-		errors.As(err, &invalidValidationErr)
+		errors.As(err, &validationErr)
 }
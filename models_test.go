@@ -0,0 +1,356 @@
+package errors
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAppErrorIs(t *testing.T) {
+	errServiceNotFound := WithCode(errors.New("not found"), KeyNotFound)
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{"explicit code and package sentinel", errServiceNotFound, ErrorNotFound, true},
+		{"wrapped explicit code and package sentinel", Wrap(errServiceNotFound, "k", "v"), ErrorNotFound, true},
+		{"New and package sentinel", New(KeyConflict, http.StatusConflict, "taken"), ErrorConflict, true},
+		{"explicit code and other explicit code", errServiceNotFound, New(KeyNotFound, http.StatusNotFound, "gone"), true},
+		{"explicit code and wrapped explicit code", errServiceNotFound, Wrap(WithCode(errors.New("x"), KeyNotFound), "k", "v"), true},
+		{"explicit code and sentinel of another code", errServiceNotFound, ErrorConflict, false},
+		{"explicit code and stdlib error of the same code", errServiceNotFound, sql.ErrNoRows, false},
+		{"explicit code and unregistered error", errServiceNotFound, errors.New("not found"), false},
+		{"internal error codes", New(KeyInternalError, http.StatusInternalServerError, "a"), ErrorInternalError, false},
+		{"internal error explicit codes", WithCode(errors.New("a"), KeyInternalError), WithCode(errors.New("b"), KeyInternalError), false},
+		{"no explicit code", Wrap(errors.New("not found"), "k", "v"), ErrorNotFound, false},
+		{"wrapped sentinel", Wrap(ErrorNotFound, "k", "v"), ErrorNotFound, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, tt.target); got != tt.want {
+				t.Errorf("errors.Is() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppErrorIsCopies(t *testing.T) {
+	base := Wrap(ErrorNotFound, "id", 1).(*AppError)
+	if !errors.Is(base.With("k", "v").With("k2", "v2"), base) {
+		t.Error("copy made with With doesn't match its original")
+	}
+	if errors.Is(base, base.With("k", "v")) {
+		t.Error("original matches its copy")
+	}
+}
+
+func TestCodeEquals(t *testing.T) {
+	tests := []struct {
+		name        string
+		err, target error
+		want        bool
+	}{
+		{"stdlib and package sentinel", sql.ErrNoRows, ErrorNotFound, true},
+		{"different codes", ErrorNotFound, ErrorConflict, false},
+		{"unknown errors", errors.New("a"), errors.New("b"), false},
+		{"nil", nil, ErrorNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeEquals(tt.err, tt.target); got != tt.want {
+				t.Errorf("CodeEquals() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorDataInsertionOrder(t *testing.T) {
+	const want = "data not found: user_id=42 post_id=7 action=like retry=true source=feed"
+	for i := 0; i < 20; i++ {
+		err := Wrap(ErrorNotFound, "user_id", 42, "post_id", 7, "action", "like", "retry", true, "source", "feed")
+		if got := err.Error(); got != want {
+			t.Fatalf("Error() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestAppErrorFields(t *testing.T) {
+	inner := Wrap(ErrorNotFound, "user_id", 42, "post_id", 7, "user_id", 43)
+	middle := fmt.Errorf("loading feed: %w", Wrap(inner, "source", "feed", "post_id", 8))
+	outer := Wrap(middle, "attempt", 2).(*AppError).With("source", "timeline")
+	want := []Field{
+		{"user_id", 43},
+		{"post_id", 8},
+		{"source", "timeline"},
+		{"attempt", 2},
+	}
+	if got := outer.Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields() = %v, want %v", got, want)
+	}
+	if got := inner.(*AppError).Fields(); !reflect.DeepEqual(got, []Field{{"user_id", 43}, {"post_id", 7}}) {
+		t.Errorf("inner Fields() = %v, want the inner layer unchanged", got)
+	}
+}
+
+func TestAppErrorJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"wrapped sentinel", Wrap(ErrorNotFound, "post_id", 7)},
+		{"wrapped stdlib error", Wrap(fmt.Errorf("loading: %w", sql.ErrNoRows), "table", "posts")},
+		{"explicit code", New(KeyConflict, http.StatusConflict, "title taken")},
+		{"status override", Wrap(WithStatus(ErrorNotAllowed, http.StatusBadRequest), "k", "v")},
+		{"unknown", Wrap(errors.New("db down"), "shard", 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.err)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var restored AppError
+			if err := json.Unmarshal(b, &restored); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := Code(&restored), Code(tt.err); got != want {
+				t.Errorf("Code() = %s after round trip, want %s", got, want)
+			}
+			if got, want := StatusOf(&restored), StatusOf(tt.err); got != want {
+				t.Errorf("StatusOf() = %d after round trip, want %d", got, want)
+			}
+			if got, want := fmt.Sprint(DetailsOf(&restored)), fmt.Sprint(DetailsOf(tt.err)); got != want {
+				t.Errorf("DetailsOf() = %s after round trip, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestAppErrorUnmarshalIgnoresUnknownFields(t *testing.T) {
+	var restored AppError
+	doc := `{"message":"data not found","code":"NOT_FOUND","status":404,"attempts":3,"stack":["a"],"data":{"post_id":"7"}}`
+	if err := json.Unmarshal([]byte(doc), &restored); err != nil {
+		t.Fatalf("Unmarshal() = %v, want unknown fields ignored", err)
+	}
+	if Code(&restored) != KeyNotFound || StatusOf(&restored) != http.StatusNotFound {
+		t.Errorf("restored = %s %d, want %s 404", Code(&restored), StatusOf(&restored), KeyNotFound)
+	}
+	if got := restored.Error(); got != "data not found: post_id=7" {
+		t.Errorf("Error() = %q, want %q", got, "data not found: post_id=7")
+	}
+}
+
+func TestAppErrorFormat(t *testing.T) {
+	_, file, line, _ := runtime.Caller(0)
+	profile := Wrapf(ErrorNotFound, "loading profile for user %d", 123)
+	taken := New(KeyConflict, http.StatusConflict, "handle taken")
+	// frame renders the stack frame of this test at the given line offset from line.
+	frame := func(offset int) string {
+		return fmt.Sprintf("\ngithub.com/A-pen-app/errors.TestAppErrorFormat\n\t%s:%d", file, line+offset)
+	}
+
+	tests := []struct {
+		name, format string
+		err          error
+		want         string
+	}{
+		{"v", "%v", Wrap(WithCode(profile, KeyGone), "tenant", "acme", "quote", `a"b`), `loading profile for user 123: data not found: tenant=acme quote=a"b`},
+		{"s", "%s", Wrap(WithCode(profile, KeyGone), "tenant", "acme", "quote", `a"b`), `loading profile for user 123: data not found: tenant=acme quote=a"b`},
+		{"q", "%q", Wrap(WithCode(profile, KeyGone), "tenant", "acme", "quote", `a"b`), `"loading profile for user 123: data not found: tenant=acme quote=a\"b"`},
+		{"+v nested", "%+v", Wrap(WithCode(profile, KeyGone), "tenant", "acme", "quote", `a"b`),
+			"tenant=acme quote=a\"b\ncaused by: code=GONE\ncaused by: loading profile for user 123\ncaused by: data not found" + frame(1)},
+		{"+v through fmt.Errorf", "%+v", Wrap(fmt.Errorf("saving: %w", taken), "handle", "gopher"),
+			"handle=gopher\ncaused by: saving: handle taken" + frame(2)},
+		{"+v New", "%+v", taken, "code=CONFLICT status=409\ncaused by: handle taken" + frame(2)},
+		{"unsupported verb", "%d", taken, "%!d(*errors.AppError=handle taken)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fmt.Sprintf(tt.format, tt.err)
+			// Frames below the test function depend on the testing package
+			got, _, _ = strings.Cut(got, "\ntesting.tRunner")
+			if got != tt.want {
+				t.Errorf("Sprintf(%q) =\n%s\nwant\n%s", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+// unhashableError is comparable by type but not by value when Value holds a map.
+type unhashableError struct {
+	Value any
+}
+
+func (e unhashableError) Error() string { return fmt.Sprint("unhashable ", e.Value) }
+
+func (e unhashableError) Unwrap() error { return ErrorNotFound }
+
+func TestUnhashableErrorInChain(t *testing.T) {
+	err := Wrap(fmt.Errorf("loading: %w", unhashableError{Value: map[string]int{"a": 1}}), "k", "v")
+	if got := StatusOf(err); got != http.StatusNotFound {
+		t.Errorf("StatusOf() = %d, want %d", got, http.StatusNotFound)
+	}
+	if w, _ := serve(t, returning(err), WithLogging(false)); w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestRootCause(t *testing.T) {
+	loop := &loopError{}
+	loop.next = loop
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"nil", nil, nil},
+		{"unwrapped", sql.ErrNoRows, sql.ErrNoRows},
+		{"wrap layers", Wrap(Wrapf(fmt.Errorf("repo: %w", sql.ErrNoRows), "service"), "k", "v"), sql.ErrNoRows},
+		{"joined", Wrap(errors.Join(sql.ErrNoRows, ErrorConflict)), sql.ErrNoRows},
+		{"self-referencing", loop, loop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RootCause(tt.err); got != tt.want {
+				t.Errorf("RootCause() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinedErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   ErrorCode
+		wantErrors string
+		wantData   map[string]any
+	}{
+		{"sentinels", errors.Join(ErrorNotFound, ErrorWrongParams), http.StatusNotFound, KeyNotFound, "[data not found wrong parameters]", nil},
+		{"app errors", errors.Join(Wrap(ErrorWrongParams, "field", "title"), Wrap(ErrorNotFound, "post_id", 7)), http.StatusNotFound, KeyNotFound, "[wrong parameters: field=title data not found: post_id=7]", map[string]any{"field": "title", "post_id": float64(7)}},
+		{"unknown only", errors.Join(fmt.Errorf("cache miss"), fmt.Errorf("db down")), http.StatusInternalServerError, KeyInternalError, "[cache miss db down]", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := serve(t, returning(tt.err), WithLogging(false))
+			if w.Code != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			if got := fmt.Sprint(body.Details["errors"]); got != tt.wantErrors {
+				t.Errorf("errors = %s, want %s", got, tt.wantErrors)
+			}
+			for k, v := range tt.wantData {
+				if body.Details[k] != v {
+					t.Errorf("details[%s] = %v, want %v", k, body.Details[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestWrappedJoinResolvesAsJoin(t *testing.T) {
+	joined := errors.Join(ErrorWrongParams, ErrorConflict)
+	for _, err := range []error{joined, fmt.Errorf("saving: %w", joined), Wrap(fmt.Errorf("saving: %w", joined), "k", "v")} {
+		if got := StatusOf(err); got != http.StatusConflict {
+			t.Errorf("StatusOf(%q) = %d, want the most severe member's %d", err, got, http.StatusConflict)
+		}
+	}
+}
+
+// loopError unwraps to next, which may be itself.
+type loopError struct {
+	next error
+}
+
+func (e *loopError) Error() string { return "loop" }
+
+func (e *loopError) Unwrap() error { return e.next }
+
+// apiError is a library error type carrying its own HTTP status.
+type apiError struct{ status int }
+
+func (e apiError) Error() string   { return fmt.Sprintf("api returned %d", e.status) }
+func (e apiError) StatusCode() int { return e.status }
+
+// grpcGatewayError reports its status through HTTPStatus instead.
+type grpcGatewayError struct{ status int }
+
+func (e *grpcGatewayError) Error() string   { return "gateway error" }
+func (e *grpcGatewayError) HTTPStatus() int { return e.status }
+
+func TestStatusCoder(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   ErrorCode
+		wantStatus int
+	}{
+		{"StatusCode", apiError{http.StatusNotFound}, KeyNotFound, http.StatusNotFound},
+		{"wrapped", fmt.Errorf("fetching profile: %w", Wrap(apiError{http.StatusConflict}, "user_id", 42)), KeyConflict, http.StatusConflict},
+		{"derived code", apiError{http.StatusLocked}, "LOCKED", http.StatusLocked},
+		{"HTTPStatus", &grpcGatewayError{http.StatusGatewayTimeout}, KeyGatewayTimeout, http.StatusGatewayTimeout},
+		{"invalid status", apiError{42}, KeyInternalError, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := serve(t, returning(tt.err), WithLogging(false))
+			if w.Code != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsUnhashableError(t *testing.T) {
+	if err := Register(unhashableError{Value: []int{1}}, KeyConflict, http.StatusConflict); err == nil {
+		t.Error("Register() = nil, want an error for an error value that can't be a map key")
+	}
+}
+
+func TestDataReturnsCopy(t *testing.T) {
+	err := Wrap(ErrorNotFound, "post_id", 7).(*AppError)
+	before := err.Error()
+
+	data := err.Data()
+	data["handled"] = true
+	data["post_id"] = 8
+
+	if got := err.Error(); got != before {
+		t.Errorf("Error() = %q after mutating Data(), want %q", got, before)
+	}
+	_, body := serve(t, returning(err), WithLogging(false))
+	if _, exists := body.Details["handled"]; exists || body.Details["post_id"] != float64(7) {
+		t.Errorf("details = %v after mutating Data(), want only post_id=7", body.Details)
+	}
+}
+
+// BenchmarkData measures the defensive copy Data makes, against the cost of a whole
+// handled request in BenchmarkHandleWrapped.
+func BenchmarkData(b *testing.B) {
+	err := Wrap(ErrorNotFound, "user_id", 123, "post_id", 7, "operation", "create_post").(*AppError)
+	for i := 0; i < b.N; i++ {
+		err.Data()
+	}
+}
+
+func BenchmarkHandleWrapped(b *testing.B) {
+	router := gin.New()
+	router.GET("/test", Handle(returning(Wrap(ErrorNotFound, "user_id", 123, "post_id", 7)), WithLogging(false)))
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
@@ -0,0 +1,92 @@
+// Package mongoerr maps errors from the official MongoDB driver to semantic HTTP errors,
+// so that missing documents and duplicate keys don't surface as 500s.
+package mongoerr
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about the MongoDB driver errors, anywhere in the
+// chain:
+//
+//	mongo.ErrNoDocuments                -> 404 NOT_FOUND, with the message "data not found"
+//	duplicate key (E11000)              -> 409 CONFLICT, with the index name
+//	timeouts and server selection       -> 503 SERVICE_UNAVAILABLE, retryable
+//	network errors, client disconnected -> 503 SERVICE_UNAVAILABLE, retryable
+//
+// Context cancellations and deadlines keep their core mappings. Calling Register more than
+// once has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		registerErr = errors.RegisterTypeClassifier(classify)
+	})
+	return registerErr
+}
+
+// duplicateIndex matches the index of "E11000 duplicate key error collection: db.users
+// index: email_1 dup key: { ... }".
+var duplicateIndex = regexp.MustCompile(`index: (\S+)`)
+
+// classify maps the driver errors in a chain. It is registered for the error interface, as
+// the driver's helpers inspect the whole chain.
+func classify(err error) (errors.Classification, bool) {
+	switch {
+	case stderrors.Is(err, mongo.ErrNoDocuments):
+		return errors.Classification{
+			Code:    errors.KeyNotFound,
+			Status:  http.StatusNotFound,
+			Message: errors.ErrorNotFound.Error(),
+		}, true
+	case mongo.IsDuplicateKeyError(err):
+		return errors.Classification{
+			Code:    errors.KeyConflict,
+			Status:  http.StatusConflict,
+			Message: "resource already exists",
+			Details: indexDetails(err),
+		}, true
+	case stderrors.Is(err, context.Canceled), stderrors.Is(err, context.DeadlineExceeded):
+		return errors.Classification{}, false
+	case mongo.IsTimeout(err), mongo.IsNetworkError(err), isServerSelectionError(err),
+		stderrors.Is(err, mongo.ErrClientDisconnected):
+		return errors.Classification{
+			Code:      errors.KeyServiceUnavailable,
+			Status:    http.StatusServiceUnavailable,
+			Message:   "service unavailable",
+			Retryable: true,
+		}, true
+	}
+	return errors.Classification{}, false
+}
+
+// isServerSelectionError reports whether no suitable server could be selected.
+func isServerSelectionError(err error) bool {
+	var selectionErr topology.ServerSelectionError
+	return stderrors.As(err, &selectionErr)
+}
+
+// indexDetails returns the duplicated index parsed from the server error message. The
+// duplicated key values are never included, as they may be personal data.
+func indexDetails(err error) map[string]any {
+	var serverErr mongo.ServerError
+	if !stderrors.As(err, &serverErr) {
+		return nil
+	}
+	m := duplicateIndex.FindStringSubmatch(serverErr.Error())
+	if m == nil {
+		return nil
+	}
+	return map[string]any{"index": m[1]}
+}
@@ -0,0 +1,80 @@
+package mongoerr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/A-pen-app/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+func TestRegister(t *testing.T) {
+	duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{
+		Code:    11000,
+		Message: `E11000 duplicate key error collection: feed.users index: email_1 dup key: { email: "a@example.com" }`,
+	}}}
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      errors.ErrorCode
+		wantStatus    int
+		wantDetails   map[string]any
+		wantRetryable bool
+	}{
+		{"no documents", mongo.ErrNoDocuments, errors.KeyNotFound, http.StatusNotFound, nil, false},
+		{"duplicate key", duplicate, errors.KeyConflict, http.StatusConflict, map[string]any{"index": "email_1"}, false},
+		{"network error", mongo.CommandError{Code: 6, Labels: []string{"NetworkError"}, Message: "connection reset"}, errors.KeyServiceUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"client disconnected", mongo.ErrClientDisconnected, errors.KeyServiceUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"deadline", mongo.CommandError{Message: "timed out", Wrapped: context.DeadlineExceeded}, errors.KeyGatewayTimeout, http.StatusGatewayTimeout, nil, false},
+		{"other command error", mongo.CommandError{Code: 2, Name: "BadValue", Message: "bad value"}, errors.KeyInternalError, http.StatusInternalServerError, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("saving user: %w", tt.err))
+			if got := errors.Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := errors.StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+			if got := errors.IsRetryable(err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %t, want %t", got, tt.wantRetryable)
+			}
+			resp := errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/users", nil), err, errors.WithLogging(false))
+			if len(tt.wantDetails) > 0 && !reflect.DeepEqual(resp.Body.Details, tt.wantDetails) {
+				t.Errorf("details = %v, want %v", resp.Body.Details, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestRegisterServerSelection(t *testing.T) {
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	err = client.Ping(context.Background(), nil)
+	if got := errors.Code(err); got != errors.KeyServiceUnavailable {
+		t.Errorf("Code(%v) = %s, want %s", err, got, errors.KeyServiceUnavailable)
+	}
+	if !errors.IsRetryable(err) {
+		t.Errorf("IsRetryable(%v) = false, want true", err)
+	}
+}
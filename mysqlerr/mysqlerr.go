@@ -0,0 +1,91 @@
+// Package mysqlerr maps errors from the go-sql-driver MySQL driver to semantic HTTP
+// errors, so that duplicate entries and lock contention don't surface as 500s.
+package mysqlerr
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about *mysql.MySQLError, anywhere in the chain, by
+// error number:
+//
+//	1062 ER_DUP_ENTRY               -> 409 CONFLICT, with the key name
+//	1452 ER_NO_REFERENCED_ROW_2     -> 400 WRONG_PARAMETER, with the constraint name
+//	1213 ER_LOCK_DEADLOCK           -> 503 SERVICE_UNAVAILABLE, retryable
+//	1205 ER_LOCK_WAIT_TIMEOUT       -> 503 SERVICE_UNAVAILABLE, retryable
+//	1040 ER_CON_COUNT_ERROR         -> 503 DATABASE_UNAVAILABLE
+//	1053 ER_SERVER_SHUTDOWN         -> 503 DATABASE_UNAVAILABLE
+//
+// mysql.ErrInvalidConn maps to 503 DATABASE_UNAVAILABLE, and other error numbers are left
+// to the default resolution. Calling Register more than once has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		if registerErr = errors.RegisterTypeClassifier(classify); registerErr != nil {
+			return
+		}
+		registerErr = errors.Register(mysql.ErrInvalidConn, errors.KeyDatabaseUnavailable, http.StatusServiceUnavailable)
+	})
+	return registerErr
+}
+
+var (
+	// duplicateKey matches the key of "Duplicate entry 'x' for key 'users.email'".
+	duplicateKey = regexp.MustCompile(`for key '([^']+)'$`)
+	// foreignKey matches the constraint of "... a foreign key constraint fails (`db`.`t`,
+	// CONSTRAINT `fk` FOREIGN KEY ...)".
+	foreignKey = regexp.MustCompile("CONSTRAINT `([^`]+)`")
+)
+
+// classify maps a MySQLError by its error number.
+func classify(err *mysql.MySQLError) (errors.Classification, bool) {
+	switch err.Number {
+	case 1062:
+		return errors.Classification{
+			Code:    errors.KeyConflict,
+			Status:  http.StatusConflict,
+			Message: "resource already exists",
+			Details: messageDetails(err, duplicateKey, "key"),
+		}, true
+	case 1452:
+		return errors.Classification{
+			Code:    errors.KeyWrongParams,
+			Status:  http.StatusBadRequest,
+			Message: "referenced resource does not exist",
+			Details: messageDetails(err, foreignKey, "constraint"),
+		}, true
+	case 1213, 1205:
+		return errors.Classification{
+			Code:      errors.KeyServiceUnavailable,
+			Status:    http.StatusServiceUnavailable,
+			Message:   "concurrent update, please retry",
+			Retryable: true,
+		}, true
+	case 1040, 1053:
+		return errors.Classification{
+			Code:    errors.KeyDatabaseUnavailable,
+			Status:  http.StatusServiceUnavailable,
+			Message: "service unavailable",
+		}, true
+	}
+	return errors.Classification{}, false
+}
+
+// messageDetails returns the name captured by re from the server message under key. The
+// duplicated value itself is never included, as it may be personal data.
+func messageDetails(err *mysql.MySQLError, re *regexp.Regexp, key string) map[string]any {
+	m := re.FindStringSubmatch(err.Message)
+	if m == nil {
+		return nil
+	}
+	return map[string]any{key: m[1]}
+}
@@ -0,0 +1,65 @@
+package mysqlerr
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/A-pen-app/errors"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      errors.ErrorCode
+		wantStatus    int
+		wantDetails   map[string]any
+		wantRetryable bool
+	}{
+		{"duplicate entry", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'users.email'"}, errors.KeyConflict, http.StatusConflict, map[string]any{"key": "users.email"}, false},
+		{"foreign key", &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`feed`.`posts`, CONSTRAINT `posts_author_fk` FOREIGN KEY (`author_id`) REFERENCES `users` (`id`))"}, errors.KeyWrongParams, http.StatusBadRequest, map[string]any{"constraint": "posts_author_fk"}, false},
+		{"deadlock", &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, errors.KeyServiceUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"lock wait timeout", &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, errors.KeyServiceUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"too many connections", &mysql.MySQLError{Number: 1040, Message: "Too many connections"}, errors.KeyDatabaseUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"invalid connection", mysql.ErrInvalidConn, errors.KeyDatabaseUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"unknown number", &mysql.MySQLError{Number: 1146, Message: "Table 'feed.posts' doesn't exist"}, errors.KeyInternalError, http.StatusInternalServerError, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("creating post: %w", tt.err))
+			if got := errors.Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := errors.StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+			if got := errors.IsRetryable(err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %t, want %t", got, tt.wantRetryable)
+			}
+			resp := errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/posts", nil), err, errors.WithLogging(false))
+			if len(tt.wantDetails) > 0 && !reflect.DeepEqual(resp.Body.Details, tt.wantDetails) {
+				t.Errorf("details = %v, want %v", resp.Body.Details, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestRegisterOmitsDuplicateValue(t *testing.T) {
+	err := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'users.email'"}
+	resp := errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/users", nil), err, errors.WithLogging(false))
+	if strings.Contains(fmt.Sprint(resp.Body), "a@example.com") {
+		t.Errorf("response %+v contains the duplicated value", resp.Body)
+	}
+}
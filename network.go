@@ -0,0 +1,100 @@
+package errors
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DetailKeyHost is the details key holding the host of a failed outgoing request.
+const DetailKeyHost = "host"
+
+// networkClassification classifies transport-level failures of outgoing requests, which
+// mean a dependency is down rather than that this service is broken: timeouts are 504
+// GATEWAY_TIMEOUT, and refused connections, DNS failures and TLS errors are 502
+// BAD_GATEWAY, both retryable. Cancellations are left to the context.Canceled mapping.
+func networkClassification(err error) (Classification, bool) {
+	if errors.Is(err, context.Canceled) {
+		return Classification{}, false
+	}
+	var urlErr *url.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if !errors.As(err, &urlErr) && !errors.As(err, &opErr) && !errors.As(err, &dnsErr) {
+		return Classification{}, false
+	}
+
+	var c Classification
+	var timeoutErr interface{ Timeout() bool }
+	switch {
+	case errors.As(err, &timeoutErr) && timeoutErr.Timeout():
+		c = Classification{Code: KeyGatewayTimeout, Status: http.StatusGatewayTimeout}
+	case errors.As(err, &opErr), errors.As(err, &dnsErr), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET), isTLSError(err):
+		c = Classification{Code: KeyBadGateway, Status: http.StatusBadGateway}
+	default:
+		return Classification{}, false
+	}
+	c.Retryable = true
+	if host := failedHost(err); host != "" {
+		c.Details = map[string]any{DetailKeyHost: host}
+	}
+	return c, true
+}
+
+// isTLSError reports whether err's chain holds a TLS handshake or certificate error.
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verificationErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &recordErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &verificationErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// failedHost returns the host of the failed request, without the path and query of its
+// URL, which may carry tokens.
+func failedHost(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil && u.Host != "" {
+			return u.Host
+		}
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.Name != "" {
+		return dnsErr.Name
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Addr != nil {
+		return opErr.Addr.String()
+	}
+	return ""
+}
+
+// redactURLs returns msg, the message of err, with the URL of every *url.Error in err's
+// chain cut down to its host, as the path and query may carry tokens.
+func redactURLs(err error, msg string) string {
+	walkErrors(err, func(err error) bool {
+		if urlErr, ok := err.(*url.Error); ok && urlErr.URL != "" {
+			host := ""
+			if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+				host = u.Host
+			}
+			msg = strings.ReplaceAll(msg, strconv.Quote(urlErr.URL), strconv.Quote(host))
+			msg = strings.ReplaceAll(msg, urlErr.URL, host)
+		}
+		return true
+	})
+	return msg
+}
@@ -0,0 +1,170 @@
+package errors
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// outgoing returns err as the *url.Error an http.Client returns for a GET of rawURL.
+func outgoing(rawURL string, err error) error {
+	return &url.Error{Op: "Get", URL: rawURL, Err: err}
+}
+
+// dialErr returns a *net.OpError for a failed dial to addr.
+func dialErr(addr string, err error) error {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	return &net.OpError{Op: "dial", Net: "tcp", Addr: tcpAddr, Err: err}
+}
+
+func TestNetworkClassification(t *testing.T) {
+	const feedURL = "https://feed.internal:8443/v1/posts?token=s3cr3t"
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   ErrorCode
+		wantStatus int
+		wantHost   string
+	}{
+		{"refused", outgoing(feedURL, dialErr("10.0.0.7:8443", &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED})), KeyBadGateway, http.StatusBadGateway, "feed.internal:8443"},
+		{"reset", fmt.Errorf("fetching feed: %w", dialErr("10.0.0.7:8443", syscall.ECONNRESET)), KeyBadGateway, http.StatusBadGateway, "10.0.0.7:8443"},
+		{"DNS", outgoing(feedURL, &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "feed.internal", IsNotFound: true}}), KeyBadGateway, http.StatusBadGateway, "feed.internal:8443"},
+		{"bare DNS", &net.DNSError{Err: "no such host", Name: "feed.internal"}, KeyBadGateway, http.StatusBadGateway, "feed.internal"},
+		{"timeout", outgoing(feedURL, &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}), KeyGatewayTimeout, http.StatusGatewayTimeout, "feed.internal:8443"},
+		{"TLS", outgoing(feedURL, &tlsVerificationError{x509.UnknownAuthorityError{}}), KeyBadGateway, http.StatusBadGateway, "feed.internal:8443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Wrap(tt.err, "feed_id", 3)
+			if got := Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+			if !IsRetryable(err) {
+				t.Error("IsRetryable() = false, want true")
+			}
+			_, body := serve(t, returning(err), WithLogging(false))
+			if body.Details[DetailKeyHost] != tt.wantHost {
+				t.Errorf("host = %v, want %q", body.Details[DetailKeyHost], tt.wantHost)
+			}
+		})
+	}
+}
+
+// tlsVerificationError is an x509.UnknownAuthorityError behind another error, as
+// crypto/tls reports it.
+type tlsVerificationError struct {
+	err x509.UnknownAuthorityError
+}
+
+func (e *tlsVerificationError) Error() string {
+	return "tls: failed to verify certificate: " + e.err.Error()
+}
+
+func (e *tlsVerificationError) Unwrap() error { return e.err }
+
+// fakeNetError is a synthetic net.Error.
+type fakeNetError struct {
+	timeout, temporary bool
+	cause              error
+}
+
+func (e fakeNetError) Error() string   { return "fake network error" }
+func (e fakeNetError) Timeout() bool   { return e.timeout }
+func (e fakeNetError) Temporary() bool { return e.temporary }
+func (e fakeNetError) Unwrap() error   { return e.cause }
+
+var _ net.Error = fakeNetError{}
+
+func TestTransientErrorMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   ErrorCode
+		wantStatus int
+	}{
+		{"timeout", fakeNetError{timeout: true}, KeyGatewayTimeout, http.StatusGatewayTimeout},
+		{"temporary", fakeNetError{temporary: true}, KeyServiceUnavailable, http.StatusServiceUnavailable},
+		{"timeout wins", fakeNetError{timeout: true, temporary: true}, KeyGatewayTimeout, http.StatusGatewayTimeout},
+		{"neither", fakeNetError{}, KeyInternalError, http.StatusInternalServerError},
+		{"client cancellation", fakeNetError{timeout: true, cause: context.Canceled}, CodeForStatus(StatusClientClosedRequest), StatusClientClosedRequest},
+		{"wrapped", Wrap(fmt.Errorf("calling feed: %w", fakeNetError{timeout: true}), "k", "v"), KeyGatewayTimeout, http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Code(tt.err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := StatusOf(tt.err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestClientTimeoutMapsToGatewayTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+	client := &http.Client{Timeout: 10 * time.Millisecond}
+	_, err := client.Get(upstream.URL)
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		t.Fatalf("Get() = %v, want a *url.Error", err)
+	}
+	w, body := serve(t, returning(err), WithLogging(false))
+	if w.Code != http.StatusGatewayTimeout || body.Code != string(KeyGatewayTimeout) {
+		t.Errorf("response = %d %s, want 504 %s", w.Code, body.Code, KeyGatewayTimeout)
+	}
+}
+
+func TestNetworkClassificationLeavesCancellations(t *testing.T) {
+	err := outgoing("https://feed.internal/v1/posts?token=s3cr3t", context.Canceled)
+	if got := StatusOf(err); got != StatusClientClosedRequest {
+		t.Errorf("StatusOf() = %d, want %d", got, StatusClientClosedRequest)
+	}
+}
+
+func TestOutgoingURLRedacted(t *testing.T) {
+	const secret = "s3cr3t"
+	rawURL := "https://feed.internal/v1/users/42/posts?token=" + secret
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"canceled", Wrap(outgoing(rawURL, context.Canceled), "k", "v")},
+		{"client error", WithStatus(outgoing(rawURL, fmt.Errorf("unexpected status 404")), http.StatusNotFound)},
+		{"joined", fmt.Errorf("syncing: %w", errors.Join(outgoing(rawURL, context.Canceled), ErrorWrongParams))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := MessageOf(tt.err)
+			if strings.Contains(msg, secret) || strings.Contains(msg, "/v1/users") {
+				t.Errorf("MessageOf() = %q, want the URL cut down to its host", msg)
+			}
+			resp := ResponseFor(httptest.NewRequest(http.MethodGet, "/sync", nil), tt.err, WithLogging(false))
+			if resp.Body == nil {
+				return
+			}
+			if s := fmt.Sprint(resp.Body.Message, resp.Body.Details); strings.Contains(s, secret) {
+				t.Errorf("response = %q, want the URL cut down to its host", s)
+			}
+		})
+	}
+	if got, want := MessageOf(WithStatus(outgoing(rawURL, fmt.Errorf("unexpected status 404")), http.StatusNotFound)), `Get "feed.internal": unexpected status 404`; got != want {
+		t.Errorf("MessageOf() = %q, want %q", got, want)
+	}
+}
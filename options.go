@@ -0,0 +1,351 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/A-pen-app/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Option customizes how errors are reported. Options can be set globally with Configure
+// and per handler with Handle; handler options are applied on top of the global ones.
+type Option func(*handlerConfig)
+
+// handlerConfig holds the settings built from Options. The zero value behaves like a
+// handler with no options at all.
+type handlerConfig struct {
+	routeMappings      []routeMapping
+	suppressLogging    bool
+	productionMessages bool
+	requestIDFunc      func(*gin.Context) string
+	encoder            Encoder
+	legacyCodesFunc    func(*gin.Context) bool
+	legacyCodesEnabled bool
+	logFields          []any
+	successStatus      int
+	noContentForNil    bool
+	streamErrorWriter  StreamErrorWriter
+	problemDetails     bool
+	problemTypeURI     string
+}
+
+// Encoder writes an error response. The default encoder aborts the gin context with
+// the body rendered as JSON.
+type Encoder func(ctx *gin.Context, status int, body HttpError)
+
+// globalOptions holds the options set by Configure, guarded by configMu.
+var globalOptions []Option
+
+// configVersion is incremented by every call to Configure, so that cached configs can
+// tell when globalOptions has changed. It is only written with configMu held.
+var configVersion atomic.Uint64
+
+// Configure sets the global options applied to every handler, replacing any options
+// set by a previous call. Calling it with no options restores the default behavior.
+func Configure(opts ...Option) {
+	newHandlerConfig(opts) // validate before storing
+	configMu.Lock()
+	defer configMu.Unlock()
+	globalOptions = append([]Option(nil), opts...)
+	configVersion.Add(1)
+}
+
+// WithLogging enables or disables logging of handled errors. Logging is on by default.
+func WithLogging(enabled bool) Option {
+	return func(c *handlerConfig) {
+		c.suppressLogging = !enabled
+	}
+}
+
+// WithProductionMessages enables production-safe messages: unless a public message is
+// set, responses carry the message of the registered sentinel (or a generic message)
+// instead of the full error string, and joined error messages are not sent as details.
+func WithProductionMessages(enabled bool) Option {
+	return func(c *handlerConfig) {
+		c.productionMessages = enabled
+	}
+}
+
+// WithRequestIDFunc sets how the request ID in responses is extracted. By default it is
+// the trace ID of the OpenTelemetry span in the request context.
+func WithRequestIDFunc(fn func(*gin.Context) string) Option {
+	return func(c *handlerConfig) {
+		if fn == nil {
+			panic("errors: WithRequestIDFunc called with nil func")
+		}
+		c.requestIDFunc = fn
+	}
+}
+
+// WithEncoder sets how error responses are written.
+func WithEncoder(encoder Encoder) Option {
+	return func(c *handlerConfig) {
+		if encoder == nil {
+			panic("errors: WithEncoder called with nil encoder")
+		}
+		c.encoder = encoder
+	}
+}
+
+// WithLogFields adds structured key-value fields to the log entries of handled errors,
+// e.g. the name of the operation that failed. Fields from several calls accumulate.
+func WithLogFields(keysAndValues ...any) Option {
+	return func(c *handlerConfig) {
+		c.logFields = append(c.logFields, keysAndValues...)
+	}
+}
+
+// WithSuccessStatus sets the status HandleJSON responds with on success, e.g. 201 for
+// handlers creating a resource; the default is 200. A 204 is sent without a body. Handle
+// panics if status is not a 2xx status.
+func WithSuccessStatus(status int) Option {
+	return func(c *handlerConfig) {
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			panic(fmt.Sprintf("errors: WithSuccessStatus called with non-2xx status %d", status))
+		}
+		c.successStatus = status
+	}
+}
+
+// WithNoContentForNil makes HandleJSON respond with 204 and no body when the handler
+// returns a nil pointer, slice, map or interface value.
+func WithNoContentForNil(enabled bool) Option {
+	return func(c *handlerConfig) {
+		c.noContentForNil = enabled
+	}
+}
+
+// StreamErrorWriter reports an error to the client of a response that had already started
+// when the error was returned, e.g. as a final event of a server-sent event stream.
+type StreamErrorWriter func(ctx *gin.Context, body HttpError)
+
+// WithStreamErrorWriter sets how errors are reported in responses that had already
+// started, such as event streams. By default they are only logged, as writing an error
+// response would corrupt the stream.
+func WithStreamErrorWriter(writer StreamErrorWriter) Option {
+	return func(c *handlerConfig) {
+		if writer == nil {
+			panic("errors: WithStreamErrorWriter called with nil writer")
+		}
+		c.streamErrorWriter = writer
+	}
+}
+
+// WithLegacyCodes makes responses use deprecated codes instead of their aliases
+// registered with RegisterAlias, for clients that still depend on the old codes.
+func WithLegacyCodes(enabled bool) Option {
+	return func(c *handlerConfig) {
+		c.legacyCodesEnabled = enabled
+		c.legacyCodesFunc = nil
+	}
+}
+
+// WithLegacyCodesFunc is like WithLegacyCodes but decides per request, e.g. from an
+// API version header.
+func WithLegacyCodesFunc(fn func(*gin.Context) bool) Option {
+	return func(c *handlerConfig) {
+		if fn == nil {
+			panic("errors: WithLegacyCodesFunc called with nil func")
+		}
+		c.legacyCodesFunc = fn
+		c.legacyCodesEnabled = false
+	}
+}
+
+// legacyCodes reports whether deprecated codes should be sent for ctx.
+func (c *handlerConfig) legacyCodes(ctx *gin.Context) bool {
+	if c.legacyCodesFunc != nil {
+		return c.legacyCodesFunc(ctx)
+	}
+	return c.legacyCodesEnabled
+}
+
+// responseCode applies code aliases to code, logging a deprecation event when a
+// deprecated code is served in legacy mode.
+func (c *handlerConfig) responseCode(ctx context.Context, code ErrorCode, legacy bool, route string) ErrorCode {
+	replacement := currentCode(code)
+	if replacement == code {
+		return code
+	}
+	if !legacy {
+		return replacement
+	}
+	logging.Infow(ctx, "errors: deprecated code served",
+		"code", string(code), "replacement", string(replacement), "path", route)
+	return code
+}
+
+// effectiveConfig returns the global options with opts applied on top. Handlers built once
+// and called per request should use a configCache instead.
+func effectiveConfig(opts []Option) *handlerConfig {
+	if len(opts) == 0 {
+		return defaultConfig.get()
+	}
+	configMu.RLock()
+	global := globalOptions
+	configMu.RUnlock()
+	return buildConfig(global, opts)
+}
+
+// buildConfig applies the global options and then opts.
+func buildConfig(global, opts []Option) *handlerConfig {
+	c := &handlerConfig{}
+	for _, opt := range global {
+		opt(c)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// configCache holds the config of one handler, built from the global options and the
+// handler's own, and rebuilds it only after Configure has changed the global options.
+// The cached config is shared between requests and must not be modified.
+type configCache struct {
+	opts   []Option
+	cached atomic.Pointer[versionedConfig]
+}
+
+// versionedConfig is a config built from the global options at version.
+type versionedConfig struct {
+	version uint64
+	cfg     *handlerConfig
+}
+
+// defaultConfig is the config of handlers without options of their own.
+var defaultConfig = &configCache{}
+
+// newConfigCache validates opts and returns a cache for the config they build.
+func newConfigCache(opts []Option) *configCache {
+	newHandlerConfig(opts)
+	return &configCache{opts: append([]Option(nil), opts...)}
+}
+
+// get returns the handler's config for the current global options.
+func (c *configCache) get() *handlerConfig {
+	if v := c.cached.Load(); v != nil && v.version == configVersion.Load() {
+		return v.cfg
+	}
+	configMu.RLock()
+	version, global := configVersion.Load(), globalOptions
+	configMu.RUnlock()
+	cfg := buildConfig(global, c.opts)
+	c.cached.Store(&versionedConfig{version, cfg})
+	return cfg
+}
+
+// requestID returns the request ID for ctx using the configured extractor.
+func (c *handlerConfig) requestID(ctx *gin.Context) string {
+	if c.requestIDFunc != nil {
+		return c.requestIDFunc(ctx)
+	}
+	return requestIDFromContext(ctx.Request.Context())
+}
+
+// encode writes the error response using the configured encoder.
+func (c *handlerConfig) encode(ctx *gin.Context, status int, body HttpError) {
+	if c.encoder != nil {
+		c.encoder(ctx, status, body)
+		return
+	}
+	format := negotiateFormat(ctx.GetHeader("Accept"))
+	if format == formatJSON && !c.problemDetails {
+		ctx.AbortWithStatusJSON(status, body)
+		return
+	}
+	contentType, data, err := c.encodeBody(format, status, body, ctx.Request.URL.Path)
+	if err != nil {
+		logging.Error(ctx.Request.Context(), "errors: encoding response: %v", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.Abort()
+	ctx.Data(status, contentType, data)
+}
+
+// message returns the response message for err resolved as r, applying production-safe
+// messages. When a route mapping chose the code, the message is that of the code rather
+// than of the original error, so that e.g. a PERMISSION_DENIED error sent as NOT_FOUND
+// doesn't give itself away.
+func (c *handlerConfig) message(err error, r resolution, lt *localeTranslator) string {
+	if r.routed {
+		if msg := explicitPublicMessage(err); msg != "" {
+			return msg
+		}
+		return codeMessage(r.mapping)
+	}
+	if !c.productionMessages {
+		return publicMessage(err, r.cause, r.mapping, lt)
+	}
+	if msg := explicitPublicMessage(err); msg != "" {
+		return msg
+	}
+	if c, ok := classification(err); ok && c.Message != "" {
+		return c.Message
+	}
+	if r.mapping.StatusCode < http.StatusInternalServerError {
+		if msg, ok := bindingMessage(err, lt); ok {
+			return msg
+		}
+		var sentinel error
+		walkErrors(r.cause, func(err error) bool {
+			if _, found := lookupMapping(err); found {
+				sentinel = err
+			}
+			return sentinel == nil
+		})
+		if sentinel != nil {
+			return sentinel.Error()
+		}
+	}
+	return defaultMessage(r.mapping)
+}
+
+// routeMapping overrides the registry mapping for one sentinel within a single handler.
+type routeMapping struct {
+	err     error
+	mapping ErrorMapping
+}
+
+// WithRouteMapping makes the handler respond with code and status for errors matching
+// err (via errors.Is), taking precedence over the global registry for that handler only.
+// Per-instance overrides such as WithCode and WithStatus still win. Handle panics if err
+// is nil or status is outside 100-599.
+func WithRouteMapping(err error, code ErrorCode, status int) Option {
+	return func(c *handlerConfig) {
+		if err == nil {
+			panic("errors: WithRouteMapping called with nil error")
+		}
+		if !isValidStatus(status) {
+			panic(fmt.Sprintf("errors: WithRouteMapping called with invalid status %d for %q", status, err))
+		}
+		c.routeMappings = append(c.routeMappings, routeMapping{err, ErrorMapping{code, status}})
+	}
+}
+
+// newHandlerConfig applies opts in order.
+func newHandlerConfig(opts []Option) *handlerConfig {
+	c := &handlerConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// routeMappingFor returns the first route mapping whose error matches err.
+func (c *handlerConfig) routeMappingFor(err error) (ErrorMapping, bool) {
+	if c == nil {
+		return ErrorMapping{}, false
+	}
+	for _, rm := range c.routeMappings {
+		if errors.Is(err, rm.err) {
+			return rm.mapping, true
+		}
+	}
+	return ErrorMapping{}, false
+}
@@ -0,0 +1,184 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouteMapping(t *testing.T) {
+	denied := returning(fmt.Errorf("reading post 42: %w", ErrorPermissionDenied))
+	router := gin.New()
+	router.GET("/posts/:id", Handle(denied,
+		WithRouteMapping(ErrorPermissionDenied, KeyNotFound, http.StatusNotFound),
+	))
+	router.GET("/legacy", Handle(denied,
+		WithRouteMapping(ErrorNotFound, KeyNotFound, http.StatusOK),
+		WithRouteMapping(ErrorPermissionDenied, "TEST_ROUTE_HIDDEN", http.StatusOK),
+	))
+	router.GET("/production", Handle(denied,
+		WithProductionMessages(true),
+		WithRouteMapping(ErrorPermissionDenied, KeyNotFound, http.StatusNotFound),
+	))
+	router.GET("/hidden", Handle(denied,
+		WithRouteMapping(ErrorPermissionDenied, "TEST_ROUTE_HIDDEN", http.StatusNotFound),
+	))
+	router.GET("/public", Handle(returning(WithPublicMessage(ErrorPermissionDenied, "nothing here")),
+		WithRouteMapping(ErrorPermissionDenied, KeyNotFound, http.StatusNotFound),
+	))
+	router.GET("/default", Handle(denied))
+
+	tests := []struct {
+		path    string
+		status  int
+		code    ErrorCode
+		message string
+	}{
+		{"/posts/42", http.StatusNotFound, KeyNotFound, ErrorNotFound.Error()},
+		{"/legacy", http.StatusOK, "TEST_ROUTE_HIDDEN", "ok"},
+		{"/production", http.StatusNotFound, KeyNotFound, ErrorNotFound.Error()},
+		{"/hidden", http.StatusNotFound, "TEST_ROUTE_HIDDEN", "not found"},
+		{"/public", http.StatusNotFound, KeyNotFound, "nothing here"},
+		{"/default", http.StatusForbidden, KeyPermissionDenied, "reading post 42: permission denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			var body HttpError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding %q: %v", w.Body.String(), err)
+			}
+			if w.Code != tt.status || ErrorCode(body.Code) != tt.code {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, tt.status, tt.code)
+			}
+			if body.Message != tt.message {
+				t.Errorf("message = %q, want %q", body.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestRouteMappingInstanceOverrideWins(t *testing.T) {
+	err := WithCode(ErrorPermissionDenied, "TEST_ROUTE_INSTANCE")
+	w, body := serve(t, returning(err), WithRouteMapping(ErrorPermissionDenied, KeyNotFound, http.StatusNotFound))
+	if w.Code != http.StatusNotFound || body.Code != "TEST_ROUTE_INSTANCE" {
+		t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, http.StatusNotFound, "TEST_ROUTE_INSTANCE")
+	}
+}
+
+func TestRouteMappingValidatedAtRegistration(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"nil error", WithRouteMapping(nil, KeyNotFound, http.StatusNotFound)},
+		{"invalid status", WithRouteMapping(ErrorNotFound, KeyNotFound, 600)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Handle() did not panic")
+				}
+			}()
+			Handle(returning(nil), tt.opt)
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	t.Cleanup(func() { Configure() })
+	requestID := func(id string) Option {
+		return WithRequestIDFunc(func(*gin.Context) string { return id })
+	}
+	handler := Handle(returning(ErrorNotFound))
+	overridden := Handle(returning(ErrorNotFound), requestID("handler"))
+	call := func(h gin.HandlerFunc) HttpError {
+		router := gin.New()
+		router.GET("/test", h)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+		var body HttpError
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decoding %q: %v", w.Body.String(), err)
+		}
+		return body
+	}
+
+	if got := call(handler).RequestID; got != "" {
+		t.Errorf("request_id = %q with the zero config, want empty", got)
+	}
+	Configure(requestID("global"))
+	if got := call(handler).RequestID; got != "global" {
+		t.Errorf("request_id = %q after Configure, want %q", got, "global")
+	}
+	if got := call(overridden).RequestID; got != "handler" {
+		t.Errorf("request_id = %q, want the handler option to win over %q", got, "global")
+	}
+	Configure(requestID("reconfigured"))
+	if got := call(handler).RequestID; got != "reconfigured" {
+		t.Errorf("request_id = %q after a second Configure, want %q", got, "reconfigured")
+	}
+	Configure()
+	if got := call(handler).RequestID; got != "" {
+		t.Errorf("request_id = %q after resetting, want empty", got)
+	}
+}
+
+func TestConfigureEncoder(t *testing.T) {
+	t.Cleanup(func() { Configure() })
+	Configure(WithEncoder(func(ctx *gin.Context, status int, body HttpError) {
+		ctx.AbortWithStatusJSON(status, gin.H{"error": body.Code})
+	}))
+	w, _ := serve(t, returning(ErrorNotFound), WithLogging(false))
+	if got, want := w.Body.String(), `{"error":"NOT_FOUND"}`; got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
+
+func TestConfigCacheReused(t *testing.T) {
+	t.Cleanup(func() { Configure() })
+	configs := newConfigCache([]Option{WithLogging(false)})
+	first := configs.get()
+	if configs.get() != first {
+		t.Error("get() rebuilt the config without a call to Configure")
+	}
+	Configure(WithProductionMessages(true))
+	cfg := configs.get()
+	if cfg == first {
+		t.Fatal("get() returned a stale config after Configure")
+	}
+	if !cfg.productionMessages || !cfg.suppressLogging {
+		t.Errorf("config = %+v, want global and handler options applied", cfg)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { configs.get() }); allocs != 0 {
+		t.Errorf("get() allocates %v times per call, want 0", allocs)
+	}
+}
+
+func TestHandlerOptions(t *testing.T) {
+	err := fmt.Errorf("loading users table: %w", ErrorNotFound)
+	if _, body := serve(t, returning(err)); body.Message != err.Error() {
+		t.Errorf("message = %q with the zero config, want %q", body.Message, err.Error())
+	}
+	if _, body := serve(t, returning(err), WithProductionMessages(true)); body.Message != ErrorNotFound.Error() {
+		t.Errorf("message = %q with production messages, want %q", body.Message, ErrorNotFound.Error())
+	}
+	joined := errors.Join(ErrorNotFound, ErrorConflict)
+	if _, body := serve(t, returning(joined), WithProductionMessages(true)); body.Details["errors"] != nil {
+		t.Errorf("details = %v with production messages, want no joined messages", body.Details)
+	}
+	logs := captureLogs(t, func() {
+		serve(t, returning(err), WithLogging(false))
+	})
+	if strings.Contains(logs, "loading users table") {
+		t.Errorf("logged %q with logging disabled", logs)
+	}
+}
@@ -0,0 +1,129 @@
+// Package pgxerr maps PostgreSQL errors from pgx to semantic HTTP errors, so that
+// constraint violations don't surface as 500s.
+//
+// Like every integration in this repository, it lives in its own module, so that services
+// not using pgx don't pull it in through the core package, and it is opt-in: nothing is
+// mapped until Register is called.
+package pgxerr
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about *pgconn.PgError and *pgconn.ConnectError,
+// anywhere in the chain, by SQLSTATE:
+//
+//	23505 unique_violation          -> 409 CONFLICT, with the constraint name
+//	23503 foreign_key_violation     -> 400 WRONG_PARAMETER, with the constraint name,
+//	                                   or 404 NOT_FOUND through ReferenceNotFound
+//	23502 not_null_violation        -> 400 WRONG_PARAMETER, with the column name
+//	23514 check_violation           -> 400 WRONG_PARAMETER, with the constraint name
+//	40001 serialization_failure     -> 409 CONFLICT, retryable
+//	40P01 deadlock_detected         -> 409 CONFLICT, retryable
+//	class 08, 53300, 57P01-57P03    -> 503 DATABASE_UNAVAILABLE
+//
+// Connection failures map to 503 DATABASE_UNAVAILABLE, and other SQLSTATEs are left to
+// the default resolution. Calling Register more than once has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		if registerErr = errors.RegisterTypeClassifier(classify); registerErr != nil {
+			return
+		}
+		registerErr = errors.RegisterTypeClassifier(func(*pgconn.ConnectError) (errors.Classification, bool) {
+			return unavailable(), true
+		})
+	})
+	return registerErr
+}
+
+// ReferenceNotFound reports a foreign_key_violation in err as 404 NOT_FOUND instead of
+// 400 WRONG_PARAMETER. The SQLSTATE alone can't tell a bad reference in the request body
+// from a missing parent the request addresses, so callers say so for writes such as
+// POST /posts/{id}/comments, where the violated reference is the post in the path. Other
+// errors are returned unchanged.
+func ReferenceNotFound(err error) error {
+	var pgErr *pgconn.PgError
+	if !stderrors.As(err, &pgErr) || pgErr.Code != "23503" {
+		return err
+	}
+	return errors.WithStatus(errors.WithCode(err, errors.KeyNotFound), http.StatusNotFound)
+}
+
+// classify maps a PgError by its SQLSTATE.
+func classify(err *pgconn.PgError) (errors.Classification, bool) {
+	switch err.Code {
+	case "23505":
+		return errors.Classification{
+			Code:    errors.KeyConflict,
+			Status:  http.StatusConflict,
+			Message: "resource already exists",
+			Details: constraintDetails(err),
+		}, true
+	case "23503":
+		return errors.Classification{
+			Code:    errors.KeyWrongParams,
+			Status:  http.StatusBadRequest,
+			Message: "referenced resource does not exist",
+			Details: constraintDetails(err),
+		}, true
+	case "23502":
+		details := map[string]any{}
+		if err.ColumnName != "" {
+			details["column"] = err.ColumnName
+		}
+		return errors.Classification{
+			Code:    errors.KeyWrongParams,
+			Status:  http.StatusBadRequest,
+			Message: "missing required value",
+			Details: details,
+		}, true
+	case "23514":
+		return errors.Classification{
+			Code:    errors.KeyWrongParams,
+			Status:  http.StatusBadRequest,
+			Message: "value out of allowed range",
+			Details: constraintDetails(err),
+		}, true
+	case "40001", "40P01":
+		return errors.Classification{
+			Code:      errors.KeyConflict,
+			Status:    http.StatusConflict,
+			Message:   "concurrent update, please retry",
+			Retryable: true,
+		}, true
+	case "53300", "57P01", "57P02", "57P03":
+		return unavailable(), true
+	}
+	if strings.HasPrefix(err.Code, "08") {
+		return unavailable(), true
+	}
+	return errors.Classification{}, false
+}
+
+// unavailable classifies database connectivity failures.
+func unavailable() errors.Classification {
+	return errors.Classification{
+		Code:    errors.KeyDatabaseUnavailable,
+		Status:  http.StatusServiceUnavailable,
+		Message: "service unavailable",
+	}
+}
+
+// constraintDetails returns the violated constraint, if the server reported it.
+func constraintDetails(err *pgconn.PgError) map[string]any {
+	if err.ConstraintName == "" {
+		return nil
+	}
+	return map[string]any{"constraint": err.ConstraintName}
+}
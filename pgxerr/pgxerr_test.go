@@ -0,0 +1,166 @@
+package pgxerr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/A-pen-app/errors"
+	"github.com/A-pen-app/logging"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           *pgconn.PgError
+		wantCode      errors.ErrorCode
+		wantStatus    int
+		wantDetails   map[string]any
+		wantRetryable bool
+	}{
+		{"unique violation", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}, errors.KeyConflict, http.StatusConflict, map[string]any{"constraint": "users_email_key"}, false},
+		{"foreign key violation", &pgconn.PgError{Code: "23503", ConstraintName: "posts_author_id_fkey"}, errors.KeyWrongParams, http.StatusBadRequest, map[string]any{"constraint": "posts_author_id_fkey"}, false},
+		{"not null violation", &pgconn.PgError{Code: "23502", ColumnName: "title"}, errors.KeyWrongParams, http.StatusBadRequest, map[string]any{"column": "title"}, false},
+		{"check violation", &pgconn.PgError{Code: "23514", ConstraintName: "rating_range"}, errors.KeyWrongParams, http.StatusBadRequest, map[string]any{"constraint": "rating_range"}, false},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, errors.KeyConflict, http.StatusConflict, nil, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, errors.KeyConflict, http.StatusConflict, nil, true},
+		{"connection exception", &pgconn.PgError{Code: "08006"}, errors.KeyDatabaseUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"too many connections", &pgconn.PgError{Code: "53300"}, errors.KeyDatabaseUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"admin shutdown", &pgconn.PgError{Code: "57P01"}, errors.KeyDatabaseUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"unknown SQLSTATE", &pgconn.PgError{Code: "22012"}, errors.KeyInternalError, http.StatusInternalServerError, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("creating post: %w", tt.err))
+			if got := errors.Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := errors.StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+			if got := errors.IsRetryable(err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %t, want %t", got, tt.wantRetryable)
+			}
+			resp := errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/posts", nil), err, errors.WithLogging(false))
+			if len(tt.wantDetails) > 0 && !reflect.DeepEqual(resp.Body.Details, tt.wantDetails) {
+				t.Errorf("details = %v, want %v", resp.Body.Details, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestRegisterConnectError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := pgconn.Connect(ctx, "postgres://app@127.0.0.1:1/feed?connect_timeout=1")
+	if err == nil {
+		t.Fatal("Connect() = nil, want a connection error")
+	}
+	if got := errors.Code(err); got != errors.KeyDatabaseUnavailable {
+		t.Errorf("Code() = %s, want %s", got, errors.KeyDatabaseUnavailable)
+	}
+	if got := errors.MessageOf(err); got != "service unavailable" {
+		t.Errorf("MessageOf() = %q, want %q", got, "service unavailable")
+	}
+}
+
+func TestReferenceNotFound(t *testing.T) {
+	violation := &pgconn.PgError{Code: "23503", ConstraintName: "comments_post_id_fkey"}
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   errors.ErrorCode
+		wantStatus int
+	}{
+		{"foreign key violation", fmt.Errorf("inserting comment: %w", violation), errors.KeyNotFound, http.StatusNotFound},
+		{"other SQLSTATE", &pgconn.PgError{Code: "23505"}, errors.KeyConflict, http.StatusConflict},
+		{"unrelated error", errors.ErrorUnauthorized, errors.KeyUnauthorized, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ReferenceNotFound(tt.err)
+			if got := errors.Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := errors.StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+	if ReferenceNotFound(nil) != nil {
+		t.Error("ReferenceNotFound(nil) != nil")
+	}
+
+	resp := errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/posts/7/comments", nil), ReferenceNotFound(violation), errors.WithLogging(false))
+	if want := map[string]any{"constraint": "comments_post_id_fkey"}; !reflect.DeepEqual(resp.Body.Details, want) {
+		t.Errorf("details = %v, want %v", resp.Body.Details, want)
+	}
+}
+
+// captureLogs returns what the logging package writes while fn runs.
+func captureLogs(t *testing.T, fn func()) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stderr := os.Stderr
+	os.Stderr = f
+	logging.Initialize(nil)
+	defer func() {
+		os.Stderr = stderr
+		logging.Initialize(nil)
+	}()
+	fn()
+	logging.Finalize()
+	out, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestPgErrorTextStaysInLogs(t *testing.T) {
+	const leak = "users_email_key_secret_index"
+	tests := []struct {
+		name string
+		err  *pgconn.PgError
+	}{
+		{"unique violation", &pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint " + leak, Detail: "Key (email)=(a@example.com) already exists."}},
+		{"unknown SQLSTATE", &pgconn.PgError{Code: "22012", Message: "division by zero in " + leak}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			logs := captureLogs(t, func() {
+				resp := errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/users", nil), fmt.Errorf("creating user: %w", tt.err))
+				var err error
+				if body, err = json.Marshal(resp.Body); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if strings.Contains(string(body), leak) || strings.Contains(string(body), "a@example.com") {
+				t.Errorf("body = %s, leaks the PostgreSQL error", body)
+			}
+			if !strings.Contains(logs, leak) {
+				t.Errorf("logs = %s, want the PostgreSQL error logged", logs)
+			}
+		})
+	}
+}
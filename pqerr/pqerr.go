@@ -0,0 +1,110 @@
+// Package pqerr maps PostgreSQL errors from lib/pq to semantic HTTP errors, mirroring the
+// pgxerr package for services that haven't moved to pgx.
+package pqerr
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"github.com/lib/pq"
+)
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about *pq.Error, anywhere in the chain,
+// by SQLSTATE:
+//
+//	23505 unique_violation          -> 409 CONFLICT
+//	23503 foreign_key_violation     -> 400 WRONG_PARAMETER
+//	23502 not_null_violation        -> 400 WRONG_PARAMETER
+//	23514 check_violation           -> 400 WRONG_PARAMETER
+//	40001 serialization_failure     -> 409 CONFLICT, retryable
+//	40P01 deadlock_detected         -> 409 CONFLICT, retryable
+//	class 08, 53300, 57P01-57P03    -> 503 DATABASE_UNAVAILABLE
+//
+// Constraint violations carry the constraint, table and column names the server reported
+// in their details. Other SQLSTATEs are left to the default resolution. Calling Register
+// more than once has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		registerErr = errors.RegisterTypeClassifier(classify)
+	})
+	return registerErr
+}
+
+// classify maps a pq.Error by its SQLSTATE. The table is a copy of the one in pgxerr
+// rather than shared with it: importing pgxerr would pull pgx into the module graph of
+// lib/pq services, and the core module stays free of database specifics.
+func classify(err *pq.Error) (errors.Classification, bool) {
+	switch err.Code {
+	case "23505":
+		return errors.Classification{
+			Code:    errors.KeyConflict,
+			Status:  http.StatusConflict,
+			Message: "resource already exists",
+			Details: constraintDetails(err),
+		}, true
+	case "23503":
+		return errors.Classification{
+			Code:    errors.KeyWrongParams,
+			Status:  http.StatusBadRequest,
+			Message: "referenced resource does not exist",
+			Details: constraintDetails(err),
+		}, true
+	case "23502":
+		return errors.Classification{
+			Code:    errors.KeyWrongParams,
+			Status:  http.StatusBadRequest,
+			Message: "missing required value",
+			Details: constraintDetails(err),
+		}, true
+	case "23514":
+		return errors.Classification{
+			Code:    errors.KeyWrongParams,
+			Status:  http.StatusBadRequest,
+			Message: "value out of allowed range",
+			Details: constraintDetails(err),
+		}, true
+	case "40001", "40P01":
+		return errors.Classification{
+			Code:      errors.KeyConflict,
+			Status:    http.StatusConflict,
+			Message:   "concurrent update, please retry",
+			Retryable: true,
+		}, true
+	case "53300", "57P01", "57P02", "57P03":
+		return unavailable(), true
+	}
+	if err.Code.Class() == "08" {
+		return unavailable(), true
+	}
+	return errors.Classification{}, false
+}
+
+// unavailable classifies database connectivity failures.
+func unavailable() errors.Classification {
+	return errors.Classification{
+		Code:    errors.KeyDatabaseUnavailable,
+		Status:  http.StatusServiceUnavailable,
+		Message: "service unavailable",
+	}
+}
+
+// constraintDetails returns the constraint, table and column the server reported, if any.
+func constraintDetails(err *pq.Error) map[string]any {
+	details := map[string]any{}
+	for key, value := range map[string]string{
+		"constraint": err.Constraint,
+		"table":      err.Table,
+		"column":     err.Column,
+	} {
+		if value != "" {
+			details[key] = value
+		}
+	}
+	return details
+}
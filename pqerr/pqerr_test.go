@@ -0,0 +1,58 @@
+package pqerr
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/A-pen-app/errors"
+	"github.com/lib/pq"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           *pq.Error
+		wantCode      errors.ErrorCode
+		wantStatus    int
+		wantDetails   map[string]any
+		wantRetryable bool
+	}{
+		{"unique violation", &pq.Error{Code: "23505", Constraint: "users_email_key", Table: "users"}, errors.KeyConflict, http.StatusConflict, map[string]any{"constraint": "users_email_key", "table": "users"}, false},
+		{"foreign key violation", &pq.Error{Code: "23503", Constraint: "posts_author_id_fkey"}, errors.KeyWrongParams, http.StatusBadRequest, map[string]any{"constraint": "posts_author_id_fkey"}, false},
+		{"not null violation", &pq.Error{Code: "23502", Table: "posts", Column: "title"}, errors.KeyWrongParams, http.StatusBadRequest, map[string]any{"table": "posts", "column": "title"}, false},
+		{"check violation", &pq.Error{Code: "23514", Constraint: "rating_range"}, errors.KeyWrongParams, http.StatusBadRequest, map[string]any{"constraint": "rating_range"}, false},
+		{"serialization failure", &pq.Error{Code: "40001"}, errors.KeyConflict, http.StatusConflict, nil, true},
+		{"deadlock", &pq.Error{Code: "40P01"}, errors.KeyConflict, http.StatusConflict, nil, true},
+		{"connection failure", &pq.Error{Code: "08006"}, errors.KeyDatabaseUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"too many connections", &pq.Error{Code: "53300"}, errors.KeyDatabaseUnavailable, http.StatusServiceUnavailable, nil, true},
+		{"unknown SQLSTATE", &pq.Error{Code: "22012"}, errors.KeyInternalError, http.StatusInternalServerError, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("creating post: %w", tt.err))
+			if got := errors.Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := errors.StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+			if got := errors.IsRetryable(err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %t, want %t", got, tt.wantRetryable)
+			}
+			resp := errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/posts", nil), err, errors.WithLogging(false))
+			if len(tt.wantDetails) > 0 && !reflect.DeepEqual(resp.Body.Details, tt.wantDetails) {
+				t.Errorf("details = %v, want %v", resp.Body.Details, tt.wantDetails)
+			}
+		})
+	}
+}
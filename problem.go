@@ -0,0 +1,67 @@
+package errors
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem documents.
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is an error response as an RFC 7807 problem document, sent instead of an
+// HttpError when WithProblemDetails is on. The code, error type, request ID and details of
+// the HttpError are extension members.
+type ProblemDetails struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	Code      string         `json:"code"`
+	ErrorType string         `json:"error_type,omitempty"`
+	RequestID string         `json:"request_id"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// WithProblemDetails makes error responses RFC 7807 problem documents, sent as
+// application/problem+json, instead of HttpErrors, for partners that require them. The
+// type member is built from the template set with WithProblemTypeURI. It applies to the
+// JSON responses of Handle and HandleHTTP, unless an encoder is set with WithEncoder.
+func WithProblemDetails(enabled bool) Option {
+	return func(c *handlerConfig) {
+		c.problemDetails = enabled
+	}
+}
+
+// WithProblemTypeURI sets the template of the type member of problem documents, whose
+// "{code}" is replaced by the error code, e.g. "https://docs.a-pen.app/errors/{code}".
+// Without a template, the type is "about:blank".
+func WithProblemTypeURI(template string) Option {
+	return func(c *handlerConfig) {
+		c.problemTypeURI = template
+	}
+}
+
+// problem returns body, sent with status for the request to instance, as a problem
+// document.
+func (c *handlerConfig) problem(status int, body HttpError, instance string) ProblemDetails {
+	problemType := "about:blank"
+	if c.problemTypeURI != "" {
+		problemType = strings.ReplaceAll(c.problemTypeURI, "{code}", body.Code)
+	}
+	title := http.StatusText(status)
+	if title == "" {
+		title = body.Code
+	}
+	return ProblemDetails{
+		Type:      problemType,
+		Title:     title,
+		Status:    status,
+		Detail:    body.Message,
+		Instance:  instance,
+		Code:      body.Code,
+		ErrorType: body.Type,
+		RequestID: body.RequestID,
+		Details:   body.Details,
+	}
+}
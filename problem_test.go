@@ -0,0 +1,57 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestProblemDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		opts []Option
+		want ProblemDetails
+	}{
+		{
+			"not found",
+			Wrap(ErrorNotFound, "post_id", 7),
+			[]Option{WithProblemTypeURI("https://docs.a-pen.app/errors/{code}")},
+			ProblemDetails{Type: "https://docs.a-pen.app/errors/NOT_FOUND", Title: "Not Found", Status: http.StatusNotFound, Detail: "data not found", Instance: "/test", Code: "NOT_FOUND", RequestID: testTraceID, Details: map[string]any{"post_id": float64(7)}},
+		},
+		{
+			"bad request",
+			WithType(Wrap(ErrorWrongParams, "field", "title"), ErrorTypeValidation),
+			nil,
+			ProblemDetails{Type: "about:blank", Title: "Bad Request", Status: http.StatusBadRequest, Detail: "wrong parameters", Instance: "/test", Code: "WRONG_PARAMETER", ErrorType: "validation", RequestID: testTraceID, Details: map[string]any{"field": "title"}},
+		},
+		{
+			"internal error",
+			fmt.Errorf("querying posts: connection reset"),
+			[]Option{WithProductionMessages(true)},
+			ProblemDetails{Type: "about:blank", Title: "Internal Server Error", Status: http.StatusInternalServerError, Detail: "internal system error", Instance: "/test", Code: "INTERNAL_ERROR", RequestID: testTraceID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithProblemDetails(true), WithLogging(false)}, tt.opts...)
+			w, _ := serveRequest(t, withTrace(httptest.NewRequest(http.MethodGet, "/test", nil)), returning(tt.err), opts...)
+			if w.Code != tt.want.Status {
+				t.Errorf("status = %d, want %d", w.Code, tt.want.Status)
+			}
+			if got := w.Header().Get("Content-Type"); got != ProblemContentType {
+				t.Errorf("Content-Type = %q, want %q", got, ProblemContentType)
+			}
+			var got ProblemDetails
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problem =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package protoerr maps failures to unmarshal protobuf, protojson and prototext request
+// bodies to 400 WRONG_PARAMETER, as the core does for JSON binding errors.
+package protoerr
+
+import (
+	stderrors "errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about protobuf unmarshal errors anywhere in the
+// chain, from proto.Unmarshal, protojson.Unmarshal and prototext.Unmarshal. They map to
+// 400 WRONG_PARAMETER with the line and column of the offending input, and the field name
+// for unknown fields, in the details.
+//
+// The protobuf module reports every failure as a proto.Error, so unmarshal failures are
+// told apart by the message shape only they produce: a "(line N:M)" position, an
+// unexpected EOF or invalid wire-format data. Failures to marshal responses keep mapping
+// to 500. Calling Register more than once has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		registerErr = errors.RegisterTypeClassifier(classify)
+	})
+	return registerErr
+}
+
+var (
+	// position matches the "(line 1:10)" position of protojson and prototext errors.
+	position = regexp.MustCompile(`\(line (\d+):(\d+)\)`)
+	// unknownField matches the field of `unknown field "nickname"` errors.
+	unknownField = regexp.MustCompile(`unknown field "([^"]+)"`)
+)
+
+// classify maps protobuf unmarshal errors in a chain. It is registered for the error
+// interface, as the protobuf error types are unexported.
+func classify(err error) (errors.Classification, bool) {
+	// The innermost error matching proto.Error, short of the sentinel itself, carries the
+	// protobuf message without our wrapping
+	var protoErr error
+	for current := err; current != nil && current != proto.Error; current = stderrors.Unwrap(current) {
+		if stderrors.Is(current, proto.Error) {
+			protoErr = current
+		}
+	}
+	if protoErr == nil {
+		return errors.Classification{}, false
+	}
+	msg := protoErr.Error()
+	switch {
+	case strings.Contains(msg, "cannot parse invalid wire-format data"), strings.HasSuffix(msg, "unexpected EOF"):
+		return malformed(nil), true
+	case !position.MatchString(msg):
+		return errors.Classification{}, false
+	}
+
+	m := position.FindStringSubmatch(msg)
+	details := map[string]any{}
+	details["line"], _ = strconv.Atoi(m[1])
+	details["column"], _ = strconv.Atoi(m[2])
+	if m := unknownField.FindStringSubmatch(msg); m != nil {
+		details["field"] = m[1]
+		c := malformed(details)
+		c.Message = "unknown field '" + m[1] + "'"
+		return c, true
+	}
+	return malformed(details), true
+}
+
+// malformed returns the classification of a request body that couldn't be unmarshaled.
+func malformed(details map[string]any) errors.Classification {
+	return errors.Classification{
+		Code:    errors.KeyWrongParams,
+		Status:  http.StatusBadRequest,
+		Message: "malformed request body",
+		Details: details,
+	}
+}
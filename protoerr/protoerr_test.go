@@ -0,0 +1,84 @@
+package protoerr
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/A-pen-app/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+func TestRegister(t *testing.T) {
+	invalidUTF8 := &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: "\xff"}}
+	_, marshalErr := proto.Marshal(invalidUTF8)
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+		wantDetails map[string]any
+	}{
+		{
+			"protojson unknown field",
+			protojson.Unmarshal([]byte(`{"name": "feed.proto", "nickname": "x"}`), &descriptorpb.FileDescriptorProto{}),
+			http.StatusBadRequest, "unknown field 'nickname'",
+			map[string]any{"line": 1, "column": 24, "field": "nickname"},
+		},
+		{
+			"protojson invalid value",
+			protojson.Unmarshal([]byte("{\n  \"name\": 1\n}"), &descriptorpb.FileDescriptorProto{}),
+			http.StatusBadRequest, "malformed request body",
+			map[string]any{"line": 2, "column": 11},
+		},
+		{
+			"prototext syntax error",
+			prototext.Unmarshal([]byte(`name: `), &descriptorpb.FileDescriptorProto{}),
+			http.StatusBadRequest, "malformed request body",
+			nil,
+		},
+		{
+			"wire format",
+			proto.Unmarshal([]byte{0xff}, &descriptorpb.FileDescriptorProto{}),
+			http.StatusBadRequest, "malformed request body",
+			nil,
+		},
+		{
+			"marshal failure",
+			marshalErr,
+			http.StatusInternalServerError, "",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatal("protobuf returned no error")
+			}
+			err := errors.Wrap(fmt.Errorf("decoding post: %w", tt.err))
+			resp := errors.ResponseFor(httptest.NewRequest(http.MethodPost, "/posts", nil), err, errors.WithLogging(false))
+			if resp.Status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Status, tt.wantStatus)
+			}
+			if tt.wantMessage != "" && resp.Body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", resp.Body.Message, tt.wantMessage)
+			}
+			if tt.wantDetails != nil && !reflect.DeepEqual(resp.Body.Details, tt.wantDetails) {
+				t.Errorf("details = %v, want %v", resp.Body.Details, tt.wantDetails)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// HandleMessage wraps a queue consumer's message handler so that failed messages are
+// settled according to the error taxonomy instead of ad-hoc string matching. ack drops a
+// message and nack redelivers it, no sooner than retryAfter when it is positive:
+//
+//	err := sub.Receive(ctx, errors.HandleMessage(processEvent,
+//	    (*pubsub.Message).Ack,
+//	    func(m *pubsub.Message, _ time.Duration) { m.Nack() },
+//	))
+//
+// Messages are acked when fn succeeds, and when it fails with a client error that isn't
+// retryable, such as WRONG_PARAMETER or NOT_FOUND, as redelivering them would fail again.
+// Retryable and server errors are nacked with the delay set by WithRetryAfter, and so are
+// errors from a canceled context, e.g. during shutdown. Errors are logged with the fields
+// handled errors get, plus a "message_action" field of "ack" or "nack", and unknown
+// errors call the unknown error hook. Options work as they do for Handle, except for
+// those taking a *gin.Context.
+func HandleMessage[M any](fn func(context.Context, M) error, ack func(M), nack func(msg M, retryAfter time.Duration), opts ...Option) func(context.Context, M) {
+	if ack == nil || nack == nil {
+		panic("errors: HandleMessage called with nil ack or nack func")
+	}
+	configs := newConfigCache(opts)
+	return func(ctx context.Context, msg M) {
+		err := fn(ctx, msg)
+		if err == nil {
+			ack(msg)
+			return
+		}
+		cfg := configs.get()
+		redeliver := shouldRedeliver(ctx, err)
+		scope := requestScope{ctx: ctx, legacyCodes: cfg.legacyCodesEnabled, messageAction: "ack"}
+		if redeliver {
+			scope.messageAction = "nack"
+		}
+		buildResponse(err, cfg, scope)
+		if redeliver {
+			nack(msg, RetryAfter(err))
+			return
+		}
+		ack(msg)
+	}
+}
+
+// shouldRedeliver reports whether a message whose handler failed with err should be
+// redelivered, i.e. unless err is a client error that isn't retryable. Cancellations are
+// always redelivered, as the message was never fully processed.
+func shouldRedeliver(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return true
+	}
+	status := StatusOf(err)
+	return isRetryable(err, status) || !isClientStatus(status)
+}
@@ -0,0 +1,69 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// message is a queue message settled by the handler under test.
+type message struct {
+	id         string
+	settlement string
+	retryAfter time.Duration
+}
+
+func TestHandleMessage(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		err            error
+		wantSettlement string
+		wantRetryAfter time.Duration
+	}{
+		{"success", context.Background(), nil, "ack", 0},
+		{"wrong params", context.Background(), Wrap(ErrorWrongParams, "field", "post_id"), "ack", 0},
+		{"not found", context.Background(), fmt.Errorf("loading post: %w", ErrorNotFound), "ack", 0},
+		{"retryable client error", context.Background(), TooManyRequests(30 * time.Second), "nack", 30 * time.Second},
+		{"client error marked retryable", context.Background(), WithRetryable(ErrorConflict, true), "nack", 0},
+		{"server error", context.Background(), fmt.Errorf("connection reset"), "nack", 0},
+		{"unavailable with delay", context.Background(), Unavailable(time.Minute), "nack", time.Minute},
+		{"server error marked not retryable", context.Background(), WithRetryable(ErrorInternalError, false), "nack", 0},
+		{"canceled error", context.Background(), fmt.Errorf("publishing: %w", context.Canceled), "nack", 0},
+		{"canceled context", canceled, ErrorWrongParams, "nack", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := HandleMessage(func(context.Context, *message) error { return tt.err },
+				func(m *message) { m.settlement = "ack" },
+				func(m *message, retryAfter time.Duration) { m.settlement, m.retryAfter = "nack", retryAfter },
+			)
+			msg := &message{id: "m1"}
+			logs := captureLogs(t, func() {
+				handle(tt.ctx, msg)
+			})
+			if msg.settlement != tt.wantSettlement || msg.retryAfter != tt.wantRetryAfter {
+				t.Errorf("settled with %s after %s, want %s after %s", msg.settlement, msg.retryAfter, tt.wantSettlement, tt.wantRetryAfter)
+			}
+			if tt.err == nil {
+				return
+			}
+			if field := `"labels.message_action": "` + tt.wantSettlement + `"`; !strings.Contains(logs, field) && !strings.Contains(logs, "message_action="+tt.wantSettlement) {
+				t.Errorf("logs = %s, want message_action %s", logs, tt.wantSettlement)
+			}
+		})
+	}
+}
+
+func TestHandleMessageNilSettlers(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("HandleMessage() with a nil nack didn't panic")
+		}
+	}()
+	HandleMessage(func(context.Context, string) error { return nil }, func(string) {}, nil)
+}
@@ -0,0 +1,53 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"syscall"
+
+	"github.com/A-pen-app/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery returns a gin middleware that recovers from panics in later handlers and
+// reports them like returned errors, as a 500 INTERNAL_ERROR HttpError with a request ID,
+// instead of gin's plain-text 500. The panic value is kept as the cause when it is an
+// error, and the stack trace is logged in a "stack" field. Panics from writing to a
+// client that has gone away are logged at info level without a response, and
+// http.ErrAbortHandler is re-panicked so that net/http aborts the response. Options work
+// as they do for Handle.
+func Recovery(opts ...Option) gin.HandlerFunc {
+	newHandlerConfig(opts)
+	return func(ctx *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			cause := panicCause(p)
+			if errors.Is(cause, syscall.EPIPE) || errors.Is(cause, syscall.ECONNRESET) {
+				logging.Info(ctx.Request.Context(), "errors: client connection broken: %v", cause)
+				ctx.Abort()
+				return
+			}
+			// A panic is a bug whatever its value, so it is always reported as a 500
+			err := WithStatus(WithCode(Wrapf(cause, "panic"), KeyInternalError), http.StatusInternalServerError)
+			cfg := effectiveConfig(append(opts[:len(opts):len(opts)], WithLogFields("stack", string(debug.Stack()))))
+			handleError(ctx, err, cfg)
+		}()
+		ctx.Next()
+	}
+}
+
+// panicCause returns a recovered panic value as an error.
+func panicCause(p any) error {
+	if err, ok := p.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", p)
+}
@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// panicking returns a router recovering with Recovery from handlers that panic with p.
+func panicking(p any) *gin.Engine {
+	router := gin.New()
+	router.Use(Recovery())
+	router.GET("/test", func(*gin.Context) { panic(p) })
+	return router
+}
+
+// postPanic is a struct panic value.
+type postPanic struct {
+	PostID int
+	Reason string
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		wantLog string
+	}{
+		{"error", fmt.Errorf("rendering feed: %w", ErrorNotFound), "panic: rendering feed: data not found"},
+		{"string", "index out of range", "panic: index out of range"},
+		{"struct", postPanic{PostID: 7, Reason: "nil author"}, "panic: {7 nil author}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			logs := captureLogs(t, func() {
+				panicking(tt.value).ServeHTTP(w, withTrace(httptest.NewRequest(http.MethodGet, "/test", nil)))
+			})
+			var body HttpError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+			}
+			if w.Code != http.StatusInternalServerError || body.Code != string(KeyInternalError) {
+				t.Errorf("response = %d %s, want 500 %s", w.Code, body.Code, KeyInternalError)
+			}
+			if body.RequestID != testTraceID {
+				t.Errorf("request_id = %q, want %q", body.RequestID, testTraceID)
+			}
+			line := logLine(t, logs, tt.wantLog)
+			if !strings.Contains(line, "ERROR") || !strings.Contains(line, "labels.stack") || !strings.Contains(line, "runtime/debug.Stack") {
+				t.Errorf("log line = %s, want an error with the stack", line)
+			}
+		})
+	}
+}
+
+func TestRecoveryBrokenConnection(t *testing.T) {
+	w := httptest.NewRecorder()
+	logs := captureLogs(t, func() {
+		panicking(fmt.Errorf("writing event: %w", syscall.EPIPE)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	})
+	if w.Body.Len() != 0 {
+		t.Errorf("body = %q, want no response", w.Body.String())
+	}
+	if line := logLine(t, logs, "client connection broken"); !strings.Contains(line, "INFO") {
+		t.Errorf("log line = %s, want level INFO", line)
+	}
+}
+
+func TestRecoveryAbortHandler(t *testing.T) {
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler re-panicked", p)
+		}
+	}()
+	panicking(http.ErrAbortHandler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+}
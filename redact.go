@@ -0,0 +1,55 @@
+package errors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const redactedPlaceholder = "[REDACTED]"
+
+// Redacted wraps a sensitive detail value so that it never appears in plaintext in
+// Error() output, logs or JSON responses. Use Unredact to read the raw value in trusted code.
+type Redacted struct {
+	value  any
+	hashed bool
+}
+
+// Redact marks value as sensitive. It renders as "[REDACTED]".
+func Redact(value any) Redacted {
+	return Redacted{value: value}
+}
+
+// RedactHash marks value as sensitive but renders a stable hash prefix, e.g.
+// "[REDACTED:3f79bb7b]", so occurrences can be correlated without exposing the value.
+func RedactHash(value any) Redacted {
+	return Redacted{value: value, hashed: true}
+}
+
+// Unredact returns the raw value. Only pass the result to trusted sinks.
+func (r Redacted) Unredact() any {
+	return r.value
+}
+
+func (r Redacted) String() string {
+	if !r.hashed {
+		return redactedPlaceholder
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprint(r.value)))
+	return fmt.Sprintf("[REDACTED:%s]", hex.EncodeToString(sum[:4]))
+}
+
+// Format implements fmt.Formatter so that no verb or flag prints the raw value.
+func (r Redacted) Format(f fmt.State, verb rune) {
+	io.WriteString(f, r.String())
+}
+
+func (r Redacted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r Redacted) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedactFormatting(t *testing.T) {
+	const secret = "hunter2"
+	hashed := RedactHash(secret).String()
+	if !strings.HasPrefix(hashed, "[REDACTED:") || len(hashed) != len("[REDACTED:")+8+1 {
+		t.Errorf("RedactHash() = %q, want an 8-digit hash prefix", hashed)
+	}
+	if RedactHash(secret).String() != hashed {
+		t.Error("RedactHash() is not stable across calls")
+	}
+	for _, r := range []Redacted{Redact(secret), RedactHash(secret)} {
+		b, err := json.Marshal(map[string]any{"password": r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, out := range []string{fmt.Sprint(r), fmt.Sprintf("%v %+v %#v %s %q %x", r, r, r, r, r, r), string(b)} {
+			if strings.Contains(out, secret) {
+				t.Errorf("output %q leaks the value", out)
+			}
+		}
+		if r.Unredact() != secret {
+			t.Errorf("Unredact() = %v, want %q", r.Unredact(), secret)
+		}
+	}
+}
+
+func TestRedactThroughWraps(t *testing.T) {
+	const secret = "hunter2"
+	err := fmt.Errorf("logging in: %w", Wrap(
+		Wrap(ErrorWrongParams, "password", Redact(secret)),
+		"token", RedactHash(secret),
+	))
+	if strings.Contains(err.Error(), secret) || strings.Contains(fmt.Sprintf("%+v", err), secret) {
+		t.Errorf("error text leaks the value: %+v", err)
+	}
+	var w string
+	logs := captureLogs(t, func() {
+		rec, body := serve(t, returning(err))
+		w = rec.Body.String()
+		if body.Details["password"] != redactedPlaceholder || body.Details["token"] != RedactHash(secret).String() {
+			t.Errorf("details = %v, want the values masked", body.Details)
+		}
+	})
+	if strings.Contains(w, secret) {
+		t.Errorf("body = %s, leaks the value", w)
+	}
+	if strings.Contains(logs, secret) {
+		t.Errorf("logs = %s, leak the value", logs)
+	}
+	if !strings.Contains(logLine(t, logs, "logging in"), redactedPlaceholder) {
+		t.Errorf("logs = %s, want the placeholder logged", logs)
+	}
+}
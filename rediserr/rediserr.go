@@ -0,0 +1,48 @@
+// Package rediserr maps go-redis errors to semantic HTTP errors, so that a cache miss
+// propagated as redis.Nil is a 404 rather than a 500.
+package rediserr
+
+import (
+	stderrors "errors"
+	"net/http"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about the go-redis errors, anywhere in the chain:
+//
+//	redis.Nil            -> 404 NOT_FOUND, with the message "data not found"
+//	redis.ErrClosed      -> 503 SERVICE_UNAVAILABLE, retryable
+//	redis.ErrPoolTimeout -> 503 SERVICE_UNAVAILABLE, retryable
+//
+// Calling Register more than once has no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		registerErr = stderrors.Join(
+			errors.RegisterTypeClassifier(classifyNil),
+			errors.Register(redis.ErrClosed, errors.KeyServiceUnavailable, http.StatusServiceUnavailable),
+			errors.Register(redis.ErrPoolTimeout, errors.KeyServiceUnavailable, http.StatusServiceUnavailable),
+		)
+	})
+	return registerErr
+}
+
+// classifyNil classifies chains holding redis.Nil, replacing its "redis: nil" message. It
+// is registered for the error interface, so it sees the whole chain.
+func classifyNil(err error) (errors.Classification, bool) {
+	if !stderrors.Is(err, redis.Nil) {
+		return errors.Classification{}, false
+	}
+	return errors.Classification{
+		Code:    errors.KeyNotFound,
+		Status:  http.StatusNotFound,
+		Message: errors.ErrorNotFound.Error(),
+	}, true
+}
@@ -0,0 +1,61 @@
+package rediserr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/A-pen-app/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantCode      errors.ErrorCode
+		wantStatus    int
+		wantRetryable bool
+	}{
+		{"nil", redis.Nil, errors.KeyNotFound, http.StatusNotFound, false},
+		{"closed", redis.ErrClosed, errors.KeyServiceUnavailable, http.StatusServiceUnavailable, true},
+		{"pool timeout", redis.ErrPoolTimeout, errors.KeyServiceUnavailable, http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("loading session: %w", tt.err), "session_id", "s1")
+			if got := errors.Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := errors.StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+			if got := errors.IsRetryable(err); got != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %t, want %t", got, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestRegisterNilMessage(t *testing.T) {
+	if got, want := errors.MessageOf(fmt.Errorf("get: %w", redis.Nil)), errors.ErrorNotFound.Error(); got != want {
+		t.Errorf("MessageOf() = %q, want %q", got, want)
+	}
+}
+
+func TestRegisterClosedClient(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	client.Close()
+	err := client.Get(context.Background(), "session:s1").Err()
+	if got := errors.StatusOf(err); got != http.StatusServiceUnavailable {
+		t.Errorf("StatusOf(%v) = %d, want %d", err, got, http.StatusServiceUnavailable)
+	}
+}
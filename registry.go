@@ -0,0 +1,373 @@
+package errors
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/A-pen-app/logging"
+)
+
+// Matcher classifies errors that can't be registered by identity, such as error families
+// distinguished by type or field values. It returns false when it doesn't apply.
+type Matcher func(error) (ErrorMapping, bool)
+
+// registryMu guards errorMappings, typeMappings and matchers, which may be registered
+// at any time while requests are being handled.
+var registryMu sync.RWMutex
+
+// packageSentinels lists the sentinels declared by this package, in declaration order.
+var packageSentinels = []error{
+	ErrorNotFound,
+	ErrorNotAllowed,
+	ErrorWrongParams,
+	ErrorUnauthorized,
+	ErrorPermissionDenied,
+	ErrorUnprocessableEntity,
+	ErrorInternalError,
+	ErrorDuplicateEntry,
+	ErrorInsufficientQuota,
+	ErrorUserNotVerified,
+	ErrorUnsupported,
+	ErrorConflict,
+	ErrorTooManyRequests,
+	ErrorUnavailable,
+	ErrorPayloadTooLarge,
+	ErrorUnsupportedMediaType,
+	ErrorNotImplemented,
+	ErrorGone,
+	ErrorPreconditionFailed,
+	ErrorPreconditionRequired,
+	ErrorMethodNotAllowed,
+	ErrorBadGateway,
+	ErrorGatewayTimeout,
+	ErrorDatabaseUnavailable,
+	ErrorClientClosedRequest,
+}
+
+// registrationOrder lists the keys of errorMappings, built-in ones first in declaration
+// order and then runtime registrations, so that lookups by code are deterministic.
+var registrationOrder = append(append([]error(nil), packageSentinels...),
+	sql.ErrNoRows,
+	http.ErrMissingFile,
+	http.ErrMissingBoundary,
+	http.ErrNotMultipart,
+	context.Canceled,
+	context.DeadlineExceeded,
+	os.ErrDeadlineExceeded,
+	driver.ErrBadConn,
+	sql.ErrConnDone,
+	sql.ErrTxDone,
+)
+
+// isPackageSentinel reports whether err is one of the sentinels declared by this package,
+// as opposed to the standard library and third-party errors mapped alongside them.
+func isPackageSentinel(err error) bool {
+	for _, sentinel := range packageSentinels {
+		if err == sentinel {
+			return true
+		}
+	}
+	return false
+}
+
+// packageSentinelFor returns the first package sentinel currently mapped to code.
+func packageSentinelFor(code ErrorCode) (error, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, sentinel := range packageSentinels {
+		if errorMappings[sentinel].Code == code {
+			return sentinel, true
+		}
+	}
+	return nil, false
+}
+
+// matchers holds the registered matchers in registration order.
+var matchers []Matcher
+
+// typeMappings holds the default mapping for each ErrorType, used when an error's cause
+// has no mapping of its own.
+var typeMappings = map[ErrorType]ErrorMapping{}
+
+// Register maps a sentinel error to a code and HTTP status, so that handleError and the
+// accessors resolve it (and anything wrapping it) like the built-in sentinels.
+// It rejects nil or non-comparable errors, invalid statuses and errors already registered;
+// use RegisterOverride to intentionally replace an existing mapping.
+func Register(err error, code ErrorCode, status int) error {
+	return register(err, code, status, false)
+}
+
+// RegisterOverride is like Register but replaces any existing mapping for err, including
+// the built-in ones, e.g. to treat sql.ErrNoRows as a 500 in a particular service.
+func RegisterOverride(err error, code ErrorCode, status int) error {
+	return register(err, code, status, true)
+}
+
+func register(err error, code ErrorCode, status int, allowOverride bool) error {
+	if err == nil {
+		return fmt.Errorf("errors: cannot register nil error")
+	}
+	if !reflect.ValueOf(err).Comparable() {
+		return fmt.Errorf("errors: cannot register non-comparable error %T", err)
+	}
+	if !isValidStatus(status) {
+		return fmt.Errorf("errors: invalid status %d for %q", status, err)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	_, exists := errorMappings[err]
+	if exists && !allowOverride {
+		return fmt.Errorf("errors: %q is already registered", err)
+	}
+	if !exists {
+		registrationOrder = append(registrationOrder, err)
+	}
+	errorMappings[err] = ErrorMapping{code, status}
+	return nil
+}
+
+// MustRegister is like Register but panics on failure. It is intended for package init.
+func MustRegister(err error, code ErrorCode, status int) {
+	if regErr := Register(err, code, status); regErr != nil {
+		panic(regErr)
+	}
+}
+
+// RegisterTypeMapping sets the default code and status for errors of the given type
+// whose cause has no mapping of its own.
+func RegisterTypeMapping(t ErrorType, code ErrorCode, status int) error {
+	if t == "" {
+		return fmt.Errorf("errors: cannot register mapping for empty error type")
+	}
+	if !isValidStatus(status) {
+		return fmt.Errorf("errors: invalid status %d for error type %q", status, t)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	typeMappings[t] = ErrorMapping{code, status}
+	return nil
+}
+
+// lookupTypeMapping returns the default mapping registered for t.
+func lookupTypeMapping(t ErrorType) (ErrorMapping, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	mapping, exists := typeMappings[t]
+	return mapping, exists
+}
+
+// RegisterMatcher adds a matcher consulted after the identity mappings and binding-error
+// detection but before the INTERNAL_ERROR fallback. Matchers run in registration order and
+// the first match wins. Matches with an invalid status are ignored.
+func RegisterMatcher(m Matcher) error {
+	if m == nil {
+		return fmt.Errorf("errors: cannot register nil matcher")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	matchers = append(matchers, m)
+	return nil
+}
+
+// RegisterType maps every error of type T anywhere in the chain to a code and status,
+// for typed errors such as *pgconn.PgError that can't be registered by identity.
+// T may be a pointer or value error type. Typed registrations are matchers, so they
+// run in registration order after the sentinels and binding-error detection.
+func RegisterType[T error](code ErrorCode, status int) error {
+	if !isValidStatus(status) {
+		return fmt.Errorf("errors: invalid status %d for %s", status, reflect.TypeFor[T]())
+	}
+	mapping := ErrorMapping{code, status}
+	return RegisterTypeFunc(func(T) ErrorMapping { return mapping })
+}
+
+// RegisterTypeFunc is like RegisterType but derives the mapping from the matched error
+// value, for finer-grained decisions such as switching on a field.
+func RegisterTypeFunc[T error](fn func(T) ErrorMapping) error {
+	if fn == nil {
+		return fmt.Errorf("errors: cannot register nil mapping func for %s", reflect.TypeFor[T]())
+	}
+	return RegisterMatcher(func(err error) (ErrorMapping, bool) {
+		var target T
+		if errors.As(err, &target) {
+			return fn(target), true
+		}
+		return ErrorMapping{}, false
+	})
+}
+
+// runMatchers runs the registered matchers against the unwrapped cause first, and then
+// against every error in the original chain.
+func runMatchers(err, cause error) (ErrorMapping, bool) {
+	// Copy the slice so matchers run without holding the lock
+	registryMu.RLock()
+	registered := append([]Matcher(nil), matchers...)
+	registryMu.RUnlock()
+
+	for _, m := range registered {
+		if mapping, ok := callMatcher(m, cause); ok {
+			return mapping, true
+		}
+		matched := false
+		var mapping ErrorMapping
+		walkErrors(err, func(err error) bool {
+			mapping, matched = callMatcher(m, err)
+			return !matched
+		})
+		if matched {
+			return mapping, true
+		}
+	}
+	return ErrorMapping{}, false
+}
+
+// callMatcher runs m against err, recovering from panics so that a broken matcher is skipped.
+func callMatcher(m Matcher, err error) (mapping ErrorMapping, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error(context.Background(), "errors: matcher panicked on %T: %v", err, r)
+			mapping, ok = ErrorMapping{}, false
+		}
+	}()
+	mapping, ok = m(err)
+	if ok && !isValidStatus(mapping.StatusCode) {
+		return ErrorMapping{}, false
+	}
+	return mapping, ok
+}
+
+// heuristicMappings are the mappings produced by built-in detection rather than by a
+// registered error, so that they can be enumerated alongside the registry.
+var heuristicMappings = []ErrorMapping{
+	{KeyGatewayTimeout, http.StatusGatewayTimeout},
+	{KeyServiceUnavailable, http.StatusServiceUnavailable},
+}
+
+// Codes returns every code currently known to the registry, built-in and registered at
+// runtime, in sorted order. Codes produced only by matchers can't be enumerated.
+func Codes() []ErrorCode {
+	seen := make(map[ErrorCode]bool)
+	for _, mapping := range allMappings() {
+		seen[mapping.Code] = true
+	}
+	codes := make([]ErrorCode, 0, len(seen))
+	for code := range seen {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Mapping returns the mapping registered for code. When several errors share a code
+// with different statuses, the lowest status is returned.
+func Mapping(code ErrorCode) (ErrorMapping, bool) {
+	var result ErrorMapping
+	found := false
+	for _, mapping := range allMappings() {
+		if mapping.Code == code && (!found || mapping.StatusCode < result.StatusCode) {
+			result, found = mapping, true
+		}
+	}
+	return result, found
+}
+
+// allMappings returns a snapshot of every registered, type and heuristic mapping.
+func allMappings() []ErrorMapping {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	mappings := make([]ErrorMapping, 0, len(errorMappings)+len(typeMappings)+len(heuristicMappings))
+	for _, mapping := range errorMappings {
+		mappings = append(mappings, mapping)
+	}
+	for _, mapping := range typeMappings {
+		mappings = append(mappings, mapping)
+	}
+	return append(mappings, heuristicMappings...)
+}
+
+// UnknownCodeError is returned by FromCode for codes the registry doesn't know. It keeps
+// the code so that it is re-emitted unchanged when the error is handled.
+type UnknownCodeError struct {
+	Code ErrorCode
+}
+
+func (e *UnknownCodeError) Error() string {
+	return fmt.Sprintf("unknown error code %s", e.Code)
+}
+
+// FromCode returns the canonical error for a code received over the wire, e.g. from
+// another service's response, so that errors.Is works end-to-end. Built-in codes return a
+// sentinel declared by this package, such as ErrorGatewayTimeout rather than
+// context.DeadlineExceeded; other codes return runtime registrations in registration
+// order. Both sides of a code alias resolve to the same error. Unknown codes return an
+// *UnknownCodeError, which resolves to its own code with status 500.
+func FromCode(code ErrorCode) error {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	target := followAliases(code)
+	for _, err := range registrationOrder {
+		if followAliases(errorMappings[err].Code) == target {
+			return err
+		}
+	}
+	return &UnknownCodeError{Code: code}
+}
+
+// codeAliases maps deprecated codes to the codes that replace them, guarded by registryMu.
+var codeAliases = map[ErrorCode]ErrorCode{}
+
+// RegisterAlias renames oldCode to newCode in responses: errors mapping to oldCode are
+// sent with newCode, unless legacy codes are enabled for the request (see WithLegacyCodes),
+// in which case oldCode is sent and a deprecation event is logged. Aliases may be chained.
+// ErrorCode values in Go code, and Code(), are unaffected.
+func RegisterAlias(oldCode, newCode ErrorCode) error {
+	if oldCode == "" || newCode == "" || oldCode == newCode {
+		return fmt.Errorf("errors: invalid alias %q -> %q", oldCode, newCode)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := codeAliases[oldCode]; exists {
+		return fmt.Errorf("errors: alias for %q is already registered", oldCode)
+	}
+	codeAliases[oldCode] = newCode
+	if followAliases(oldCode) == oldCode {
+		delete(codeAliases, oldCode)
+		return fmt.Errorf("errors: alias %q -> %q would create a cycle", oldCode, newCode)
+	}
+	return nil
+}
+
+// currentCode returns the code that replaces code after following all aliases.
+func currentCode(code ErrorCode) ErrorCode {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return followAliases(code)
+}
+
+// followAliases follows the alias chain from code; registryMu must be held.
+func followAliases(code ErrorCode) ErrorCode {
+	start := code
+	for depth := 0; depth < maxUnwrapDepth; depth++ {
+		next, exists := codeAliases[code]
+		if !exists {
+			return code
+		}
+		if next == start {
+			return start
+		}
+		code = next
+	}
+	return code
+}
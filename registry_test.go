@@ -0,0 +1,319 @@
+package errors
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterConcurrentWithHandle(t *testing.T) {
+	router := gin.New()
+	router.GET("/test", Handle(returning(fmt.Errorf("loading: %w", ErrorNotFound)), WithLogging(false)))
+
+	const workers = 8
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				sentinel := fmt.Errorf("test concurrent %d-%d", i, j)
+				if err := Register(sentinel, "TEST_CONCURRENT", http.StatusTeapot); err != nil {
+					t.Error(err)
+					return
+				}
+				if got := StatusOf(Wrap(sentinel, "k", "v")); got != http.StatusTeapot {
+					t.Errorf("StatusOf(registered) = %d, want %d", got, http.StatusTeapot)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+				if w.Code != http.StatusNotFound {
+					t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRegisterOverrideNoRows(t *testing.T) {
+	t.Cleanup(func() {
+		if err := RegisterOverride(sql.ErrNoRows, KeyNotFound, http.StatusNotFound); err != nil {
+			t.Fatal(err)
+		}
+	})
+	noRowsInfo := func() MappingInfo {
+		for _, info := range Mappings() {
+			if info.Message == sql.ErrNoRows.Error() {
+				return info
+			}
+		}
+		t.Fatal("sql.ErrNoRows missing from Mappings()")
+		return MappingInfo{}
+	}
+
+	if err := Register(sql.ErrNoRows, KeyInternalError, http.StatusInternalServerError); err == nil {
+		t.Error("Register() of a built-in mapping = nil, want an error")
+	}
+	if err := RegisterOverride(sql.ErrNoRows, KeyInternalError, http.StatusInternalServerError); err != nil {
+		t.Fatal(err)
+	}
+	if w, body := serve(t, returning(Wrap(sql.ErrNoRows, "table", "posts")), WithLogging(false)); w.Code != http.StatusInternalServerError || body.Code != string(KeyInternalError) {
+		t.Errorf("response = %d %s after override, want 500 %s", w.Code, body.Code, KeyInternalError)
+	}
+	if info := noRowsInfo(); info.Code != KeyInternalError || info.Status != http.StatusInternalServerError {
+		t.Errorf("exported mapping = %+v after override, want 500 %s", info, KeyInternalError)
+	}
+
+	if err := RegisterOverride(sql.ErrNoRows, KeyNotFound, http.StatusNotFound); err != nil {
+		t.Fatal(err)
+	}
+	if got := StatusOf(sql.ErrNoRows); got != http.StatusNotFound {
+		t.Errorf("StatusOf() = %d after restoring, want %d", got, http.StatusNotFound)
+	}
+	if info := noRowsInfo(); info.Code != KeyNotFound || info.Status != http.StatusNotFound {
+		t.Errorf("exported mapping = %+v after restoring, want 404 %s", info, KeyNotFound)
+	}
+}
+
+func TestFromCodeRoundTrip(t *testing.T) {
+	for _, code := range Codes() {
+		t.Run(string(code), func(t *testing.T) {
+			err := FromCode(code)
+			if _, unknown := err.(*UnknownCodeError); unknown {
+				t.Fatalf("FromCode() = %v, want a known error", err)
+			}
+			if got := Code(err); got != code {
+				t.Errorf("Code() = %s, want %s", got, code)
+			}
+			if again := FromCode(Code(err)); again != err {
+				t.Errorf("FromCode(Code()) = %v, want %v", again, err)
+			}
+			mapping, _ := Mapping(code)
+			if got := StatusOf(err); got != mapping.StatusCode {
+				t.Errorf("StatusOf() = %d, want %d", got, mapping.StatusCode)
+			}
+			w, body := serve(t, returning(Wrap(err, "k", "v")), WithLogging(false))
+			if w.Code != mapping.StatusCode || body.Code != string(code) {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, mapping.StatusCode, code)
+			}
+		})
+	}
+}
+
+func TestFromCodeUnknown(t *testing.T) {
+	err := FromCode("QUOTA_EXCEEDED")
+	if _, ok := err.(*UnknownCodeError); !ok {
+		t.Fatalf("FromCode() = %T, want *UnknownCodeError", err)
+	}
+	if got := Code(err); got != "QUOTA_EXCEEDED" {
+		t.Errorf("Code() = %s, want QUOTA_EXCEEDED", got)
+	}
+	if got := StatusOf(err); got != http.StatusInternalServerError {
+		t.Errorf("StatusOf() = %d, want %d", got, http.StatusInternalServerError)
+	}
+	if _, body := serve(t, returning(err), WithLogging(false)); body.Code != "QUOTA_EXCEEDED" {
+		t.Errorf("code = %s, want QUOTA_EXCEEDED re-emitted", body.Code)
+	}
+}
+
+func TestRegisterThroughHandle(t *testing.T) {
+	errPostLocked := fmt.Errorf("post is locked")
+	if err := Register(errPostLocked, "TEST_POST_LOCKED", http.StatusLocked); err != nil {
+		t.Fatal(err)
+	}
+	err := Wrap(fmt.Errorf("editing post: %w", errPostLocked), "post_id", 7)
+	w, body := serve(t, returning(err), WithLogging(false))
+	if w.Code != http.StatusLocked || body.Code != "TEST_POST_LOCKED" {
+		t.Errorf("response = %d %s, want %d TEST_POST_LOCKED", w.Code, body.Code, http.StatusLocked)
+	}
+	if body.Details["post_id"] != float64(7) {
+		t.Errorf("details = %v, want post_id=7", body.Details)
+	}
+}
+
+func TestRegisterRejects(t *testing.T) {
+	errAlreadyFollowing := fmt.Errorf("already following")
+	MustRegister(errAlreadyFollowing, "TEST_ALREADY_FOLLOWING", http.StatusConflict)
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"nil error", nil, http.StatusConflict},
+		{"invalid status", fmt.Errorf("unregistered"), 42},
+		{"registered twice", errAlreadyFollowing, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Register(tt.err, "TEST_REJECTED", tt.status); err == nil {
+				t.Error("Register() = nil, want an error")
+			}
+		})
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("MustRegister() of a registered error didn't panic")
+		}
+	}()
+	MustRegister(errAlreadyFollowing, "TEST_ALREADY_FOLLOWING", http.StatusConflict)
+}
+
+// quotaError is a typed error family classified by matchers.
+type quotaError struct {
+	Kind string
+}
+
+func (e *quotaError) Error() string { return "quota exceeded: " + e.Kind }
+
+// matcherPanic makes the panicking matcher in TestRegisterMatcher panic.
+type matcherPanic struct{}
+
+func (matcherPanic) Error() string { return "matcher panic trigger" }
+
+func TestRegisterMatcher(t *testing.T) {
+	matchKind := func(kind string, mapping ErrorMapping) Matcher {
+		return func(err error) (ErrorMapping, bool) {
+			if q, ok := err.(*quotaError); ok && q.Kind == kind {
+				return mapping, true
+			}
+			return ErrorMapping{}, false
+		}
+	}
+	for _, m := range []Matcher{
+		func(err error) (ErrorMapping, bool) {
+			if _, ok := err.(matcherPanic); ok {
+				panic("broken matcher")
+			}
+			return ErrorMapping{}, false
+		},
+		matchKind("posts", ErrorMapping{"TEST_POST_QUOTA", http.StatusConflict}),
+		matchKind("posts", ErrorMapping{"TEST_LATER_POST_QUOTA", http.StatusTooManyRequests}),
+		func(err error) (ErrorMapping, bool) {
+			if _, ok := err.(matcherPanic); ok {
+				return ErrorMapping{"TEST_MATCHED_AFTER_PANIC", http.StatusConflict}, true
+			}
+			return ErrorMapping{}, false
+		},
+	} {
+		if err := RegisterMatcher(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := Wrap(fmt.Errorf("creating post: %w", &quotaError{Kind: "posts"}), "user_id", 42)
+	w, body := serve(t, returning(err), WithLogging(false))
+	if w.Code != http.StatusConflict || body.Code != "TEST_POST_QUOTA" {
+		t.Errorf("response = %d %s, want the first matcher's 409 TEST_POST_QUOTA", w.Code, body.Code)
+	}
+	if got := StatusOf(&quotaError{Kind: "uploads"}); got != http.StatusInternalServerError {
+		t.Errorf("StatusOf(unmatched) = %d, want %d", got, http.StatusInternalServerError)
+	}
+
+	var code ErrorCode
+	logs := captureLogs(t, func() {
+		code = Code(fmt.Errorf("wrapped: %w", matcherPanic{}))
+	})
+	if code != "TEST_MATCHED_AFTER_PANIC" {
+		t.Errorf("Code() = %s, want the panicking matcher skipped", code)
+	}
+	if !strings.Contains(logs, "broken matcher") {
+		t.Errorf("logs = %q, want the matcher panic logged", logs)
+	}
+}
+
+// BenchmarkLookupMapping compares the guarded registry lookup with a bare read of the
+// same map, as done before the registry was made safe for concurrent use.
+func BenchmarkLookupMapping(b *testing.B) {
+	err := ErrorNotFound
+	b.Run("guarded", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			lookupMapping(err)
+		}
+	})
+	b.Run("unguarded", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = errorMappings[err]
+		}
+	})
+	b.Run("guarded parallel", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				lookupMapping(err)
+			}
+		})
+	})
+}
+
+// BenchmarkStatusOf measures a full resolution of a wrapped sentinel.
+func BenchmarkStatusOf(b *testing.B) {
+	err := Wrap(fmt.Errorf("loading: %w", ErrorNotFound), "k", "v")
+	for i := 0; i < b.N; i++ {
+		StatusOf(err)
+	}
+}
+
+func TestRegisterAliasChain(t *testing.T) {
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(codeAliases, "TEST_QUOTA_V1")
+		delete(codeAliases, "TEST_QUOTA_V2")
+	})
+	if err := RegisterAlias("TEST_QUOTA_V1", "TEST_QUOTA_V2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterAlias("TEST_QUOTA_V2", "TEST_QUOTA_V3"); err != nil {
+		t.Fatal(err)
+	}
+	for _, alias := range [][2]ErrorCode{{"TEST_QUOTA_V3", "TEST_QUOTA_V1"}, {"TEST_QUOTA_V1", "TEST_OTHER"}, {"TEST_SAME", "TEST_SAME"}, {"", "TEST_QUOTA_V1"}} {
+		if err := RegisterAlias(alias[0], alias[1]); err == nil {
+			t.Errorf("RegisterAlias(%s, %s) = nil, want an error", alias[0], alias[1])
+		}
+	}
+
+	err := Wrap(New("TEST_QUOTA_V1", http.StatusPaymentRequired, "quota exceeded"), "plan", "free")
+	if got := Code(err); got != "TEST_QUOTA_V1" {
+		t.Errorf("Code() = %s, want the unaliased TEST_QUOTA_V1", got)
+	}
+	legacyHeader := func(ctx *gin.Context) bool { return ctx.GetHeader("X-API-Version") == "1" }
+	tests := []struct {
+		name       string
+		opts       []Option
+		apiVersion string
+		want       string
+		wantLog    bool
+	}{
+		{"current", nil, "", "TEST_QUOTA_V3", false},
+		{"legacy", []Option{WithLegacyCodes(true)}, "", "TEST_QUOTA_V1", true},
+		{"legacy func, old client", []Option{WithLegacyCodesFunc(legacyHeader)}, "1", "TEST_QUOTA_V1", true},
+		{"legacy func, new client", []Option{WithLegacyCodesFunc(legacyHeader)}, "2", "TEST_QUOTA_V3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("X-API-Version", tt.apiVersion)
+			var body HttpError
+			logs := captureLogs(t, func() {
+				_, body = serveRequest(t, req, returning(err), tt.opts...)
+			})
+			if body.Code != tt.want {
+				t.Errorf("code = %s, want %s", body.Code, tt.want)
+			}
+			if got := strings.Contains(logs, "deprecated code served"); got != tt.wantLog {
+				t.Errorf("deprecation logged = %t, want %t: %s", got, tt.wantLog, logs)
+			}
+		})
+	}
+}
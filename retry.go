@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithRetryAfter wraps an error as retryable after d. The delay is sent as a Retry-After
+// header when positive; zero or negative durations mark the error retryable without a header.
+func WithRetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	retryable := true
+	if d < 0 {
+		d = 0
+	}
+	return &AppError{
+		cause:      err,
+		retryable:  &retryable,
+		retryAfter: d,
+		data:       make(map[string]any),
+	}
+}
+
+// WithRetryable wraps an error with an explicit retryable flag that overrides the
+// status-based default.
+func WithRetryable(err error, retryable bool) error {
+	if err == nil {
+		return nil
+	}
+	return &AppError{
+		cause:     err,
+		retryable: &retryable,
+		data:      make(map[string]any),
+	}
+}
+
+// IsRetryable reports whether the caller may retry the operation that produced err.
+// The outermost explicit flag in the chain wins; otherwise errors classified as retryable,
+// errors carrying a retry delay and errors mapping to 429 or 503 are retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	return isRetryable(err, StatusOf(err))
+}
+
+// isRetryable is IsRetryable with the resolved status of err already known.
+func isRetryable(err error, status int) bool {
+	var flag *bool
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok {
+			flag = appErr.retryable
+		}
+		return flag == nil
+	})
+	if flag != nil {
+		return *flag
+	}
+	if c, ok := classification(err); ok && c.Retryable {
+		return true
+	}
+	if RetryAfter(err) > 0 {
+		return true
+	}
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// RetryAfter returns the outermost retry delay in err's chain, set via WithRetryAfter
+// or the retry_after wrap key, or zero when none is set.
+func RetryAfter(err error) time.Duration {
+	var d time.Duration
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok {
+			if appErr.retryAfter > 0 {
+				d = appErr.retryAfter
+			} else if value, exists := appErr.data[WrapKeyRetryAfter]; exists {
+				d, _ = retryAfterValue(value)
+			}
+		}
+		return d <= 0
+	})
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// retryAfterValue converts a retry_after wrap value into a duration.
+func retryAfterValue(value any) (time.Duration, bool) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, true
+	case int:
+		return time.Duration(v) * time.Second, true
+	}
+	return 0, false
+}
@@ -0,0 +1,56 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		wantHeader string
+		wantDelay  time.Duration
+	}{
+		{"whole seconds", 2 * time.Minute, "120", 2 * time.Minute},
+		{"rounded up", 100 * time.Millisecond, "1", 100 * time.Millisecond},
+		{"zero", 0, "", 0},
+		{"negative", -time.Second, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("saving draft: %w", WithRetryAfter(ErrorConflict, tt.retryAfter))
+			if !IsRetryable(err) {
+				t.Error("IsRetryable() = false, want true")
+			}
+			if got := RetryAfter(err); got != tt.wantDelay {
+				t.Errorf("RetryAfter() = %s, want %s", got, tt.wantDelay)
+			}
+			w, body := serve(t, returning(err), WithLogging(false))
+			if w.Code != http.StatusConflict {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantHeader {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantHeader)
+			}
+			if body.Details["retryable"] != true {
+				t.Errorf("details = %v, want retryable=true", body.Details)
+			}
+		})
+	}
+}
+
+func TestWithRetryAfterOutermostWins(t *testing.T) {
+	err := WithRetryAfter(Wrap(WithRetryAfter(ErrorUnavailable, time.Minute), "region", "eu"), 5*time.Second)
+	if got := RetryAfter(err); got != 5*time.Second {
+		t.Errorf("RetryAfter() = %s, want 5s", got)
+	}
+	if WithRetryAfter(nil, time.Second) != nil {
+		t.Error("WithRetryAfter(nil) != nil")
+	}
+	if IsRetryable(WithRetryable(WithRetryAfter(ErrorConflict, time.Second), false)) {
+		t.Error("IsRetryable() = true under WithRetryable(false), want false")
+	}
+}
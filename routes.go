@@ -0,0 +1,71 @@
+package errors
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NoRoute returns a gin handler for router.NoRoute that answers unknown paths with a
+// NOT_FOUND HttpError instead of gin's plain-text 404.
+func NoRoute(opts ...Option) gin.HandlerFunc {
+	configs := newConfigCache(opts)
+	return func(ctx *gin.Context) {
+		handleError(ctx, routeNotFound(ctx.Request), configs.get())
+	}
+}
+
+// NoMethod returns a gin handler for router.NoMethod that answers requests with an
+// unsupported method with a METHOD_NOT_ALLOWED HttpError listing the allowed methods.
+// Gin only calls it when Engine.HandleMethodNotAllowed is true, and sets the Allow
+// header itself before doing so.
+func NoMethod(opts ...Option) gin.HandlerFunc {
+	configs := newConfigCache(opts)
+	return func(ctx *gin.Context) {
+		handleError(ctx, methodNotAllowed(ctx.Request, ctx.Writer.Header()), configs.get())
+	}
+}
+
+// NotFoundHandler is NoRoute for net/http routers.
+func NotFoundHandler(opts ...Option) http.HandlerFunc {
+	return WrapHandler(func(w http.ResponseWriter, r *http.Request) error {
+		return routeNotFound(r)
+	}, opts...)
+}
+
+// MethodNotAllowedHandler is NoMethod for net/http routers. The allowed methods are only
+// listed when the router has set the Allow header before calling it.
+func MethodNotAllowedHandler(opts ...Option) http.HandlerFunc {
+	return WrapHandler(func(w http.ResponseWriter, r *http.Request) error {
+		return methodNotAllowed(r, w.Header())
+	}, opts...)
+}
+
+// Router is a router with replaceable 404 and 405 handlers, such as a chi.Router.
+type Router interface {
+	NotFound(h http.HandlerFunc)
+	MethodNotAllowed(h http.HandlerFunc)
+}
+
+// MountRouteHandlers sets NotFoundHandler and MethodNotAllowedHandler as router's 404 and
+// 405 handlers. Chi passes them on to its subrouters.
+func MountRouteHandlers(router Router, opts ...Option) {
+	router.NotFound(NotFoundHandler(opts...))
+	router.MethodNotAllowed(MethodNotAllowedHandler(opts...))
+}
+
+// routeNotFound returns the error for a request to an unknown path.
+func routeNotFound(r *http.Request) error {
+	return WithPublicMessage(Wrap(ErrorNotFound, "path", r.URL.Path), "route not found")
+}
+
+// methodNotAllowed returns the error for a request with an unsupported method, listing
+// the methods of the Allow header in header.
+func methodNotAllowed(r *http.Request, header http.Header) error {
+	keyValues := []any{"method", r.Method}
+	if allow := header.Get("Allow"); allow != "" {
+		keyValues = append(keyValues, "allowed", strings.Split(allow, ", "))
+	}
+	return Wrap(ErrorMethodNotAllowed, keyValues...)
+}
@@ -0,0 +1,71 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNoRouteNoMethod(t *testing.T) {
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(NoRoute(WithLogging(false)))
+	router.NoMethod(NoMethod(WithLogging(false)))
+	router.GET("/posts", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	router.POST("/posts", func(ctx *gin.Context) { ctx.Status(http.StatusCreated) })
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		wantCode    ErrorCode
+		wantAllow   string
+		wantDetails map[string]any
+	}{
+		{"unknown path", http.MethodGet, "/users", http.StatusNotFound, KeyNotFound, "", map[string]any{"path": "/users"}},
+		{"wrong method", http.MethodDelete, "/posts", http.StatusMethodNotAllowed, KeyMethodNotAllowed, "GET, POST", map[string]any{"method": "DELETE", "allowed": []any{"GET", "POST"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, withTrace(httptest.NewRequest(tt.method, tt.path, nil)))
+			var body HttpError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+			}
+			if w.Code != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if body.RequestID != testTraceID {
+				t.Errorf("request_id = %q, want %q", body.RequestID, testTraceID)
+			}
+			if !reflect.DeepEqual(body.Details, tt.wantDetails) {
+				t.Errorf("details = %v, want %v", body.Details, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowedHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Allow", "GET")
+	MethodNotAllowedHandler(WithLogging(false))(w, httptest.NewRequest(http.MethodPut, "/posts", nil))
+	var body HttpError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusMethodNotAllowed || body.Code != string(KeyMethodNotAllowed) {
+		t.Errorf("response = %d %s, want 405 %s", w.Code, body.Code, KeyMethodNotAllowed)
+	}
+	if want := []any{"GET"}; !reflect.DeepEqual(body.Details["allowed"], want) {
+		t.Errorf("details = %v, want allowed=%v", body.Details, want)
+	}
+}
@@ -0,0 +1,125 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// RPCCode is a canonical RPC status code, as defined by google.rpc.Code. gRPC's
+// codes.Code and Connect's connect.Code use the same values, so an RPCCode converts to
+// either.
+type RPCCode uint32
+
+// The canonical RPC status codes.
+const (
+	RPCCodeOK RPCCode = iota
+	RPCCodeCanceled
+	RPCCodeUnknown
+	RPCCodeInvalidArgument
+	RPCCodeDeadlineExceeded
+	RPCCodeNotFound
+	RPCCodeAlreadyExists
+	RPCCodePermissionDenied
+	RPCCodeResourceExhausted
+	RPCCodeFailedPrecondition
+	RPCCodeAborted
+	RPCCodeOutOfRange
+	RPCCodeUnimplemented
+	RPCCodeInternal
+	RPCCodeUnavailable
+	RPCCodeDataLoss
+	RPCCodeUnauthenticated
+)
+
+// RPCMetadataKeyRequestID is the RPCMetadata key holding the request ID.
+const RPCMetadataKeyRequestID = "request_id"
+
+// rpcCodes maps error codes whose meaning is more specific than their HTTP status to RPC
+// codes. Other codes are mapped by status with statusRPCCodes.
+var rpcCodes = map[ErrorCode]RPCCode{
+	KeyDuplicateEntry:       RPCCodeAlreadyExists,
+	KeyConflict:             RPCCodeAborted,
+	KeyNotAllowed:           RPCCodeFailedPrecondition,
+	KeyInsufficientQuota:    RPCCodeResourceExhausted,
+	KeyDatabaseUnavailable:  RPCCodeUnavailable,
+	KeyClientClosedRequest:  RPCCodeCanceled,
+	KeyPreconditionFailed:   RPCCodeFailedPrecondition,
+	KeyPreconditionRequired: RPCCodeFailedPrecondition,
+}
+
+// statusRPCCodes maps HTTP statuses to RPC codes.
+var statusRPCCodes = map[int]RPCCode{
+	http.StatusBadRequest:                  RPCCodeInvalidArgument,
+	http.StatusUnauthorized:                RPCCodeUnauthenticated,
+	http.StatusForbidden:                   RPCCodePermissionDenied,
+	http.StatusNotFound:                    RPCCodeNotFound,
+	http.StatusMethodNotAllowed:            RPCCodeUnimplemented,
+	http.StatusConflict:                    RPCCodeAborted,
+	http.StatusGone:                        RPCCodeNotFound,
+	http.StatusPreconditionFailed:          RPCCodeFailedPrecondition,
+	http.StatusRequestEntityTooLarge:       RPCCodeInvalidArgument,
+	http.StatusUnsupportedMediaType:        RPCCodeInvalidArgument,
+	http.StatusUnprocessableEntity:         RPCCodeInvalidArgument,
+	http.StatusPreconditionRequired:        RPCCodeFailedPrecondition,
+	http.StatusTooManyRequests:             RPCCodeResourceExhausted,
+	http.StatusRequestHeaderFieldsTooLarge: RPCCodeInvalidArgument,
+	StatusClientClosedRequest:              RPCCodeCanceled,
+	http.StatusInternalServerError:         RPCCodeInternal,
+	http.StatusNotImplemented:              RPCCodeUnimplemented,
+	http.StatusBadGateway:                  RPCCodeUnavailable,
+	http.StatusServiceUnavailable:          RPCCodeUnavailable,
+	http.StatusGatewayTimeout:              RPCCodeDeadlineExceeded,
+}
+
+// RPCCodeFor returns the RPC code sent for an error with the given code and HTTP status:
+//
+//	DUPLICATE_ENTRY                                -> AlreadyExists
+//	CONFLICT, 409                                  -> Aborted
+//	ACTION_NOT_ALLOWED, PRECONDITION_*, 412, 428   -> FailedPrecondition
+//	INSUFFICIENT_QUOTA, 429                        -> ResourceExhausted
+//	400, 413, 415, 422, 431, other 4xx             -> InvalidArgument
+//	401                                            -> Unauthenticated
+//	403                                            -> PermissionDenied
+//	404, 410                                       -> NotFound
+//	405, 501                                       -> Unimplemented
+//	499                                            -> Canceled
+//	DATABASE_UNAVAILABLE, 502, 503                 -> Unavailable
+//	504                                            -> DeadlineExceeded
+//	500, other 5xx                                 -> Internal
+func RPCCodeFor(code ErrorCode, status int) RPCCode {
+	if rpcCode, exists := rpcCodes[code]; exists {
+		return rpcCode
+	}
+	if rpcCode, exists := statusRPCCodes[status]; exists {
+		return rpcCode
+	}
+	if status < http.StatusInternalServerError {
+		return RPCCodeInvalidArgument
+	}
+	return RPCCodeInternal
+}
+
+// RPCMetadata returns the details and request ID of body as string metadata, such as for
+// a google.rpc.ErrorInfo. Non-string values are JSON-encoded.
+func RPCMetadata(body *HttpError) map[string]string {
+	metadata := make(map[string]string, len(body.Details)+1)
+	for key, value := range body.Details {
+		metadata[key] = rpcMetadataValue(value)
+	}
+	if body.RequestID != "" {
+		metadata[RPCMetadataKeyRequestID] = body.RequestID
+	}
+	return metadata
+}
+
+// rpcMetadataValue renders a detail value as metadata, which only holds strings.
+func rpcMetadataValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	if encoded, err := json.Marshal(value); err == nil {
+		return string(encoded)
+	}
+	return fmt.Sprint(value)
+}
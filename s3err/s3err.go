@@ -0,0 +1,77 @@
+// Package s3err maps Amazon S3 errors from the AWS SDK for Go v2 to semantic HTTP errors,
+// so that a missing object is a 404 rather than a 500. It only depends on smithy-go, whose
+// APIError every SDK service error implements.
+package s3err
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/A-pen-app/errors"
+	"github.com/aws/smithy-go"
+)
+
+// DetailKeyObjectKey is the detail holding the object key set by WithKey.
+const DetailKeyObjectKey = "object_key"
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Register teaches the errors package about S3 API errors, anywhere in the chain, by
+// error code:
+//
+//	NoSuchKey, NoSuchBucket, NotFound          -> 404 NOT_FOUND
+//	PreconditionFailed                         -> 412 PRECONDITION_FAILED
+//	AccessDenied, InvalidAccessKeyId, ...      -> 500 INTERNAL_ERROR
+//
+// Authorization failures stay 500, as they mean the service credentials are misconfigured
+// rather than that the client may not see the object. Calling Register more than once has
+// no further effect.
+func Register() error {
+	registerOnce.Do(func() {
+		registerErr = errors.RegisterTypeClassifier(classify)
+	})
+	return registerErr
+}
+
+// WithKey adds the object key to err's details, so that not-found and precondition
+// responses say which object was meant. Bucket names and presigned URLs should not be
+// passed.
+func WithKey(err error, key string) error {
+	return errors.Wrap(err, DetailKeyObjectKey, key)
+}
+
+// authErrorCodes are the S3 error codes of credential and authorization failures.
+var authErrorCodes = map[string]bool{
+	"AccessDenied":          true,
+	"InvalidAccessKeyId":    true,
+	"SignatureDoesNotMatch": true,
+	"ExpiredToken":          true,
+	"InvalidToken":          true,
+}
+
+// classify maps an S3 API error by its code.
+func classify(err smithy.APIError) (errors.Classification, bool) {
+	switch code := err.ErrorCode(); {
+	case code == "NoSuchKey", code == "NoSuchBucket", code == "NotFound":
+		return errors.Classification{
+			Code:    errors.KeyNotFound,
+			Status:  http.StatusNotFound,
+			Message: "object not found",
+		}, true
+	case code == "PreconditionFailed":
+		return errors.Classification{
+			Code:    errors.KeyPreconditionFailed,
+			Status:  http.StatusPreconditionFailed,
+			Message: "object was modified",
+		}, true
+	case authErrorCodes[code]:
+		return errors.Classification{
+			Code:   errors.KeyInternalError,
+			Status: http.StatusInternalServerError,
+		}, true
+	}
+	return errors.Classification{}, false
+}
@@ -0,0 +1,58 @@
+package s3err
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/A-pen-app/errors"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestMain(m *testing.M) {
+	if err := Register(); err != nil {
+		panic(err)
+	}
+	m.Run()
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   errors.ErrorCode
+		wantStatus int
+	}{
+		{"no such key", &types.NoSuchKey{}, errors.KeyNotFound, http.StatusNotFound},
+		{"no such bucket", &types.NoSuchBucket{}, errors.KeyNotFound, http.StatusNotFound},
+		{"head object not found", &smithy.GenericAPIError{Code: "NotFound"}, errors.KeyNotFound, http.StatusNotFound},
+		{"precondition failed", &smithy.GenericAPIError{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}, errors.KeyPreconditionFailed, http.StatusPreconditionFailed},
+		{"access denied", &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}, errors.KeyInternalError, http.StatusInternalServerError},
+		{"expired token", &smithy.GenericAPIError{Code: "ExpiredToken"}, errors.KeyInternalError, http.StatusInternalServerError},
+		{"other code", &types.InvalidObjectState{}, errors.KeyInternalError, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Wrap(fmt.Errorf("operation error S3: GetObject: %w", tt.err))
+			if got := errors.Code(err); got != tt.wantCode {
+				t.Errorf("Code() = %s, want %s", got, tt.wantCode)
+			}
+			if got := errors.StatusOf(err); got != tt.wantStatus {
+				t.Errorf("StatusOf() = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWithKey(t *testing.T) {
+	err := WithKey(fmt.Errorf("reading avatar: %w", &types.NoSuchKey{}), "avatars/7.png")
+	resp := errors.ResponseFor(httptest.NewRequest(http.MethodGet, "/avatars/7", nil), err, errors.WithLogging(false))
+	if resp.Status != http.StatusNotFound || resp.Body.Message != "object not found" {
+		t.Errorf("response = %d %q, want 404 %q", resp.Status, resp.Body.Message, "object not found")
+	}
+	if got := resp.Body.Details[DetailKeyObjectKey]; got != "avatars/7.png" {
+		t.Errorf("%s = %v, want %q", DetailKeyObjectKey, got, "avatars/7.png")
+	}
+}
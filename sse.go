@@ -0,0 +1,10 @@
+package errors
+
+import "github.com/gin-gonic/gin"
+
+// SSEErrorEvent is a StreamErrorWriter that ends a server-sent event stream with an
+// "error" event whose data is the HttpError as JSON.
+func SSEErrorEvent(ctx *gin.Context, body HttpError) {
+	ctx.SSEvent("error", body)
+	ctx.Writer.Flush()
+}
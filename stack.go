@@ -0,0 +1,60 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// maxStackDepth bounds the number of frames recorded for an error.
+const maxStackDepth = 32
+
+// stack holds the program counters of the calls leading to where an error was created.
+type stack []uintptr
+
+// callers returns the stack of the function calling the caller of callers, skipping
+// skip further frames.
+func callers(skip int) stack {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(3+skip, pcs[:])
+	return append(stack(nil), pcs[:n]...)
+}
+
+// stackFor returns the stack of the caller of the function calling it, or nil when an
+// AppError in err's chain already records one, which is closer to the failure.
+func stackFor(err error) stack {
+	if stackOf(err) != nil {
+		return nil
+	}
+	return callers(1)
+}
+
+// stackOf returns the innermost stack recorded in err's chain.
+func stackOf(err error) stack {
+	var s stack
+	walkErrors(err, func(err error) bool {
+		if appErr, ok := err.(*AppError); ok && appErr.stack != nil {
+			s = appErr.stack
+		}
+		return true
+	})
+	return s
+}
+
+// String renders the stack like a panic does: one frame per line, with the function
+// followed by its indented file and line.
+func (s stack) String() string {
+	if len(s) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	frames := runtime.CallersFrames(s)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
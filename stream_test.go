@@ -0,0 +1,91 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streaming returns a handler that sends two events, flushes them and then fails with err.
+func streaming(err error) HandlerFunc {
+	return func(ctx *gin.Context) error {
+		ctx.SSEvent("post", gin.H{"id": 1})
+		ctx.SSEvent("post", gin.H{"id": 2})
+		ctx.Writer.Flush()
+		return err
+	}
+}
+
+func TestStreamFailsAfterFlush(t *testing.T) {
+	const events = "event:post\ndata:{\"id\":1}\n\nevent:post\ndata:{\"id\":2}\n\n"
+	upstreamErr := Wrap(ErrorBadGateway, "upstream", "feed")
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantEvent bool
+	}{
+		{"gin stream", nil, false},
+		{"SSE error event", []Option{WithStreamErrorWriter(SSEErrorEvent)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/events", Handle(streaming(upstreamErr), tt.opts...))
+			w := httptest.NewRecorder()
+			logs := captureLogs(t, func() {
+				router.ServeHTTP(w, withTrace(httptest.NewRequest(http.MethodGet, "/events", nil)))
+			})
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want the 200 already sent", w.Code)
+			}
+			got := w.Body.String()
+			if !strings.HasPrefix(got, events) {
+				t.Fatalf("body = %q, want the flushed events first", got)
+			}
+			rest := strings.TrimPrefix(got, events)
+			if !tt.wantEvent {
+				if rest != "" {
+					t.Errorf("body ends with %q, want nothing after the events", rest)
+				}
+			} else {
+				data, found := strings.CutPrefix(rest, "event:error\ndata:")
+				if !found {
+					t.Fatalf("body ends with %q, want an error event", rest)
+				}
+				var body HttpError
+				if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &body); err != nil {
+					t.Fatalf("decoding error event %q: %v", data, err)
+				}
+				if body.Code != string(KeyBadGateway) || body.RequestID != testTraceID {
+					t.Errorf("error event = %+v, want %s with request_id %s", body, KeyBadGateway, testTraceID)
+				}
+			}
+			if line := logLine(t, logs, "bad gateway"); !strings.Contains(line, `"labels.response_started": "true"`) {
+				t.Errorf("log line = %s, want response_started", line)
+			}
+		})
+	}
+}
+
+func TestStreamClientGone(t *testing.T) {
+	written := false
+	router := gin.New()
+	router.GET("/events", Handle(streaming(context.Canceled), WithLogging(false), WithStreamErrorWriter(func(*gin.Context, HttpError) {
+		written = true
+	})))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx))
+	if written {
+		t.Error("stream error writer called for a client that went away")
+	}
+	if strings.Contains(w.Body.String(), "error") {
+		t.Errorf("body = %q, want only the events", w.Body.String())
+	}
+}
@@ -0,0 +1,127 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DetailKeyUpstream is the details key marking errors received from another service.
+	DetailKeyUpstream = "upstream"
+	// DetailKeyUpstreamStatus is the details key holding the upstream status of an
+	// UpstreamError whose status wasn't allowed to pass through.
+	DetailKeyUpstreamStatus = "upstream_status"
+)
+
+// maxUpstreamBodyBytes bounds how much of an upstream error response is read.
+const maxUpstreamBodyBytes = 1 << 20
+
+// UpstreamError is an error response received from another service. When handled, its
+// status, code, message and details pass straight through to the client, unless the
+// passthrough filter set by SetUpstreamPassthrough rejects the status, in which case it is
+// reported as 502 BAD_GATEWAY.
+type UpstreamError struct {
+	Status  int
+	Code    ErrorCode
+	Message string
+	Details map[string]any
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("upstream responded %d %s", e.Status, e.Code)
+	}
+	return fmt.Sprintf("upstream responded %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// FromUpstreamResponse returns an *UpstreamError for an error response from another
+// service, decoding the body when it is in this package's HttpError format, and nil for
+// statuses below 400. Bodies in other formats leave the code derived from the status. The
+// body is read but not closed.
+func FromUpstreamResponse(resp *http.Response) error {
+	if resp == nil || resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	upstreamErr := &UpstreamError{Status: resp.StatusCode, Code: CodeForStatus(resp.StatusCode)}
+	if resp.Body == nil || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return upstreamErr
+	}
+	var body HttpError
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxUpstreamBodyBytes)).Decode(&body); err != nil {
+		return upstreamErr
+	}
+	if body.Code != "" {
+		upstreamErr.Code = ErrorCode(body.Code)
+	}
+	upstreamErr.Message = body.Message
+	upstreamErr.Details = body.Details
+	return upstreamErr
+}
+
+// defaultUpstreamPassthrough lets every upstream status through except 401 and 407, which
+// mean this service's own credentials were rejected rather than the client's.
+func defaultUpstreamPassthrough(status int) bool {
+	return status != http.StatusUnauthorized && status != http.StatusProxyAuthRequired
+}
+
+// upstreamPassthrough holds the filter set by SetUpstreamPassthrough, guarded by configMu.
+var upstreamPassthrough = defaultUpstreamPassthrough
+
+// SetUpstreamPassthrough sets which upstream statuses an UpstreamError may pass through to
+// the client; rejected statuses are reported as 502 BAD_GATEWAY. By default every status
+// passes except 401 and 407. A nil filter restores the default.
+func SetUpstreamPassthrough(allow func(status int) bool) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if allow == nil {
+		allow = defaultUpstreamPassthrough
+	}
+	upstreamPassthrough = allow
+}
+
+// passesThrough reports whether the upstream status may be sent to the client.
+func passesThrough(status int) bool {
+	configMu.RLock()
+	allow := upstreamPassthrough
+	configMu.RUnlock()
+	return isValidStatus(status) && allow(status)
+}
+
+// upstreamClassification returns the classification of the outermost UpstreamError in
+// err's chain.
+func upstreamClassification(err error) (Classification, bool) {
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		return Classification{}, false
+	}
+	if !passesThrough(upstreamErr.Status) {
+		return Classification{
+			Code:    KeyBadGateway,
+			Status:  http.StatusBadGateway,
+			Details: map[string]any{DetailKeyUpstream: true, DetailKeyUpstreamStatus: upstreamErr.Status},
+		}, true
+	}
+	details := make(map[string]any, len(upstreamErr.Details)+1)
+	for key, value := range upstreamErr.Details {
+		details[key] = value
+	}
+	details[DetailKeyUpstream] = true
+	code := upstreamErr.Code
+	if code == "" {
+		code = CodeForStatus(upstreamErr.Status)
+	}
+	message := upstreamErr.Message
+	if message == "" {
+		message = defaultMessage(ErrorMapping{code, upstreamErr.Status})
+	}
+	return Classification{
+		Code:    code,
+		Status:  upstreamErr.Status,
+		Message: message,
+		Details: details,
+	}, true
+}
@@ -0,0 +1,74 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getUpstream serves fn with Handle on a test server standing in for another service,
+// and returns the error FromUpstreamResponse makes of its response.
+func getUpstream(t *testing.T, fn HandlerFunc) error {
+	t.Helper()
+	router := gin.New()
+	router.GET("/posts/7", Handle(fn, WithLogging(false)))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/posts/7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	return FromUpstreamResponse(resp)
+}
+
+func TestUpstreamRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{"unprefixed", "", Wrap(ErrorNotFound, "post_id", 7), http.StatusNotFound, "NOT_FOUND", "data not found"},
+		{"shared prefix", "FEED", Wrap(ErrorNotFound, "post_id", 7), http.StatusNotFound, "FEED.NOT_FOUND", "data not found"},
+		{"public message", "", WithPublicMessage(ErrorConflict, "post was edited meanwhile"), http.StatusConflict, "CONFLICT", "post was edited meanwhile"},
+		{"rejected credentials", "", ErrorUnauthorized, http.StatusBadGateway, "BAD_GATEWAY", "bad gateway"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetCodePrefix(tt.prefix)
+			t.Cleanup(func() { SetCodePrefix("") })
+
+			err := getUpstream(t, returning(tt.err))
+			w, body := serve(t, returning(Wrapf(err, "loading post")), WithLogging(false))
+			if w.Code != tt.wantStatus || body.Code != tt.wantCode || body.Message != tt.wantMessage {
+				t.Errorf("response = %d %s %q, want %d %s %q", w.Code, body.Code, body.Message, tt.wantStatus, tt.wantCode, tt.wantMessage)
+			}
+			if body.Details[DetailKeyUpstream] != true {
+				t.Errorf("details = %v, want %s=true", body.Details, DetailKeyUpstream)
+			}
+		})
+	}
+}
+
+func TestUpstreamRoundTripDetails(t *testing.T) {
+	err := getUpstream(t, returning(Wrap(ErrorNotFound, "post_id", 7)))
+	_, body := serve(t, returning(err), WithLogging(false))
+	if body.Details["post_id"] != float64(7) {
+		t.Errorf("details = %v, want the upstream post_id=7", body.Details)
+	}
+}
+
+func TestFromUpstreamResponseWithoutErrorBody(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Content-Type": {"text/html"}}}
+	if got := Code(FromUpstreamResponse(resp)); got != KeyServiceUnavailable {
+		t.Errorf("Code() = %s, want %s derived from the status", got, KeyServiceUnavailable)
+	}
+	if err := FromUpstreamResponse(&http.Response{StatusCode: http.StatusOK}); err != nil {
+		t.Errorf("FromUpstreamResponse(200) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,357 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldViolation describes a single failed validation rule, as sent in the "fields"
+// details of validation errors.
+type FieldViolation struct {
+	// Path locates the field from the request root, e.g. "items[0].qty".
+	Path string `json:"path"`
+	// Field is the last field name of Path, e.g. "qty".
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Value   any    `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// validationMessagesMu guards the registered message templates below.
+var validationMessagesMu sync.RWMutex
+
+var (
+	fieldMessages = map[fieldMessageKey]string{}
+	tagOverrides  = map[string]string{}
+)
+
+// fieldMessageKey identifies a field-specific message template.
+type fieldMessageKey struct {
+	namespace string
+	tag       string
+}
+
+// tagMessages holds the built-in message templates for validator tags. Templates may use the
+// {field}, {param} and {unit} placeholders, where {unit} is " characters" for strings,
+// " items" for slices, arrays and maps, and empty otherwise.
+var tagMessages = map[string]string{
+	"required":         "{field} is required",
+	"email":            "{field} must be a valid email address",
+	"url":              "{field} must be a valid URL",
+	"uri":              "{field} must be a valid URI",
+	"uuid":             "{field} must be a valid UUID",
+	"uuid4":            "{field} must be a valid UUID",
+	"alpha":            "{field} may only contain letters",
+	"alphanum":         "{field} may only contain letters and numbers",
+	"numeric":          "{field} must be numeric",
+	"oneof":            "{field} must be one of {param}",
+	"len":              "{field} must be exactly {param}{unit}",
+	"min":              "{field} must be at least {param}{unit}",
+	"max":              "{field} must be at most {param}{unit}",
+	"gt":               "{field} must be greater than {param}{unit}",
+	"gte":              "{field} must be at least {param}{unit}",
+	"lt":               "{field} must be less than {param}{unit}",
+	"lte":              "{field} must be at most {param}{unit}",
+	"eqfield":          "{field} must match {param}",
+	"nefield":          "{field} must differ from {param}",
+	"datetime":         "{field} must be a date in the format {param}",
+	"unique":           "{field} must not contain duplicates",
+	"iso3166_1_alpha2": "{field} must be a valid country code",
+}
+
+// defaultTagMessage is the template for tags without a registered message.
+const defaultTagMessage = "{field} is invalid"
+
+// RegisterTagMessage sets the message template used for failures of the validator tag,
+// replacing the built-in one and any translation. Templates may use the {field}, {param}
+// and {unit} placeholders, e.g. "{field} must be at most {param}{unit}".
+func RegisterTagMessage(tag, template string) {
+	validationMessagesMu.Lock()
+	defer validationMessagesMu.Unlock()
+	tagOverrides[tag] = template
+}
+
+// RegisterFieldMessage sets the message template used when field of the struct type named
+// structName fails the validator tag, taking precedence over RegisterTagMessage. field is
+// the Go field name, or a dotted path of Go field names for nested structs such as
+// "Author.Handle"; slice indices are ignored. Templates use the same placeholders as
+// RegisterTagMessage.
+func RegisterFieldMessage(structName, field, tag, template string) {
+	validationMessagesMu.Lock()
+	defer validationMessagesMu.Unlock()
+	fieldMessages[fieldMessageKey{structName + "." + field, tag}] = template
+}
+
+// violationMessage renders the message for a failed validation of the named field. The
+// template is the field-specific one, else the registered one for the tag, else the
+// translation by lt, else the built-in one.
+func violationMessage(fe validator.FieldError, field string, lt *localeTranslator) string {
+	template, exists := registeredTemplate(fe)
+	if !exists && lt != nil {
+		if msg := fe.Translate(lt.translator); msg != fe.Error() {
+			return msg
+		}
+	}
+	if !exists {
+		template, exists = tagMessages[fe.Tag()]
+	}
+	if !exists {
+		template = defaultTagMessage
+	}
+
+	param := fe.Param()
+	if fe.Tag() == "oneof" {
+		param = strings.Join(strings.Fields(param), ", ")
+	}
+	return strings.NewReplacer(
+		"{field}", field,
+		"{param}", param,
+		"{unit}", sizeUnit(fe.Kind()),
+	).Replace(template)
+}
+
+// registeredTemplate returns the field-specific or tag template registered for fe.
+func registeredTemplate(fe validator.FieldError) (string, bool) {
+	validationMessagesMu.RLock()
+	defer validationMessagesMu.RUnlock()
+	if template, exists := fieldMessages[fieldMessageKey{stripIndices(fe.StructNamespace()), fe.Tag()}]; exists {
+		return template, true
+	}
+	template, exists := tagOverrides[fe.Tag()]
+	return template, exists
+}
+
+// stripIndices removes slice indices and map keys from a validator namespace, e.g.
+// "Order.Items[0].Qty" becomes "Order.Items.Qty".
+func stripIndices(namespace string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range namespace {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// sizeUnit returns the unit that size params such as max and len count in for a kind.
+func sizeUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	}
+	return ""
+}
+
+// sensitiveFieldNames are substrings of field names whose values are never echoed back.
+var sensitiveFieldNames = []string{"password", "secret", "token", "credential", "apikey", "api_key"}
+
+// isSensitiveField reports whether the value of the named field must be left out of details.
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveFieldNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// validationErrors collects the entries of every validator.ValidationErrors in err's
+// chain, including the members of joined errors, in the order the validator reported
+// them, which follows the struct field order.
+func validationErrors(err error) validator.ValidationErrors {
+	var collected validator.ValidationErrors
+	walkErrors(err, func(err error) bool {
+		if errs, ok := err.(validator.ValidationErrors); ok {
+			collected = append(collected, errs...)
+		}
+		return true
+	})
+	return collected
+}
+
+// fieldViolations converts validator errors into field violations, in the order the
+// validator reported them, with echoed values limited to MaxValueLength. Field paths
+// are resolved by namer, which falls back to the names known to the validator when nil,
+// i.e. Go field names for gin's default validator, and fields hidden from clients by a
+// "-" tag name are left out. Messages are translated by lt when it is not nil.
+func fieldViolations(errs validator.ValidationErrors, namer *fieldNamer, lt *localeTranslator) []FieldViolation {
+	maxValueLength := currentDetailLimits().MaxValueLength
+	violations := make([]FieldViolation, 0, len(errs))
+	for _, fe := range errs {
+		path, visible := namer.path(fe)
+		if !visible {
+			continue
+		}
+		violation := FieldViolation{
+			Path:    path,
+			Field:   leaf(path),
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: violationMessage(fe, path, lt),
+		}
+		if !isSensitiveField(fe.Field()) && !isSensitiveField(fe.StructField()) {
+			violation.Value = limitValue(fe.Value(), maxValueLength)
+		}
+		violations = append(violations, violation)
+	}
+	return violations
+}
+
+// jsonFieldPath renders the dotted field path of a json.UnmarshalTypeError, where array
+// indices are plain segments, in JSON path form, e.g. "a.b.2.c" becomes "a.b[2].c".
+func jsonFieldPath(field string) string {
+	if field == "" {
+		return ""
+	}
+	var b strings.Builder
+	for i, segment := range strings.Split(field, ".") {
+		if isIndex(segment) {
+			b.WriteString("[" + segment + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
+
+// isIndex reports whether a path segment is an array index.
+func isIndex(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, r := range segment {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonKind names the JSON kind a Go type is decoded from.
+func jsonKind(t reflect.Type) string {
+	if t == nil {
+		return "value"
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return "value"
+}
+
+// receivedKind normalizes the Value of a json.UnmarshalTypeError, such as "bool" or
+// "number 300", to a JSON kind.
+func receivedKind(value string) string {
+	kind, _, _ := strings.Cut(value, " ")
+	if kind == "bool" {
+		return "boolean"
+	}
+	return kind
+}
+
+// withArticle prefixes a JSON kind with its indefinite article.
+func withArticle(kind string) string {
+	switch kind {
+	case "array", "object":
+		return "an " + kind
+	}
+	return "a " + kind
+}
+
+// unmarshalTypeDetails returns the details describing a json.UnmarshalTypeError.
+func unmarshalTypeDetails(err *json.UnmarshalTypeError) map[string]any {
+	return map[string]any{
+		"field":    jsonFieldPath(err.Field),
+		"expected": jsonKind(err.Type),
+		"received": receivedKind(err.Value),
+		"offset":   err.Offset,
+	}
+}
+
+// unmarshalTypeMessage returns the client-facing message for a json.UnmarshalTypeError.
+func unmarshalTypeMessage(err *json.UnmarshalTypeError) string {
+	expected := withArticle(jsonKind(err.Type))
+	if path := jsonFieldPath(err.Field); path != "" {
+		return fmt.Sprintf("field '%s' must be %s", path, expected)
+	}
+	return "request body must be " + expected
+}
+
+// bindingMessage returns a client-friendly message for binding and validation errors in
+// err's chain, which replaces their raw error strings in responses. Validation messages are
+// translated by lt when it is not nil.
+func bindingMessage(err error, lt *localeTranslator) (string, bool) {
+	if validationErrs := validationErrors(err); len(validationErrs) > 0 {
+		if violations := fieldViolations(validationErrs, fieldNamerFor(err), lt); len(violations) == 1 {
+			return violations[0].Message, true
+		}
+		return "validation failed", true
+	}
+	var unmarshalErr *json.UnmarshalTypeError
+	if errors.As(err, &unmarshalErr) {
+		return unmarshalTypeMessage(unmarshalErr), true
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return "malformed JSON", true
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, multipart.ErrMessageTooLarge) {
+		return "request body too large", true
+	}
+	if errors.Is(err, http.ErrNotMultipart) {
+		return "request body must be multipart/form-data", true
+	}
+	if errors.Is(err, http.ErrMissingBoundary) {
+		return "malformed multipart body: missing boundary", true
+	}
+	var bindingErr *BindingError
+	if errors.As(err, &bindingErr) {
+		if errors.Is(err, http.ErrMissingFile) && bindingErr.Parameter != "" {
+			return fmt.Sprintf("missing file '%s'", bindingErr.Parameter), true
+		}
+		if bindingErr.Source == binding.FormMultipart.Name() && !errors.Is(err, http.ErrMissingFile) {
+			return "malformed multipart body", true
+		}
+		if bindingErr.Parameter != "" {
+			return fmt.Sprintf("invalid %s parameter '%s'", bindingErr.Source, bindingErr.Parameter), true
+		}
+		return fmt.Sprintf("invalid %s parameters", bindingErr.Source), true
+	}
+	return "", false
+}
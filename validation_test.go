@@ -0,0 +1,423 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// postJSON returns a JSON POST request to /test.
+func postJSON(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// violationsOf decodes the "fields" details of a response body.
+func violationsOf(t *testing.T, body HttpError) []FieldViolation {
+	t.Helper()
+	data, err := json.Marshal(body.Details["fields"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var violations []FieldViolation
+	if err := json.Unmarshal(data, &violations); err != nil {
+		t.Fatalf("decoding fields %s: %v", data, err)
+	}
+	return violations
+}
+
+func TestValidationDetailsFromShouldBindJSON(t *testing.T) {
+	type createPost struct {
+		Title    string `json:"title" binding:"required"`
+		Body     string `json:"body" binding:"max=5"`
+		Rating   int    `json:"rating" binding:"oneof=1 2 3"`
+		Password string `json:"password" binding:"min=8"`
+	}
+	w, body := serveRequest(t, postJSON(`{"body": "too long", "rating": 7, "password": "short"}`), func(ctx *gin.Context) error {
+		var req createPost
+		return ctx.ShouldBindJSON(&req)
+	}, WithLogging(false))
+	if w.Code != http.StatusBadRequest || body.Code != string(KeyWrongParams) {
+		t.Fatalf("response = %d %s, want 400 %s", w.Code, body.Code, KeyWrongParams)
+	}
+	if body.Message != "validation failed" {
+		t.Errorf("message = %q, want %q", body.Message, "validation failed")
+	}
+	want := []FieldViolation{
+		{Path: "Title", Field: "Title", Tag: "required", Value: "", Message: "Title is required"},
+		{Path: "Body", Field: "Body", Tag: "max", Param: "5", Value: "too long", Message: "Body must be at most 5 characters"},
+		{Path: "Rating", Field: "Rating", Tag: "oneof", Param: "1 2 3", Value: float64(7), Message: "Rating must be one of 1, 2, 3"},
+		{Path: "Password", Field: "Password", Tag: "min", Param: "8", Message: "Password must be at least 8 characters"},
+	}
+	if got := violationsOf(t, body); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestOversizedValidationDetailsCapped(t *testing.T) {
+	type item struct {
+		Name string `json:"name" binding:"max=3"`
+	}
+	type batch struct {
+		Items []item `json:"items" binding:"dive"`
+	}
+	limits := DetailLimits{MaxFields: 20, MaxValueLength: 16, MaxDetailsBytes: 2 << 10}
+	SetDetailLimits(limits)
+	t.Cleanup(func() { SetDetailLimits(DefaultDetailLimits) })
+
+	const total = 10000
+	items := make([]string, total)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"name":%q}`, strings.Repeat("x", 100))
+	}
+	req := postJSON(`{"items":[` + strings.Join(items, ",") + `]}`)
+
+	var w *httptest.ResponseRecorder
+	var body HttpError
+	logs := captureLogs(t, func() {
+		w, body = serveRequest(t, req, func(ctx *gin.Context) error {
+			var b batch
+			return ctx.ShouldBindJSON(&b)
+		})
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	details, err := json.Marshal(body.Details)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(details) > limits.MaxDetailsBytes {
+		t.Errorf("details = %d bytes, want at most %d", len(details), limits.MaxDetailsBytes)
+	}
+	if body.Details["fields_total"] != float64(total) {
+		t.Errorf("fields_total = %v, want %d", body.Details["fields_total"], total)
+	}
+	violations := violationsOf(t, body)
+	if shown := body.Details["fields_shown"]; shown != float64(len(violations)) || len(violations) > limits.MaxFields {
+		t.Errorf("fields_shown = %v with %d fields, want at most %d", shown, len(violations), limits.MaxFields)
+	}
+	for _, v := range violations {
+		if s, _ := v.Value.(string); len(s) > limits.MaxValueLength {
+			t.Fatalf("value = %d bytes, want at most %d", len(s), limits.MaxValueLength)
+		}
+	}
+	if line := logLine(t, logs, "WRONG_PARAMETER"); len(line) > 2*limits.MaxDetailsBytes {
+		t.Errorf("log line = %d bytes, want the message capped at %d", len(line), limits.MaxDetailsBytes)
+	}
+}
+
+func TestUnmarshalTypeErrorDetails(t *testing.T) {
+	type comment struct {
+		Likes int `json:"likes"`
+	}
+	type createPost struct {
+		Age    int  `json:"age"`
+		Public bool `json:"public"`
+		Thread struct {
+			Comments []comment `json:"comments"`
+		} `json:"thread"`
+		Tags []string `json:"tags"`
+	}
+	tests := []struct {
+		name        string
+		payload     string
+		wantMessage string
+		wantField   string
+		wantKinds   [2]string
+	}{
+		{"number", `{"age": "twelve"}`, "field 'age' must be a number", "age", [2]string{"number", "string"}},
+		{"boolean", `{"public": 1}`, "field 'public' must be a boolean", "public", [2]string{"boolean", "number"}},
+		{"nested array", `{"thread": {"comments": [{}, {}, {"likes": "many"}]}}`, "field 'thread.comments[2].likes' must be a number", "thread.comments[2].likes", [2]string{"number", "string"}},
+		{"array", `{"tags": "go"}`, "field 'tags' must be an array", "tags", [2]string{"array", "string"}},
+		{"array element", `{"tags": ["go", 7]}`, "field 'tags[1]' must be a string", "tags[1]", [2]string{"string", "number"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := serveRequest(t, postJSON(tt.payload), func(ctx *gin.Context) error {
+				var req createPost
+				return ctx.ShouldBindJSON(&req)
+			}, WithLogging(false))
+			if w.Code != http.StatusBadRequest || body.Code != string(KeyWrongParams) {
+				t.Errorf("response = %d %s, want 400 %s", w.Code, body.Code, KeyWrongParams)
+			}
+			if body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Message, tt.wantMessage)
+			}
+			if body.Details["field"] != tt.wantField || body.Details["expected"] != tt.wantKinds[0] || body.Details["received"] != tt.wantKinds[1] {
+				t.Errorf("details = %v, want field %s expecting %s, received %s", body.Details, tt.wantField, tt.wantKinds[0], tt.wantKinds[1])
+			}
+			if offset, ok := body.Details["offset"].(float64); !ok || offset <= 0 || int(offset) > len(tt.payload) {
+				t.Errorf("offset = %v, want a position in the payload", body.Details["offset"])
+			}
+			if strings.Contains(w.Body.String(), "Go struct") || strings.Contains(w.Body.String(), "createPost") {
+				t.Errorf("body = %s, leaks Go type names", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTagMessages(t *testing.T) {
+	type form struct {
+		Required string   `json:"required" binding:"required"`
+		Email    string   `json:"email" binding:"email"`
+		URL      string   `json:"url" binding:"url"`
+		URI      string   `json:"uri" binding:"uri"`
+		UUID     string   `json:"uuid" binding:"uuid"`
+		UUID4    string   `json:"uuid4" binding:"uuid4"`
+		Alpha    string   `json:"alpha" binding:"alpha"`
+		Alphanum string   `json:"alphanum" binding:"alphanum"`
+		Numeric  string   `json:"numeric" binding:"numeric"`
+		Oneof    string   `json:"oneof" binding:"oneof=draft published"`
+		Len      string   `json:"len" binding:"len=4"`
+		Min      string   `json:"min" binding:"min=3"`
+		Max      string   `json:"max" binding:"max=3"`
+		MinItems []string `json:"min_items" binding:"min=2"`
+		Gt       int      `json:"gt" binding:"gt=5"`
+		Gte      int      `json:"gte" binding:"gte=5"`
+		Lt       int      `json:"lt" binding:"lt=5"`
+		Lte      int      `json:"lte" binding:"lte=5"`
+		Handle   string   `json:"handle"`
+		Confirm  string   `json:"confirm" binding:"eqfield=Handle"`
+		Previous string   `json:"previous" binding:"nefield=Handle"`
+		Date     string   `json:"date" binding:"datetime=2006-01-02"`
+		Unique   []string `json:"unique" binding:"unique"`
+		Country  string   `json:"country" binding:"iso3166_1_alpha2"`
+		Prefixed string   `json:"prefixed" binding:"startswith=@"`
+	}
+	payload := `{
+		"email": "alice", "url": "nope", "uri": "::", "uuid": "x", "uuid4": "x",
+		"alpha": "a1", "alphanum": "a-1", "numeric": "one", "oneof": "deleted",
+		"len": "abc", "min": "ab", "max": "abcd", "min_items": ["a"],
+		"gt": 5, "gte": 4, "lt": 5, "lte": 6,
+		"handle": "alice", "confirm": "bob", "previous": "alice",
+		"date": "yesterday", "unique": ["a", "a"], "country": "XX", "prefixed": "alice"
+	}`
+	want := map[string]string{
+		"Required": "Required is required",
+		"Email":    "Email must be a valid email address",
+		"URL":      "URL must be a valid URL",
+		"URI":      "URI must be a valid URI",
+		"UUID":     "UUID must be a valid UUID",
+		"UUID4":    "UUID4 must be a valid UUID",
+		"Alpha":    "Alpha may only contain letters",
+		"Alphanum": "Alphanum may only contain letters and numbers",
+		"Numeric":  "Numeric must be numeric",
+		"Oneof":    "Oneof must be one of draft, published",
+		"Len":      "Len must be exactly 4 characters",
+		"Min":      "Min must be at least 3 characters",
+		"Max":      "Max must be at most 3 characters",
+		"MinItems": "MinItems must be at least 2 items",
+		"Gt":       "Gt must be greater than 5",
+		"Gte":      "Gte must be at least 5",
+		"Lt":       "Lt must be less than 5",
+		"Lte":      "Lte must be at most 5",
+		"Confirm":  "Confirm must match Handle",
+		"Previous": "Previous must differ from Handle",
+		"Date":     "Date must be a date in the format 2006-01-02",
+		"Unique":   "Unique must not contain duplicates",
+		"Country":  "Country must be a valid country code",
+		"Prefixed": "Prefixed is invalid",
+	}
+	_, body := serveRequest(t, postJSON(payload), func(ctx *gin.Context) error {
+		var req form
+		return ctx.ShouldBindJSON(&req)
+	}, WithLogging(false))
+	got := make(map[string]string)
+	for _, v := range violationsOf(t, body) {
+		got[v.Field] = v.Message
+	}
+	for field, msg := range want {
+		t.Run(field, func(t *testing.T) {
+			if got[field] != msg {
+				t.Errorf("message = %q, want %q", got[field], msg)
+			}
+		})
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d violations, want %d: %v", len(got), len(want), got)
+	}
+}
+
+func TestTagMessageSummarizesSingleFailure(t *testing.T) {
+	type createPost struct {
+		Title string `json:"title" binding:"max=5"`
+	}
+	_, body := serveRequest(t, postJSON(`{"title": "far too long"}`), func(ctx *gin.Context) error {
+		var req createPost
+		return ctx.ShouldBindJSON(&req)
+	}, WithLogging(false))
+	if want := "Title must be at most 5 characters"; body.Message != want {
+		t.Errorf("message = %q, want %q", body.Message, want)
+	}
+}
+
+func TestFieldMessageBeatsTagMessage(t *testing.T) {
+	type author struct {
+		Handle string `json:"handle" binding:"alphanum"`
+	}
+	type signupRequest struct {
+		Handle string   `json:"handle" binding:"alphanum"`
+		Bio    string   `json:"bio" binding:"alphanum"`
+		Author author   `json:"author"`
+		Links  []author `json:"links" binding:"dive"`
+	}
+	type renameRequest struct {
+		Handle string `json:"handle" binding:"alphanum"`
+	}
+	t.Cleanup(func() {
+		validationMessagesMu.Lock()
+		defer validationMessagesMu.Unlock()
+		delete(tagOverrides, "alphanum")
+		delete(fieldMessages, fieldMessageKey{"signupRequest.Handle", "alphanum"})
+		delete(fieldMessages, fieldMessageKey{"signupRequest.Links.Handle", "alphanum"})
+	})
+	RegisterTagMessage("alphanum", "{field} has invalid characters")
+	RegisterFieldMessage("signupRequest", "Handle", "alphanum", "{field} may only contain letters, numbers and underscores")
+	RegisterFieldMessage("signupRequest", "Links.Handle", "alphanum", "link {field} is not a valid handle")
+
+	_, body := serveRequest(t, postJSON(`{"handle": "a-b", "bio": "hi!", "author": {"handle": "c-d"}, "links": [{"handle": "e-f"}]}`), func(ctx *gin.Context) error {
+		var req signupRequest
+		return ctx.ShouldBindJSON(&req)
+	}, WithLogging(false))
+	got := make(map[string]string)
+	for _, v := range violationsOf(t, body) {
+		got[v.Path] = v.Message
+	}
+	want := map[string]string{
+		"Handle":          "Handle may only contain letters, numbers and underscores",
+		"Bio":             "Bio has invalid characters",
+		"Author.Handle":   "Author.Handle has invalid characters",
+		"Links[0].Handle": "link Links[0].Handle is not a valid handle",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %v, want %v", got, want)
+	}
+
+	_, body = serveRequest(t, postJSON(`{"handle": "a-b"}`), func(ctx *gin.Context) error {
+		var req renameRequest
+		return ctx.ShouldBindJSON(&req)
+	}, WithLogging(false))
+	if want := "Handle has invalid characters"; body.Message != want {
+		t.Errorf("message for another struct = %q, want the tag message %q", body.Message, want)
+	}
+}
+
+func TestRegisterMessagesConcurrentWithHandle(t *testing.T) {
+	type createPost struct {
+		Title string `json:"title" binding:"startswith=#"`
+	}
+	t.Cleanup(func() {
+		validationMessagesMu.Lock()
+		defer validationMessagesMu.Unlock()
+		delete(tagOverrides, "startswith")
+		delete(fieldMessages, fieldMessageKey{"createPost.Title", "startswith"})
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterTagMessage("startswith", "{field} must start with {param}")
+			RegisterFieldMessage("createPost", "Title", "startswith", "titles start with {param}")
+		}()
+		go func() {
+			defer wg.Done()
+			serveRequest(t, postJSON(`{"title": "hello"}`), func(ctx *gin.Context) error {
+				var req createPost
+				return ctx.ShouldBindJSON(&req)
+			}, WithLogging(false))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestValidationCollectsEveryField(t *testing.T) {
+	type createPost struct {
+		Title    string   `json:"title" binding:"required"`
+		Body     string   `json:"body" binding:"min=10"`
+		Category string   `json:"category" binding:"oneof=news blog"`
+		Tags     []string `json:"tags" binding:"max=2"`
+		Rating   int      `json:"rating" binding:"lte=5"`
+		Email    string   `json:"email" binding:"email"`
+	}
+	bind := func(ctx *gin.Context) error {
+		var req createPost
+		return ctx.ShouldBindJSON(&req)
+	}
+	// Keys are sent out of struct order to show the order follows the struct.
+	const payload = `{"email": "x", "rating": 9, "tags": ["a", "b", "c"], "category": "misc", "body": "short"}`
+	wantPaths := []string{"Title", "Body", "Category", "Tags", "Rating", "Email"}
+
+	_, body := serveRequest(t, postJSON(payload), bind, WithLogging(false))
+	var paths []string
+	for _, v := range violationsOf(t, body) {
+		paths = append(paths, v.Path)
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("fields = %v, want %v", paths, wantPaths)
+	}
+	if _, truncated := body.Details["truncated"]; truncated {
+		t.Errorf("details = %v, want no truncation", body.Details)
+	}
+
+	SetDetailLimits(DetailLimits{MaxFields: 4, MaxValueLength: DefaultDetailLimits.MaxValueLength, MaxDetailsBytes: DefaultDetailLimits.MaxDetailsBytes})
+	t.Cleanup(func() { SetDetailLimits(DefaultDetailLimits) })
+	_, body = serveRequest(t, postJSON(payload), bind, WithLogging(false))
+	paths = nil
+	for _, v := range violationsOf(t, body) {
+		paths = append(paths, v.Path)
+	}
+	if !reflect.DeepEqual(paths, wantPaths[:4]) {
+		t.Errorf("capped fields = %v, want %v", paths, wantPaths[:4])
+	}
+	if body.Details["truncated"] != true || body.Details["fields_total"] != float64(6) || body.Details["fields_shown"] != float64(4) {
+		t.Errorf("details = %v, want truncated with 4 of 6 fields shown", body.Details)
+	}
+}
+
+func TestInvalidValidationErrorIsServerError(t *testing.T) {
+	type createPost struct {
+		Title string `validate:"required"`
+	}
+	validate := validator.New()
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   ErrorCode
+		wantLevel  string
+	}{
+		{"non-struct", fmt.Errorf("validating: %w", validate.Struct(42)), http.StatusInternalServerError, KeyInternalError, "ERROR"},
+		{"nil", Wrap(validate.Struct(nil), "handler", "create_post"), http.StatusInternalServerError, KeyInternalError, "ERROR"},
+		{"validation errors", fmt.Errorf("validating: %w", validate.Struct(createPost{})), http.StatusBadRequest, KeyWrongParams, "WARN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w *httptest.ResponseRecorder
+			var body HttpError
+			logs := captureLogs(t, func() {
+				w, body = serve(t, returning(tt.err))
+			})
+			if w.Code != tt.wantStatus || body.Code != string(tt.wantCode) {
+				t.Errorf("response = %d %s, want %d %s", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+			line := logLine(t, logs, "validat")
+			if !strings.Contains(line, tt.wantLevel) {
+				t.Errorf("log line = %s, want level %s", line, tt.wantLevel)
+			}
+			if tt.wantStatus == http.StatusInternalServerError && !strings.Contains(line, `"labels.validated_type"`) {
+				t.Errorf("log line = %s, want the validated type logged", line)
+			}
+		})
+	}
+}
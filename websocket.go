@@ -0,0 +1,77 @@
+package errors
+
+import "net/http"
+
+// WebSocket close codes, as defined by RFC 6455.
+const (
+	CloseNormalClosure      = 1000
+	CloseGoingAway          = 1001
+	CloseUnsupportedData    = 1003
+	CloseInvalidPayloadData = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseInternalServerErr  = 1011
+	CloseTryAgainLater      = 1013
+)
+
+// MaxCloseReasonBytes is the longest reason a WebSocket close frame can carry: the 125
+// bytes of a control frame payload less the 2 bytes of the close code.
+const MaxCloseReasonBytes = 123
+
+// closeCodes maps error codes whose meaning is more specific than their HTTP status to
+// close codes. Other codes are mapped by status with statusCloseCodes.
+var closeCodes = map[ErrorCode]int{
+	KeyInsufficientQuota:   CloseTryAgainLater,
+	KeyDatabaseUnavailable: CloseTryAgainLater,
+	KeyClientClosedRequest: CloseGoingAway,
+}
+
+// statusCloseCodes maps HTTP statuses to close codes.
+var statusCloseCodes = map[int]int{
+	http.StatusBadRequest:            CloseInvalidPayloadData,
+	http.StatusRequestEntityTooLarge: CloseMessageTooBig,
+	http.StatusUnsupportedMediaType:  CloseUnsupportedData,
+	http.StatusUnprocessableEntity:   CloseInvalidPayloadData,
+	http.StatusTooManyRequests:       CloseTryAgainLater,
+	StatusClientClosedRequest:        CloseGoingAway,
+	http.StatusServiceUnavailable:    CloseTryAgainLater,
+}
+
+// CloseCode returns the close code and reason to close a WebSocket connection with when
+// err ends it after the upgrade. The reason is the message MessageOf returns, truncated to
+// MaxCloseReasonBytes. A nil err is a normal closure without a reason.
+func CloseCode(err error) (code int, reason string) {
+	if err == nil {
+		return CloseNormalClosure, ""
+	}
+	return CloseCodeFor(Code(err), StatusOf(err)), CloseReason(MessageOf(err))
+}
+
+// CloseCodeFor returns the WebSocket close code sent for an error with the given code and
+// HTTP status:
+//
+//	400, 422                                       -> 1007 invalid payload data
+//	413                                            -> 1009 message too big
+//	415                                            -> 1003 unsupported data
+//	INSUFFICIENT_QUOTA, DATABASE_UNAVAILABLE,
+//	429, 503                                       -> 1013 try again later
+//	CLIENT_CLOSED_REQUEST, 499                     -> 1001 going away
+//	401, 403, other 4xx                            -> 1008 policy violation
+//	500, other 5xx                                 -> 1011 internal error
+func CloseCodeFor(code ErrorCode, status int) int {
+	if closeCode, exists := closeCodes[code]; exists {
+		return closeCode
+	}
+	if closeCode, exists := statusCloseCodes[status]; exists {
+		return closeCode
+	}
+	if status < http.StatusInternalServerError {
+		return ClosePolicyViolation
+	}
+	return CloseInternalServerErr
+}
+
+// CloseReason truncates msg to fit a close frame, cutting at a rune boundary.
+func CloseReason(msg string) string {
+	return truncateString(msg, MaxCloseReasonBytes)
+}
@@ -0,0 +1,99 @@
+// Package wserr reports errors of WebSocket endpoints served with gorilla/websocket: as an
+// HttpError response before the upgrade, and as a close frame after it.
+package wserr
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/A-pen-app/errors"
+	"github.com/gorilla/websocket"
+)
+
+// closeTimeout bounds how long writing the close frame may take.
+const closeTimeout = time.Second
+
+// UpgradeFunc upgrades the request's connection to the WebSocket protocol, with
+// responseHeader added to the handshake response.
+type UpgradeFunc func(responseHeader http.Header) (*websocket.Conn, error)
+
+// HandlerFunc serves a WebSocket endpoint: it checks the request, calls upgrade and then
+// serves the connection until it returns.
+type HandlerFunc func(r *http.Request, upgrade UpgradeFunc) error
+
+// HandleWS wraps a HandlerFunc so that its errors are reported according to when they
+// happened:
+//
+//	http.Handle("/feed", wserr.HandleWS(&upgrader, func(r *http.Request, upgrade wserr.UpgradeFunc) error {
+//	    if err := authorize(r); err != nil {
+//	        return err // 403 {"code":"PERMISSION_DENIED",...}
+//	    }
+//	    conn, err := upgrade(nil)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    return serveFeed(r.Context(), conn) // closes the connection with errors.CloseCode
+//	}))
+//
+// Errors returned before the upgrade are served as by errors.HandleHTTP, and so are
+// failed handshakes, with the upgrader's status, e.g. 400 for a missing Upgrade header,
+// instead of gorilla's plain-text response. Errors returned after the upgrade are logged
+// as errors.HandleHTTP does and close the connection with the code and reason of
+// errors.CloseCodeFor, using the response message, so production messages apply. A
+// connection the peer closed normally is closed without logging. The handler's
+// connection is always closed when it returns. Options work as they do for
+// errors.HandleHTTP.
+func HandleWS(upgrader *websocket.Upgrader, fn HandlerFunc, opts ...errors.Option) http.HandlerFunc {
+	responder := errors.NewResponder(opts...)
+	return errors.WrapHandler(func(w http.ResponseWriter, r *http.Request) error {
+		var conn *websocket.Conn
+		err := fn(r, func(responseHeader http.Header) (*websocket.Conn, error) {
+			c, err := upgrade(upgrader, w, r, responseHeader)
+			if err == nil {
+				conn = c
+			}
+			return c, err
+		})
+		if conn == nil {
+			return err
+		}
+		defer conn.Close()
+		if err != nil {
+			closeWithError(conn, r, err, responder)
+		}
+		return nil
+	}, opts...)
+}
+
+// upgrade upgrades the connection with a copy of upgrader whose handshake errors carry
+// the status to respond with, leaving the response to HandleWS.
+func upgrade(upgrader *websocket.Upgrader, w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*websocket.Conn, error) {
+	u := *upgrader
+	status := 0
+	u.Error = func(w http.ResponseWriter, _ *http.Request, s int, _ error) {
+		w.Header().Set("Sec-Websocket-Version", "13")
+		status = s
+	}
+	conn, err := u.Upgrade(w, r, responseHeader)
+	if err != nil && status != 0 {
+		err = errors.WithStatus(errors.WithCode(err, errors.CodeForStatus(status)), status)
+		if status < http.StatusInternalServerError {
+			err = errors.WithPublicMessage(err, err.Error())
+		}
+	}
+	return conn, err
+}
+
+// closeWithError logs err and sends the close frame for it.
+func closeWithError(conn *websocket.Conn, r *http.Request, err error, responder *errors.Responder) {
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return
+	}
+	resp := responder.ResponseFor(r, err)
+	if resp.Body == nil {
+		return
+	}
+	code := errors.CloseCodeFor(resp.Code, resp.Status)
+	msg := websocket.FormatCloseMessage(code, errors.CloseReason(resp.Body.Message))
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeTimeout))
+}
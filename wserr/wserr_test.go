@@ -0,0 +1,171 @@
+package wserr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/A-pen-app/errors"
+	"github.com/A-pen-app/logging"
+	"github.com/gorilla/websocket"
+)
+
+func TestMain(m *testing.M) {
+	logging.Initialize(nil)
+	m.Run()
+}
+
+// serve starts a server for fn and returns its ws:// URL.
+func serve(t *testing.T, fn HandlerFunc, opts ...errors.Option) string {
+	t.Helper()
+	srv := httptest.NewServer(HandleWS(&websocket.Upgrader{}, fn, opts...))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// failAfterUpgrade returns a HandlerFunc that upgrades and then fails with err.
+func failAfterUpgrade(err error) HandlerFunc {
+	return func(r *http.Request, upgrade UpgradeFunc) error {
+		if _, upgradeErr := upgrade(nil); upgradeErr != nil {
+			return upgradeErr
+		}
+		return err
+	}
+}
+
+// closeError dials url and returns the close frame the server ends the connection with.
+func closeError(t *testing.T, url string) *websocket.CloseError {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _, err = conn.ReadMessage()
+	closeErr, ok := err.(*websocket.CloseError)
+	if !ok {
+		t.Fatalf("ReadMessage() = %v, want a close error", err)
+	}
+	return closeErr
+}
+
+func TestHandleWSBeforeUpgrade(t *testing.T) {
+	url := serve(t, func(*http.Request, UpgradeFunc) error {
+		return errors.ErrorPermissionDenied
+	})
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("Dial() = nil, want a handshake error")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	var body errors.HttpError
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != string(errors.KeyPermissionDenied) {
+		t.Errorf("code = %q, want %q", body.Code, errors.KeyPermissionDenied)
+	}
+}
+
+func TestHandleWSFailedHandshake(t *testing.T) {
+	srv := httptest.NewServer(HandleWS(&websocket.Upgrader{}, failAfterUpgrade(nil)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Errorf("Content-Type = %q, want JSON", got)
+	}
+	var body errors.HttpError
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != string(errors.KeyWrongParams) || body.Message == "" {
+		t.Errorf("body = %+v, want %s with the handshake error", body, errors.KeyWrongParams)
+	}
+}
+
+func TestHandleWSAfterUpgrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   int
+		wantReason string
+	}{
+		{"wrong params", errors.WithPublicMessage(errors.ErrorWrongParams, "bad frame"), errors.CloseInvalidPayloadData, "bad frame"},
+		{"permission denied", errors.ErrorPermissionDenied, errors.ClosePolicyViolation, "permission denied"},
+		{"too many requests", errors.ErrorTooManyRequests, errors.CloseTryAgainLater, "too many requests"},
+		{"internal", errors.Wrap(errors.ErrorInternalError, "secret", "s3cr3t"), errors.CloseInternalServerErr, errors.MessageOf(errors.ErrorInternalError)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			closeErr := closeError(t, serve(t, failAfterUpgrade(tt.err)))
+			if closeErr.Code != tt.wantCode {
+				t.Errorf("close code = %d, want %d", closeErr.Code, tt.wantCode)
+			}
+			if closeErr.Text != tt.wantReason {
+				t.Errorf("reason = %q, want %q", closeErr.Text, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestHandleWSCloseCodeIgnoresCodePrefix(t *testing.T) {
+	errors.SetCodePrefix("FEED")
+	t.Cleanup(func() { errors.SetCodePrefix("") })
+
+	// INSUFFICIENT_QUOTA closes with try again later by code, where its 402 status would
+	// give policy violation, so the prefixed code sent must not be what is mapped.
+	closeErr := closeError(t, serve(t, failAfterUpgrade(errors.ErrorInsufficientQuota)))
+	if closeErr.Code != errors.CloseTryAgainLater {
+		t.Errorf("close code = %d, want %d", closeErr.Code, errors.CloseTryAgainLater)
+	}
+}
+
+func TestHandleWSTruncatesReason(t *testing.T) {
+	msg := strings.Repeat("é", 100)
+	closeErr := closeError(t, serve(t, failAfterUpgrade(errors.WithPublicMessage(errors.ErrorWrongParams, msg))))
+	if len(closeErr.Text) > errors.MaxCloseReasonBytes {
+		t.Errorf("reason is %d bytes, want at most %d", len(closeErr.Text), errors.MaxCloseReasonBytes)
+	}
+	if !utf8.ValidString(closeErr.Text) {
+		t.Errorf("reason %q is cut inside a rune", closeErr.Text)
+	}
+	if !strings.HasPrefix(msg, strings.TrimSuffix(closeErr.Text, "…")) {
+		t.Errorf("reason = %q, want a prefix of the message", closeErr.Text)
+	}
+}
+
+func TestHandleWSNormalClosure(t *testing.T) {
+	done := make(chan error, 1)
+	url := serve(t, func(r *http.Request, upgrade UpgradeFunc) error {
+		conn, err := upgrade(nil)
+		if err != nil {
+			return err
+		}
+		_, _, err = conn.ReadMessage()
+		done <- err
+		return err
+	})
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	if err := <-done; !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("server read %v, want a normal closure", err)
+	}
+}